@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command mailpreview renders every email template in every locale with
+// sample data, so template changes can be reviewed without sending mail.
+//
+//	go run ./cmd/mailpreview -templates ./mail-templates -out ./preview
+//
+// Without -out the rendered subject and text parts are printed to stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+)
+
+func main() {
+	dir := flag.String("templates", os.Getenv("MAIL_TEMPLATES_DIR"), "directory with template overrides")
+	locale := flag.String("default-locale", email.DefaultLocale, "locale every template must exist in")
+	out := flag.String("out", "", "write <locale>/<name>.{txt,html} here instead of printing")
+	flag.Parse()
+
+	templates, err := email.LoadTemplates(*dir, *locale)
+	if err != nil {
+		log.Fatalf("failed load mail templates: %v", err)
+	}
+
+	for _, name := range templates.Names() {
+		for _, l := range templates.Locales(name) {
+			msg, err := templates.Render(name, []string{l}, sampleData())
+			if err != nil {
+				log.Fatalf("render %s (%s): %v", name, l, err)
+			}
+			if *out == "" {
+				fmt.Printf("==> %s (%s)\nSubject: %s\n\n%s\n", name, l, msg.Subject, msg.Text)
+				continue
+			}
+			if err := write(filepath.Join(*out, l), name, msg); err != nil {
+				log.Fatalf("write %s (%s): %v", name, l, err)
+			}
+		}
+	}
+}
+
+// sampleData fills every field templates may use. The username contains
+// markup to show that the HTML part escapes it.
+func sampleData() email.Data {
+	now := time.Now().UTC()
+	return email.Data{
+		Username:     "Alice <b>& Bob</b>",
+		Link:         "https://example.com/api/v1/verify?token=sample-token",
+		NewEmail:     "alice.new@example.com",
+		Organization: "Example Corp",
+		Reason:       "Investigating support ticket #1234",
+		ExpiresAt:    now.Add(24 * time.Hour),
+		Time:         now,
+	}
+}
+
+func write(dir, name string, msg *email.Message) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	text := "Subject: " + msg.Subject + "\n\n" + msg.Text
+	if err := os.WriteFile(filepath.Join(dir, name+".txt"), []byte(text), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".html"), []byte(msg.HTML), 0o644)
+}
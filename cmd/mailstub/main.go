@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command mailstub runs a local stand-in for an HTTP email provider, so
+// MAIL_DRIVER=http can be tried without a provider account:
+//
+//	go run ./cmd/mailstub -addr :8025
+//	MAIL_DRIVER=http MAIL_HTTP_URL=http://localhost:8025/send go run ./cmd/server
+//
+// Accepted messages are logged.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/email/emailstub"
+)
+
+func main() {
+	addr := flag.String("addr", ":8025", "listen address")
+	apiKey := flag.String("api-key", os.Getenv("MAIL_HTTP_API_KEY"), "required API key, empty to accept any")
+	secret := flag.String("signing-secret", os.Getenv("MAIL_HTTP_SIGNING_SECRET"), "required signing secret, empty to skip verification")
+	flag.Parse()
+
+	h := &emailstub.Handler{
+		APIKey:        *apiKey,
+		SigningSecret: *secret,
+		OnMessage: func(msg email.Message) {
+			log.Printf("[mail] id=%s to=%s subject=%q\n%s", msg.ID, msg.To, msg.Subject, msg.Text)
+		},
+	}
+	log.Printf("mail stub running on %s", *addr)
+	if err := http.ListenAndServe(*addr, h); err != nil {
+		log.Fatalf("failed to serve mail stub: %v", err)
+	}
+}
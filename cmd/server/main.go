@@ -18,7 +18,11 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
+	"net"
+	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"syscall"
@@ -26,9 +30,21 @@ import (
 
 	"github.com/shinoda4/sd-svc-auth/internal/config"
 	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service/audit"
 	"github.com/shinoda4/sd-svc-auth/internal/service/auth"
+	"github.com/shinoda4/sd-svc-auth/internal/service/clientapp"
+	"github.com/shinoda4/sd-svc-auth/internal/service/emaildomain"
+	"github.com/shinoda4/sd-svc-auth/internal/service/ippolicy"
+	"github.com/shinoda4/sd-svc-auth/internal/service/onetimetoken"
+	"github.com/shinoda4/sd-svc-auth/internal/service/outbox"
+	"github.com/shinoda4/sd-svc-auth/internal/service/provisioning"
+	"github.com/shinoda4/sd-svc-auth/internal/service/serviceaccount"
+	"github.com/shinoda4/sd-svc-auth/internal/service/webhook"
 	"github.com/shinoda4/sd-svc-auth/internal/transport/grpc"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/jsonschema"
 	"github.com/shinoda4/sd-svc-auth/pkg/logger"
+	"github.com/shinoda4/sd-svc-auth/pkg/siem"
 )
 
 func main() {
@@ -37,7 +53,7 @@ func main() {
 	// 加载配置（关键）
 	cfg := config.MustLoad()
 
-	_, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	db, err := repo.NewUserRepo(cfg.DatabaseDSN)
@@ -54,10 +70,98 @@ func main() {
 		}
 	}(cache)
 
-	authService := auth.NewAuthService(db, cache)
+	var domainOpts []emaildomain.Option
+	if cfg.DisposableDomainsFile != "" {
+		extra, err := emaildomain.LoadFile(cfg.DisposableDomainsFile)
+		if err != nil {
+			log.Fatalf("failed load disposable domains: %v", err)
+		}
+		domainOpts = append(domainOpts, emaildomain.WithDisposableDomains(extra))
+	}
+	if cfg.EmailMXCheck {
+		domainOpts = append(domainOpts, emaildomain.WithResolver(net.DefaultResolver))
+	}
+	emailDomains := emaildomain.NewChecker(repo.NewEmailDomainRepo(db.Repo), domainOpts...)
+	orgs := repo.NewOrganizationRepo(db.Repo)
+	groups := repo.NewGroupRepo(db.Repo)
+
+	userMetadataSchema := mustLoadSchema(cfg.UserMetadataSchemaFile)
+	appMetadataSchema := mustLoadSchema(cfg.AppMetadataSchemaFile)
+	metadataClaims, err := auth.ParseMetadataClaims(cfg.TokenMetadataClaims)
+	if err != nil {
+		log.Fatalf("invalid TOKEN_METADATA_CLAIMS: %v", err)
+	}
+
+	// 邮件先写入 outbox，由后台 worker 投递
+	mailer := mustMailer(cfg)
+	outboxService := outbox.NewService(repo.NewOutboxRepo(db.Repo), outbox.WithMaxAttempts(cfg.OutboxMaxAttempts))
+	outboxService.Handle(outbox.TopicMail, outbox.MailHandler(mailer))
+	webhooks := webhook.NewService(repo.NewWebhookRepo(db.Repo), outboxService,
+		webhook.WithMaxAttempts(cfg.OutboxMaxAttempts),
+		webhook.WithTransactor(db),
+	)
+	outboxService.Handle(webhook.TopicWebhook, webhooks.Deliver)
+	go outboxService.Run(context.Background(), cfg.OutboxPollInterval)
+
+	auditEvents := repo.NewAuditEventRepo(db.Repo)
+
+	authService := auth.NewAuthService(db, cache, onetimetoken.NewService(repo.NewOneTimeTokenRepo(db.Repo)),
+		auth.WithMailer(mailer),
+		auth.WithMailQueue(outboxService),
+		auth.WithTransactor(db),
+		auth.WithEvents(webhooks),
+		auth.WithMailTemplates(mustMailTemplates(cfg)),
+		auth.WithDeletionGrace(cfg.AccountDeletionGrace),
+		auth.WithVerifyTokenTTL(cfg.VerifyTokenTTL),
+		auth.WithInvitations(repo.NewInvitationRepo(db.Repo)),
+		auth.WithPersonalAccessTokens(repo.NewPersonalAccessTokenRepo(db.Repo)),
+		auth.WithAuditEvents(auditEvents),
+		auth.WithRegistrationPolicy(auth.RegistrationPolicy{
+			Mode:            cfg.RegistrationMode,
+			AllowedDomains:  cfg.RegistrationAllowedDomains,
+			RequireApproval: cfg.RegistrationRequireApproval,
+		}),
+		auth.WithEmailDomainChecker(emailDomains),
+		auth.WithOrganizations(orgs),
+		auth.WithGroups(groups),
+		auth.WithGroupsClaim(cfg.TokenGroupsClaim),
+		auth.WithMetadataSchemas(userMetadataSchema, appMetadataSchema),
+		auth.WithMetadataClaims(metadataClaims),
+	)
+	go authService.RunAccountPurge(context.Background(), time.Hour)
+
+	ipPolicies := ippolicy.NewService(repo.NewIPPolicyRepo(db.Repo))
+	if _, err := ipPolicies.Reload(ctx); err != nil {
+		log.Fatalf("failed load ip policies: %v", err)
+	}
+	go ipPolicies.Watch(context.Background(), cfg.IPPolicyReloadInterval)
+
+	serviceAccounts := serviceaccount.NewService(repo.NewServiceAccountRepo(db.Repo), cache, cfg.OAuthTokenURL)
 
-	go grpc.RunGRPCServer(authService) // gRPC server
-	go grpc.RunGateway()
+	provisioner := provisioning.NewService(db, groups, repo.NewScimClientRepo(db.Repo), orgs, authService, authService, cfg.ScimBaseURL)
+
+	clientApps := clientapp.NewService(repo.NewClientAppRepo(db.Repo), clientapp.Links{
+		Verify:      cfg.VerifyURL,
+		Reset:       cfg.ResetPasswordURL,
+		EmailChange: cfg.EmailChangeURL,
+		Invitation:  cfg.InvitationURL,
+		MagicLink:   cfg.MagicLinkURL,
+	})
+
+	// 审计事件可同时导出到 SIEM
+	auditExports := mustAuditExports(cfg)
+	auditOpts := []audit.Option{audit.WithRetention(cfg.AuditRetention)}
+	for _, q := range auditExports {
+		auditOpts = append(auditOpts, audit.WithExporter(q))
+	}
+	auditLog := audit.NewService(auditEvents, auditOpts...)
+	go auditLog.RunRetention(context.Background(), time.Hour)
+
+	go grpc.RunGRPCServer(grpc.NewAuthServer(authService, ipPolicies, emailDomains, serviceAccounts, provisioner, clientApps, webhooks, auditLog), cfg.TrustedProxies) // gRPC server
+	go grpc.RunGateway(serviceAccounts, provisioner, auditLog, cfg.TrustedProxies)
+	if cfg.MetricsAddr != "" {
+		go runMetrics(cfg.MetricsAddr)
+	}
 	//go handler.StartServer(authService) // Http server
 
 	// 优雅关闭
@@ -65,4 +169,122 @@ func main() {
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	<-sig
 	log.Println("shutdown signal received")
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	for _, q := range auditExports {
+		if err := q.Close(flushCtx); err != nil {
+			log.Printf("[audit] %v", err)
+		}
+	}
+}
+
+// runMetrics serves expvar metrics, including the outbox queue depth, on
+// /debug/vars.
+func runMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Printf("metrics running on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("failed to serve metrics: %v", err)
+	}
+}
+
+// mustLoadSchema compiles the schema at path, nil when path is empty.
+func mustLoadSchema(path string) *jsonschema.Schema {
+	if path == "" {
+		return nil
+	}
+	schema, err := jsonschema.LoadFile(path)
+	if err != nil {
+		log.Fatalf("failed load schema %s: %v", path, err)
+	}
+	return schema
+}
+
+// mustMailTemplates loads the email templates, with overrides from
+// MAIL_TEMPLATES_DIR.
+func mustMailTemplates(cfg *config.Config) *email.Templates {
+	t, err := email.LoadTemplates(cfg.MailTemplatesDir, cfg.MailDefaultLocale)
+	if err != nil {
+		log.Fatalf("failed load mail templates: %v", err)
+	}
+	return t
+}
+
+// mustMailer builds the email sink selected by MAIL_DRIVER.
+func mustMailer(cfg *config.Config) email.Mailer {
+	from := mail.Address{Name: cfg.MailFromName, Address: cfg.MailFromAddress}
+	switch cfg.MailDriver {
+	case "maildir":
+		m, err := email.NewMaildirMailer(cfg.MaildirPath, from)
+		if err != nil {
+			log.Fatalf("failed open maildir %s: %v", cfg.MaildirPath, err)
+		}
+		return m
+	case "log":
+		return email.NewLogMailer(from)
+	case "http":
+		m, err := email.NewHTTPMailer(email.HTTPConfig{
+			URL:           cfg.MailHTTPURL,
+			Format:        cfg.MailHTTPFormat,
+			APIKey:        cfg.MailHTTPAPIKey,
+			SigningSecret: cfg.MailHTTPSigningSecret,
+			From:          from,
+		})
+		if err != nil {
+			log.Fatalf("invalid mail api config: %v", err)
+		}
+		return m
+	}
+	m, err := email.NewSMTPMailer(email.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Security: cfg.SMTPSecurity,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     from,
+	})
+	if err != nil {
+		log.Fatalf("invalid smtp config: %v", err)
+	}
+	return m
+}
+
+// mustAuditExports builds a queue for every sink in AUDIT_EXPORT.
+func mustAuditExports(cfg *config.Config) []*siem.Queue {
+	queueCfg := siem.QueueConfig{Size: cfg.AuditExportBuffer, Overflow: cfg.AuditExportOverflow}
+	var queues []*siem.Queue
+	for _, name := range cfg.AuditExport {
+		var (
+			sink siem.Sink
+			err  error
+		)
+		switch name {
+		case "syslog":
+			sink, err = siem.NewSyslogSink(siem.SyslogConfig{
+				Network: cfg.AuditSyslogNetwork,
+				Addr:    cfg.AuditSyslogAddr,
+				Format:  cfg.AuditSyslogFormat,
+				Framing: cfg.AuditSyslogFraming,
+				CAFile:  cfg.AuditSyslogCAFile,
+			})
+		case "file":
+			sink, err = siem.NewFileSink(siem.FileConfig{
+				Path:       cfg.AuditFilePath,
+				Format:     cfg.AuditFileFormat,
+				MaxBytes:   cfg.AuditFileMaxBytes,
+				MaxBackups: cfg.AuditFileMaxBackups,
+			})
+		}
+		if err != nil {
+			log.Fatalf("invalid audit export %s: %v", name, err)
+		}
+		q, err := siem.NewQueue(name, sink, queueCfg)
+		if err != nil {
+			log.Fatalf("invalid audit export %s: %v", name, err)
+		}
+		queues = append(queues, q)
+	}
+	return queues
 }
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS ip_policies
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    method      TEXT       NOT NULL      DEFAULT '*',
+    action      VARCHAR(8) NOT NULL CHECK (action IN ('allow', 'deny')),
+    cidr        CIDR       NOT NULL,
+    description TEXT       NOT NULL      DEFAULT '',
+    created_by  UUID,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+ADD COLUMN pending_email TEXT,
+ADD COLUMN verify_token_purpose VARCHAR(32) NOT NULL DEFAULT 'email_verify';
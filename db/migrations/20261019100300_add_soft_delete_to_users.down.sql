@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_delete_after;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS deleted_at,
+DROP COLUMN IF EXISTS delete_after;
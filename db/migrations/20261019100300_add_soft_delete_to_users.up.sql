@@ -0,0 +1,5 @@
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN delete_after TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after) WHERE delete_after IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Usernames that differ only by case cannot share the new index. Stop with
+-- a list of them instead of failing halfway; rename the accounts and rerun.
+DO
+$$
+DECLARE
+    clashes text;
+BEGIN
+    SELECT string_agg(names, '; ')
+    INTO clashes
+    FROM (SELECT string_agg(username, ', ' ORDER BY username) AS names
+          FROM users
+          GROUP BY lower(username)
+          HAVING count(*) > 1) dup;
+    IF clashes IS NOT NULL THEN
+        RAISE EXCEPTION 'usernames differ only by case: %', clashes
+            USING HINT = 'rename all but one account of each group, then rerun the migration';
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Stored usernames keep their case; lookups compare lower(username).
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
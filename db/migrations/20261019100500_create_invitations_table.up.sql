@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS invitations
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    email       TEXT   NOT NULL,
+    roles       TEXT[] NOT NULL          DEFAULT '{}',
+    invited_by  UUID,
+    user_id     UUID,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    revoked_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (lower(email));
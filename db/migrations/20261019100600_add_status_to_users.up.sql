@@ -0,0 +1,4 @@
+ALTER TABLE users
+ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status) WHERE status <> 'active';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS email_domain_rules
+(
+    domain     TEXT PRIMARY KEY,
+    action     VARCHAR(8) NOT NULL CHECK (action IN ('allow', 'deny')),
+    note       TEXT       NOT NULL      DEFAULT '',
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
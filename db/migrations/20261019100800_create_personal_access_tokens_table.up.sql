@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id      UUID   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT   NOT NULL,
+    prefix       TEXT   NOT NULL,
+    token_hash   TEXT   NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL          DEFAULT '{}',
+    expires_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at   TIMESTAMP WITH TIME ZONE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
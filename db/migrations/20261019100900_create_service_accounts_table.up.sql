@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    client_id   TEXT   NOT NULL UNIQUE,
+    name        TEXT   NOT NULL,
+    secret_hash TEXT,
+    public_key  TEXT,
+    scopes      TEXT[] NOT NULL          DEFAULT '{}',
+    audiences   TEXT[] NOT NULL          DEFAULT '{}',
+    created_by  UUID,
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (secret_hash IS NOT NULL OR public_key IS NOT NULL)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_external_id;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS external_id,
+DROP COLUMN IF EXISTS given_name,
+DROP COLUMN IF EXISTS family_name;
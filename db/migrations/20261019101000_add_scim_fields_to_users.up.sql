@@ -0,0 +1,6 @@
+ALTER TABLE users
+ADD COLUMN external_id TEXT,
+ADD COLUMN given_name  TEXT,
+ADD COLUMN family_name TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_id ON users (external_id) WHERE external_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS groups
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    display_name TEXT NOT NULL,
+    external_id  TEXT,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_display_name ON groups (lower(display_name));
+
+CREATE TABLE IF NOT EXISTS group_members
+(
+    group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    user_id  UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
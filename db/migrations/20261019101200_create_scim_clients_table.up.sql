@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS scim_clients
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    created_by   UUID,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at   TIMESTAMP WITH TIME ZONE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
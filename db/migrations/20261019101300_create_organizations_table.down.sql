@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_scim_clients_org_id;
+DROP INDEX IF EXISTS idx_service_accounts_org_id;
+DROP INDEX IF EXISTS idx_invitations_org_id;
+
+DROP INDEX IF EXISTS idx_groups_display_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_display_name ON groups (lower(display_name));
+
+ALTER TABLE ip_policies DROP COLUMN IF EXISTS org_id;
+ALTER TABLE groups DROP COLUMN IF EXISTS org_id;
+ALTER TABLE scim_clients DROP COLUMN IF EXISTS org_id;
+ALTER TABLE service_accounts DROP COLUMN IF EXISTS org_id;
+ALTER TABLE personal_access_tokens DROP COLUMN IF EXISTS org_id;
+ALTER TABLE invitations DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS organizations
+(
+    id                            UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    slug                          VARCHAR(63) NOT NULL UNIQUE,
+    name                          TEXT        NOT NULL,
+    registration_mode             VARCHAR(32),
+    registration_allowed_domains  TEXT[]      NOT NULL     DEFAULT '{}',
+    registration_require_approval BOOLEAN     NOT NULL     DEFAULT FALSE,
+    access_token_ttl_seconds      INTEGER,
+    refresh_token_ttl_seconds     INTEGER,
+    created_at                    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at                    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members
+(
+    org_id     UUID   NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    UUID   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    roles      TEXT[] NOT NULL          DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+-- existing data moves into a default organization; users keep their roles there
+INSERT INTO organizations (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default');
+
+INSERT INTO organization_members (org_id, user_id, roles)
+SELECT '00000000-0000-0000-0000-000000000001', id, roles
+FROM users;
+
+ALTER TABLE invitations
+ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE invitations ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE personal_access_tokens
+ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE personal_access_tokens ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE service_accounts
+ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE service_accounts ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE scim_clients
+ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE scim_clients ALTER COLUMN org_id DROP DEFAULT;
+
+ALTER TABLE groups
+ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE groups ALTER COLUMN org_id DROP DEFAULT;
+
+DROP INDEX IF EXISTS idx_groups_display_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_display_name ON groups (org_id, lower(display_name));
+
+-- existing IP policies stay platform-wide (org_id NULL); organization
+-- policies only apply to calls made with a token of that organization
+ALTER TABLE ip_policies
+ADD COLUMN org_id UUID REFERENCES organizations (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_invitations_org_id ON invitations (org_id);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_org_id ON service_accounts (org_id);
+CREATE INDEX IF NOT EXISTS idx_scim_clients_org_id ON scim_clients (org_id);
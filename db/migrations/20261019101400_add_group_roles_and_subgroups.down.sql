@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_subgroups;
+
+ALTER TABLE groups
+    DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,15 @@
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{}';
+
+-- A member group's users are members of the parent group as well and
+-- inherit its roles.
+CREATE TABLE IF NOT EXISTS group_subgroups
+(
+    group_id        UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    member_group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, member_group_id),
+    CHECK (group_id <> member_group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_subgroups_member_group_id ON group_subgroups (member_group_id);
@@ -0,0 +1,4 @@
+-- user_metadata is editable by the user, app_metadata by admins only.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS user_metadata JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS app_metadata  JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS verify_token_expire TIMESTAMP WITH TIME ZONE;
+
+-- Links mailed before tokens expired keep working for one more day.
+UPDATE users
+SET verify_token_expire = now() + INTERVAL '24 hours'
+WHERE verify_token IS NOT NULL;
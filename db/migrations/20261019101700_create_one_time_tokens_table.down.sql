@@ -0,0 +1,10 @@
+-- Outstanding tokens cannot be restored from their hashes; users have to
+-- request new links.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS verify_token         VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS verify_token_purpose VARCHAR(32) NOT NULL DEFAULT 'email_verify',
+    ADD COLUMN IF NOT EXISTS verify_token_expire  TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS reset_token          TEXT,
+    ADD COLUMN IF NOT EXISTS reset_token_expire   TIMESTAMP WITH TIME ZONE;
+
+DROP TABLE IF EXISTS one_time_tokens;
@@ -0,0 +1,41 @@
+-- Single-use tokens mailed to users (email verification, email change,
+-- password reset). Only the SHA-256 of a token is stored.
+CREATE TABLE IF NOT EXISTS one_time_tokens
+(
+    id          UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    user_id     UUID                     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose     VARCHAR(32)              NOT NULL,
+    token_hash  CHAR(64)                 NOT NULL UNIQUE,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts    INTEGER                  NOT NULL DEFAULT 0,
+    consumed_at TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_purpose ON one_time_tokens (user_id, purpose);
+
+-- Carry over outstanding tokens so links already mailed keep working.
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+SELECT id,
+       verify_token_purpose,
+       encode(sha256(convert_to(verify_token, 'UTF8')), 'hex'),
+       COALESCE(verify_token_expire, now() + INTERVAL '24 hours')
+FROM users
+WHERE verify_token IS NOT NULL
+  AND deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+SELECT id, 'password_reset', encode(sha256(convert_to(reset_token, 'UTF8')), 'hex'), reset_token_expire
+FROM users
+WHERE reset_token IS NOT NULL
+  AND reset_token_expire > now()
+  AND deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS verify_token,
+    DROP COLUMN IF EXISTS verify_token_purpose,
+    DROP COLUMN IF EXISTS verify_token_expire,
+    DROP COLUMN IF EXISTS reset_token,
+    DROP COLUMN IF EXISTS reset_token_expire;
@@ -0,0 +1,19 @@
+-- Messages written in the same transaction as the change they describe and
+-- delivered in the background by internal/service/outbox. Delivered rows
+-- have their payload cleared, dead-lettered rows keep it for inspection.
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    topic           VARCHAR(32)              NOT NULL,
+    payload         JSONB                    NOT NULL,
+    status          VARCHAR(16)              NOT NULL DEFAULT 'pending',
+    attempts        INTEGER                  NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    locked_until    TIMESTAMP WITH TIME ZONE,
+    last_error      TEXT,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    processed_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_status ON outbox (status, processed_at);
@@ -0,0 +1,21 @@
+-- Frontends that emailed links point at. A request names its app by
+-- client_id; the link templates are only ever taken from this table.
+-- client_id is public and unique across organizations; org_id is the
+-- organization whose admins manage the app.
+CREATE TABLE IF NOT EXISTS client_apps
+(
+    id               UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    org_id           UUID                     NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    client_id        VARCHAR(64)              NOT NULL UNIQUE,
+    name             VARCHAR(128)             NOT NULL,
+    verify_url       TEXT                     NOT NULL DEFAULT '',
+    reset_url        TEXT                     NOT NULL DEFAULT '',
+    email_change_url TEXT                     NOT NULL DEFAULT '',
+    invitation_url   TEXT                     NOT NULL DEFAULT '',
+    magic_link_url   TEXT                     NOT NULL DEFAULT '',
+    created_by       UUID,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_apps_org_id ON client_apps (org_id);
@@ -0,0 +1,38 @@
+-- Endpoints notified of user lifecycle events. events lists the event
+-- types a subscription receives, '*' matches all of them. The secret signs
+-- every delivery and is shown to the admin once. A subscription only
+-- receives events of users who belong to its organization.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id          UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    org_id      UUID                     NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    url         TEXT                     NOT NULL,
+    events      TEXT[]                   NOT NULL DEFAULT '{}',
+    description VARCHAR(255)             NOT NULL DEFAULT '',
+    secret      VARCHAR(128)             NOT NULL,
+    disabled    BOOLEAN                  NOT NULL DEFAULT FALSE,
+    created_by  UUID,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- One row per event sent to a subscription, kept as the delivery log.
+-- Replays are new rows pointing at the delivery they repeat.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              UUID PRIMARY KEY                  DEFAULT gen_random_uuid(),
+    subscription_id UUID                     NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        UUID                     NOT NULL,
+    event           VARCHAR(64)              NOT NULL,
+    payload         JSONB                    NOT NULL,
+    status          VARCHAR(16)              NOT NULL DEFAULT 'pending',
+    attempts        INTEGER                  NOT NULL DEFAULT 0,
+    response_status INTEGER                  NOT NULL DEFAULT 0,
+    last_error      TEXT                     NOT NULL DEFAULT '',
+    replay_of       UUID REFERENCES webhook_deliveries (id) ON DELETE SET NULL,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_org_id ON webhook_subscriptions (org_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at DESC);
@@ -0,0 +1,38 @@
+-- Security audit log. Rows are only ever inserted; the trigger rejects
+-- updates and deletes, except the retention purge, which sets audit.purge
+-- for its own transaction. id orders the log and serves as the cursor.
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id          BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    action      VARCHAR(64)              NOT NULL,
+    outcome     VARCHAR(16)              NOT NULL,
+    reason      TEXT                     NOT NULL DEFAULT '',
+    actor_id    TEXT                     NOT NULL DEFAULT '',
+    actor_type  VARCHAR(32)              NOT NULL DEFAULT '',
+    target_id   TEXT                     NOT NULL DEFAULT '',
+    org_id      TEXT                     NOT NULL DEFAULT '',
+    ip          VARCHAR(64)              NOT NULL DEFAULT '',
+    user_agent  TEXT                     NOT NULL DEFAULT '',
+    request_id  VARCHAR(128)             NOT NULL DEFAULT '',
+    details     JSONB                    NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, id) WHERE actor_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_id, id) WHERE target_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, id);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -18,7 +18,11 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -31,6 +35,105 @@ type Config struct {
 	JWTSecret     string
 	EmailAddress  string
 	EmailPassword string
+
+	// MailDriver is one of smtp, http, maildir or log.
+	MailDriver      string
+	MailFromAddress string
+	MailFromName    string
+	SMTPHost        string
+	SMTPPort        int
+	// SMTPSecurity is one of starttls, tls (implicit) or none.
+	SMTPSecurity string
+	SMTPUsername string
+	SMTPPassword string
+	MaildirPath  string
+	// MailHTTP* configure MAIL_DRIVER=http, a provider's HTTP API.
+	MailHTTPURL           string
+	MailHTTPFormat        string
+	MailHTTPAPIKey        string
+	MailHTTPSigningSecret string
+	// MailTemplatesDir holds <locale>/<name>.tmpl files that replace the
+	// embedded email templates.
+	MailTemplatesDir  string
+	MailDefaultLocale string
+
+	// OutboxPollInterval is how often the outbox worker looks for due
+	// messages; OutboxMaxAttempts how often delivery is tried before a
+	// message is dead-lettered.
+	OutboxPollInterval time.Duration
+	OutboxMaxAttempts  int
+	// MetricsAddr serves expvar metrics on /debug/vars when set.
+	MetricsAddr string
+
+	// AuditRetention is how long audit events are kept; zero keeps them
+	// forever.
+	AuditRetention time.Duration
+	// AuditExport lists the sinks recorded audit events are copied to:
+	// syslog and/or file. Each sink buffers AuditExportBuffer events; when
+	// full, AuditExportOverflow decides whether to drop or block.
+	AuditExport         []string
+	AuditExportBuffer   int
+	AuditExportOverflow string
+	// AuditSyslog* configure the syslog sink. Network is udp, tcp or tls,
+	// Format rfc5424 or cef, Framing octet (RFC 6587 octet counting) or lf.
+	AuditSyslogNetwork string
+	AuditSyslogAddr    string
+	AuditSyslogFormat  string
+	AuditSyslogFraming string
+	AuditSyslogCAFile  string
+	// AuditFile* configure the file sink, rotated at AuditFileMaxBytes.
+	AuditFilePath       string
+	AuditFileFormat     string
+	AuditFileMaxBytes   int64
+	AuditFileMaxBackups int
+
+	// TrustedProxies are the peers allowed to set X-Forwarded-For, e.g. the
+	// grpc-gateway running next to the gRPC server.
+	TrustedProxies         []netip.Prefix
+	IPPolicyReloadInterval time.Duration
+
+	// AccountDeletionGrace is how long a deleted account is kept before it
+	// is purged for good.
+	AccountDeletionGrace time.Duration
+
+	// VerifyTokenTTL is how long email verification links stay valid.
+	VerifyTokenTTL time.Duration
+
+	// RegistrationMode is one of open, closed, invite_only or domains.
+	RegistrationMode            string
+	RegistrationAllowedDomains  []string
+	RegistrationRequireApproval bool
+
+	// DisposableDomainsFile extends the built-in disposable-domain list.
+	DisposableDomainsFile string
+	EmailMXCheck          bool
+
+	// OAuthTokenURL is the public URL of POST /oauth2/token. Client
+	// assertions (private_key_jwt) must use it as their audience.
+	OAuthTokenURL string
+
+	// ScimBaseURL is the public URL of the SCIM endpoints, used in
+	// meta.location.
+	ScimBaseURL string
+
+	// Link templates for requests that name no client app, and for the
+	// templates a registered app leaves empty. See pkg/linktemplate.
+	VerifyURL        string
+	ResetPasswordURL string
+	EmailChangeURL   string
+	InvitationURL    string
+	MagicLinkURL     string
+
+	// TokenGroupsClaim adds the subject's group names to access tokens.
+	TokenGroupsClaim bool
+
+	// UserMetadataSchemaFile and AppMetadataSchemaFile are JSON schemas
+	// the metadata documents must satisfy. Empty accepts any object.
+	UserMetadataSchemaFile string
+	AppMetadataSchemaFile  string
+	// TokenMetadataClaims maps metadata values into tokens, e.g.
+	// "plan=app_metadata.plan".
+	TokenMetadataClaims string
 }
 
 func MustLoad() *Config {
@@ -40,8 +143,6 @@ func MustLoad() *Config {
 		"SERVER_HOST",
 		"GRPC_PORT",
 		"JWT_SECRET",
-		"EMAIL_ADDRESS",
-		"EMAIL_PASSWORD",
 	}
 
 	// 统一检查缺失变量
@@ -56,7 +157,7 @@ func MustLoad() *Config {
 		log.Fatalf("missing required environment variables: %v", missing)
 	}
 
-	return &Config{
+	cfg := &Config{
 		DatabaseDSN:   os.Getenv("DATABASE_DSN"),
 		RedisAddr:     os.Getenv("REDIS_ADDR"),
 		RedisPassword: os.Getenv("REDIS_PASSWORD"), // 可选
@@ -65,5 +166,150 @@ func MustLoad() *Config {
 		JWTSecret:     os.Getenv("JWT_SECRET"),
 		EmailAddress:  os.Getenv("EMAIL_ADDRESS"),
 		EmailPassword: os.Getenv("EMAIL_PASSWORD"),
+
+		// EMAIL_ADDRESS / EMAIL_PASSWORD 仍作为默认值，兼容旧配置
+		MailDriver:      mustOneOf("MAIL_DRIVER", "smtp", "smtp", "http", "maildir", "log"),
+		MailFromAddress: getenv("MAIL_FROM_ADDRESS", os.Getenv("EMAIL_ADDRESS")),
+		MailFromName:    os.Getenv("MAIL_FROM_NAME"),
+		SMTPHost:        getenv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:        getenvInt("SMTP_PORT", 587),
+		SMTPSecurity:    mustOneOf("SMTP_SECURITY", "starttls", "starttls", "tls", "none"),
+		SMTPUsername:    getenv("SMTP_USERNAME", os.Getenv("EMAIL_ADDRESS")),
+		SMTPPassword:    getenv("SMTP_PASSWORD", os.Getenv("EMAIL_PASSWORD")),
+		MaildirPath:     getenv("MAILDIR_PATH", "./maildir"),
+
+		MailHTTPURL:           os.Getenv("MAIL_HTTP_URL"),
+		MailHTTPFormat:        mustOneOf("MAIL_HTTP_FORMAT", "sendgrid", "sendgrid", "postmark"),
+		MailHTTPAPIKey:        os.Getenv("MAIL_HTTP_API_KEY"),
+		MailHTTPSigningSecret: os.Getenv("MAIL_HTTP_SIGNING_SECRET"),
+
+		MailTemplatesDir:  os.Getenv("MAIL_TEMPLATES_DIR"),
+		MailDefaultLocale: getenv("MAIL_DEFAULT_LOCALE", "en"),
+
+		OutboxPollInterval: time.Duration(getenvInt("OUTBOX_POLL_SECONDS", 5)) * time.Second,
+		OutboxMaxAttempts:  getenvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		MetricsAddr:        os.Getenv("METRICS_ADDR"),
+
+		AuditRetention:      time.Duration(getenvInt("AUDIT_RETENTION_DAYS", 365)) * 24 * time.Hour,
+		AuditExport:         getenvList("AUDIT_EXPORT"),
+		AuditExportBuffer:   getenvInt("AUDIT_EXPORT_BUFFER", 1024),
+		AuditExportOverflow: mustOneOf("AUDIT_EXPORT_OVERFLOW", "drop", "drop", "block"),
+		AuditSyslogNetwork:  mustOneOf("AUDIT_SYSLOG_NETWORK", "udp", "udp", "tcp", "tls"),
+		AuditSyslogAddr:     os.Getenv("AUDIT_SYSLOG_ADDR"),
+		AuditSyslogFormat:   mustOneOf("AUDIT_SYSLOG_FORMAT", "rfc5424", "rfc5424", "cef"),
+		AuditSyslogFraming:  mustOneOf("AUDIT_SYSLOG_FRAMING", "octet", "octet", "lf"),
+		AuditSyslogCAFile:   os.Getenv("AUDIT_SYSLOG_CA_FILE"),
+		AuditFilePath:       getenv("AUDIT_FILE_PATH", "./audit/audit.log"),
+		AuditFileFormat:     mustOneOf("AUDIT_FILE_FORMAT", "json", "json", "cef"),
+		AuditFileMaxBytes:   int64(getenvInt("AUDIT_FILE_MAX_MB", 100)) << 20,
+		AuditFileMaxBackups: getenvInt("AUDIT_FILE_MAX_BACKUPS", 5),
+
+		TrustedProxies:         mustPrefixes("TRUSTED_PROXIES", "127.0.0.1/32,::1/128"),
+		IPPolicyReloadInterval: time.Duration(getenvInt("IP_POLICY_RELOAD_SECONDS", 30)) * time.Second,
+
+		AccountDeletionGrace: time.Duration(getenvInt("ACCOUNT_DELETION_GRACE_HOURS", 720)) * time.Hour,
+		VerifyTokenTTL:       time.Duration(getenvInt("VERIFY_TOKEN_TTL_HOURS", 24)) * time.Hour,
+
+		RegistrationMode:            mustOneOf("REGISTRATION_MODE", "open", "open", "closed", "invite_only", "domains"),
+		RegistrationAllowedDomains:  getenvList("REGISTRATION_ALLOWED_DOMAINS"),
+		RegistrationRequireApproval: getenvBool("REGISTRATION_REQUIRE_APPROVAL", false),
+
+		DisposableDomainsFile: os.Getenv("DISPOSABLE_DOMAINS_FILE"),
+		EmailMXCheck:          getenvBool("EMAIL_MX_CHECK", false),
+
+		OAuthTokenURL: getenv("OAUTH_TOKEN_URL", os.Getenv("SERVER_HOST")+":"+os.Getenv("HTTP_PORT")+"/oauth2/token"),
+		ScimBaseURL:   getenv("SCIM_BASE_URL", os.Getenv("SERVER_HOST")+":"+os.Getenv("HTTP_PORT")+"/scim/v2"),
+
+		VerifyURL:        getenv("VERIFY_URL", os.Getenv("SERVER_HOST")+":"+os.Getenv("SERVER_PORT")+"/api/v1/verify"),
+		ResetPasswordURL: os.Getenv("RESET_PASSWORD_URL"),
+		EmailChangeURL:   getenv("EMAIL_CHANGE_URL", os.Getenv("SERVER_HOST")+":"+os.Getenv("SERVER_PORT")+"/api/v1/confirm-email-change"),
+		InvitationURL:    getenv("INVITATION_URL", os.Getenv("SERVER_HOST")+":"+os.Getenv("SERVER_PORT")+"/api/v1/accept-invitation"),
+		MagicLinkURL:     os.Getenv("MAGIC_LINK_URL"),
+
+		TokenGroupsClaim: getenvBool("TOKEN_GROUPS_CLAIM", false),
+
+		UserMetadataSchemaFile: os.Getenv("USER_METADATA_SCHEMA_FILE"),
+		AppMetadataSchemaFile:  os.Getenv("APP_METADATA_SCHEMA_FILE"),
+		TokenMetadataClaims:    os.Getenv("TOKEN_METADATA_CLAIMS"),
+	}
+	if (cfg.MailDriver == "smtp" || cfg.MailDriver == "http") && cfg.MailFromAddress == "" {
+		log.Fatalf("MAIL_FROM_ADDRESS (or EMAIL_ADDRESS) is required for MAIL_DRIVER=%s", cfg.MailDriver)
+	}
+	if cfg.MailDriver == "http" && cfg.MailHTTPURL == "" {
+		log.Fatalf("MAIL_HTTP_URL is required for MAIL_DRIVER=http")
+	}
+	for _, sink := range cfg.AuditExport {
+		switch sink {
+		case "syslog":
+			if cfg.AuditSyslogAddr == "" {
+				log.Fatalf("AUDIT_SYSLOG_ADDR is required for AUDIT_EXPORT=syslog")
+			}
+		case "file":
+		default:
+			log.Fatalf("invalid AUDIT_EXPORT entry %q, expected syslog or file", sink)
+		}
+	}
+	return cfg
+}
+
+func getenvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func getenvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func mustOneOf(key, def string, allowed ...string) string {
+	v := getenv(key, def)
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	log.Fatalf("invalid %s %q, expected one of %v", key, v, allowed)
+	return ""
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getenvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+func mustPrefixes(key, def string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, raw := range strings.Split(getenv(key, def), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			log.Fatalf("invalid %s entry %q: %v", key, raw, err)
+		}
+		prefixes = append(prefixes, prefix)
 	}
+	return prefixes
 }
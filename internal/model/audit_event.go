@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// AuditEvent records one security relevant action: who (actor) did what
+// (action) to whom (target), from where, and whether it worked.
+type AuditEvent struct {
+	ID         int64          `db:"id" json:"id"`
+	OccurredAt time.Time      `db:"occurred_at" json:"occurred_at"`
+	Action     string         `db:"action" json:"action"`
+	Outcome    string         `db:"outcome" json:"outcome"`
+	Reason     string         `db:"reason" json:"reason,omitempty"`
+	ActorID    string         `db:"actor_id" json:"actor_id,omitempty"`
+	ActorType  string         `db:"actor_type" json:"actor_type,omitempty"`
+	TargetID   string         `db:"target_id" json:"target_id,omitempty"`
+	OrgID      string         `db:"org_id" json:"org_id,omitempty"`
+	IP         string         `db:"ip" json:"ip,omitempty"`
+	UserAgent  string         `db:"user_agent" json:"user_agent,omitempty"`
+	RequestID  string         `db:"request_id" json:"request_id,omitempty"`
+	Details    types.JSONText `db:"details" json:"details,omitempty"`
+}
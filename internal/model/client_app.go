@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// ClientApp is a registered frontend with its own link templates. An empty
+// template falls back to the service-wide default. ClientID is unique
+// across organizations; OrgID is the organization that manages the app.
+type ClientApp struct {
+	ID             string    `db:"id"`
+	OrgID          string    `db:"org_id"`
+	ClientID       string    `db:"client_id"`
+	Name           string    `db:"name"`
+	VerifyURL      string    `db:"verify_url"`
+	ResetURL       string    `db:"reset_url"`
+	EmailChangeURL string    `db:"email_change_url"`
+	InvitationURL  string    `db:"invitation_url"`
+	MagicLinkURL   string    `db:"magic_link_url"`
+	CreatedBy      string    `db:"created_by"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+const (
+	EmailDomainAllow = "allow"
+	EmailDomainDeny  = "deny"
+)
+
+// EmailDomainRule overrides the built-in disposable list for a domain and
+// all of its subdomains.
+type EmailDomainRule struct {
+	Domain    string    `db:"domain"`
+	Action    string    `db:"action"`
+	Note      string    `db:"note"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Group collects users of an organization. Its Roles are granted to every
+// direct and nested member.
+type Group struct {
+	ID          string         `db:"id"`
+	OrgID       string         `db:"org_id"`
+	DisplayName string         `db:"display_name"`
+	ExternalID  string         `db:"external_id"`
+	Roles       pq.StringArray `db:"roles"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+// GroupMember is a user's membership in a group, with both names for
+// display.
+type GroupMember struct {
+	GroupID     string `db:"group_id"`
+	DisplayName string `db:"display_name"`
+	UserID      string `db:"user_id"`
+	Username    string `db:"username"`
+}
+
+// Subgroup is a group nested in another one. Its members are members of
+// the parent as well.
+type Subgroup struct {
+	GroupID       string `db:"group_id"`
+	MemberGroupID string `db:"member_group_id"`
+	DisplayName   string `db:"display_name"`
+}
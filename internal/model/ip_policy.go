@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+const (
+	IPPolicyAllow = "allow"
+	IPPolicyDeny  = "deny"
+)
+
+// IPPolicy is a single CIDR rule. Method is a full gRPC method name,
+// a prefix ending in "*", or "*" for every method. Policies without OrgID
+// are platform-wide; the others only apply to calls made with a token of
+// that organization.
+type IPPolicy struct {
+	ID          string    `db:"id"`
+	OrgID       string    `db:"org_id"`
+	Method      string    `db:"method"`
+	Action      string    `db:"action"`
+	CIDR        string    `db:"cidr"`
+	Description string    `db:"description"`
+	CreatedBy   string    `db:"created_by"`
+	CreatedAt   time.Time `db:"created_at"`
+}
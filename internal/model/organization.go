@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultOrganizationID is the organization that data created before
+// multi-tenancy was migrated into.
+const DefaultOrganizationID = "00000000-0000-0000-0000-000000000001"
+
+// Organization is a tenant. The settings override the service-wide
+// defaults; empty or zero values mean "use the default".
+type Organization struct {
+	ID                          string         `db:"id"`
+	Slug                        string         `db:"slug"`
+	Name                        string         `db:"name"`
+	RegistrationMode            string         `db:"registration_mode"`
+	RegistrationAllowedDomains  pq.StringArray `db:"registration_allowed_domains"`
+	RegistrationRequireApproval bool           `db:"registration_require_approval"`
+	AccessTokenTTLSeconds       int            `db:"access_token_ttl_seconds"`
+	RefreshTokenTTLSeconds      int            `db:"refresh_token_ttl_seconds"`
+	CreatedAt                   time.Time      `db:"created_at"`
+	UpdatedAt                   time.Time      `db:"updated_at"`
+}
+
+// AccessTTL returns the organization's access token lifetime, or def when
+// none is set.
+func (o *Organization) AccessTTL(def time.Duration) time.Duration {
+	if o.AccessTokenTTLSeconds > 0 {
+		return time.Duration(o.AccessTokenTTLSeconds) * time.Second
+	}
+	return def
+}
+
+// RefreshTTL returns the organization's refresh token lifetime, or def when
+// none is set.
+func (o *Organization) RefreshTTL(def time.Duration) time.Duration {
+	if o.RefreshTokenTTLSeconds > 0 {
+		return time.Duration(o.RefreshTokenTTLSeconds) * time.Second
+	}
+	return def
+}
+
+// OrganizationMember is a user's membership in an organization together
+// with the roles the user holds there. The name fields are filled in for
+// display.
+type OrganizationMember struct {
+	OrgID     string         `db:"org_id"`
+	OrgSlug   string         `db:"org_slug"`
+	OrgName   string         `db:"org_name"`
+	UserID    string         `db:"user_id"`
+	Username  string         `db:"username"`
+	Email     string         `db:"email"`
+	Roles     pq.StringArray `db:"roles"`
+	CreatedAt time.Time      `db:"created_at"`
+}
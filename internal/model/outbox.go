@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// OutboxMessage is a message waiting for, or done with, background
+// delivery.
+type OutboxMessage struct {
+	ID            string         `db:"id"`
+	Topic         string         `db:"topic"`
+	Payload       types.JSONText `db:"payload"`
+	Status        string         `db:"status"`
+	Attempts      int            `db:"attempts"`
+	NextAttemptAt time.Time      `db:"next_attempt_at"`
+	LockedUntil   *time.Time     `db:"locked_until"`
+	LastError     *string        `db:"last_error"`
+	CreatedAt     time.Time      `db:"created_at"`
+	ProcessedAt   *time.Time     `db:"processed_at"`
+}
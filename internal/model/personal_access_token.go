@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PersonalAccessToken is a long-lived credential a user issues for scripts
+// and CI. Only the SHA-256 of the token is stored; Prefix is kept in clear
+// so users can tell their tokens apart.
+type PersonalAccessToken struct {
+	ID         string         `db:"id"`
+	UserID     string         `db:"user_id"`
+	OrgID      string         `db:"org_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	TokenHash  string         `db:"token_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (t *PersonalAccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
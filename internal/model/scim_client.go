@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// ScimClient is an identity provider allowed to call the SCIM endpoints.
+// Like personal access tokens, only the token hash and a prefix are kept.
+type ScimClient struct {
+	ID         string     `db:"id"`
+	OrgID      string     `db:"org_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	TokenHash  string     `db:"token_hash"`
+	CreatedBy  string     `db:"created_by"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
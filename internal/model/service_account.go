@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ServiceAccount is a machine identity that obtains tokens through the
+// client-credentials grant. It authenticates either with a secret (stored
+// as a SHA-256 hash) or with a JWT signed by the key in PublicKey.
+type ServiceAccount struct {
+	ID         string         `db:"id"`
+	OrgID      string         `db:"org_id"`
+	ClientID   string         `db:"client_id"`
+	Name       string         `db:"name"`
+	SecretHash string         `db:"secret_hash"`
+	PublicKey  string         `db:"public_key"`
+	Scopes     pq.StringArray `db:"scopes"`
+	Audiences  pq.StringArray `db:"audiences"`
+	CreatedBy  string         `db:"created_by"`
+	DisabledAt *time.Time     `db:"disabled_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
@@ -17,20 +17,30 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID               string    `db:"id"`
-	Email            string    `db:"email"`
-	Username         string    `db:"username"`
-	PasswordHash     string    `db:"password_hash"`
-	EmailVerified    bool      `db:"email_verified"`
-	CreatedAt        time.Time `db:"created_at"`
-	UpdatedAt        time.Time `db:"updated_at"`
-	ResetTokenExpire time.Time `db:"reset_token_expire"`
+	ID            string         `db:"id"`
+	Email         string         `db:"email"`
+	Username      string         `db:"username"`
+	PasswordHash  string         `db:"password_hash"`
+	EmailVerified bool           `db:"email_verified"`
+	CreatedAt     time.Time      `db:"created_at"`
+	UpdatedAt     time.Time      `db:"updated_at"`
+	Roles         pq.StringArray `db:"roles"`
+	PendingEmail  string         `db:"pending_email"`
+	Status        string         `db:"status"`
+	ExternalID    string         `db:"external_id"`
+	GivenName     string         `db:"given_name"`
+	FamilyName    string         `db:"family_name"`
+	UserMetadata  types.JSONText `db:"user_metadata"`
+	AppMetadata   types.JSONText `db:"app_metadata"`
 }
 
 func (u *User) GetID() string       { return u.ID }
@@ -44,6 +54,52 @@ func (u *User) GetEmailVerified() bool {
 	return u.EmailVerified
 }
 
-func (u *User) GetResetTokenExpire() time.Time {
-	return u.ResetTokenExpire
+func (u *User) GetRoles() []string {
+	return u.Roles
+}
+
+func (u *User) GetPendingEmail() string {
+	return u.PendingEmail
+}
+
+func (u *User) GetCreatedAt() time.Time {
+	return u.CreatedAt
+}
+
+func (u *User) GetUpdatedAt() time.Time {
+	return u.UpdatedAt
+}
+
+func (u *User) GetStatus() string {
+	return u.Status
+}
+
+func (u *User) GetExternalID() string {
+	return u.ExternalID
+}
+
+func (u *User) GetGivenName() string {
+	return u.GivenName
+}
+
+func (u *User) GetFamilyName() string {
+	return u.FamilyName
+}
+
+// GetUserMetadata returns the user-editable metadata document, "{}" when
+// it was not loaded.
+func (u *User) GetUserMetadata() json.RawMessage {
+	return metadataOrEmpty(u.UserMetadata)
+}
+
+// GetAppMetadata returns the admin-managed metadata document.
+func (u *User) GetAppMetadata() json.RawMessage {
+	return metadataOrEmpty(u.AppMetadata)
+}
+
+func metadataOrEmpty(doc types.JSONText) json.RawMessage {
+	if len(doc) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(doc)
 }
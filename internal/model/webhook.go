@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+// WebhookSubscription is an endpoint notified of the events it lists, for
+// users who belong to OrgID.
+type WebhookSubscription struct {
+	ID          string         `db:"id"`
+	OrgID       string         `db:"org_id"`
+	URL         string         `db:"url"`
+	Events      pq.StringArray `db:"events"`
+	Description string         `db:"description"`
+	Secret      string         `db:"secret"`
+	Disabled    bool           `db:"disabled"`
+	CreatedBy   string         `db:"created_by"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or being sent, to a subscription.
+type WebhookDelivery struct {
+	ID             string         `db:"id"`
+	SubscriptionID string         `db:"subscription_id"`
+	EventID        string         `db:"event_id"`
+	Event          string         `db:"event"`
+	Payload        types.JSONText `db:"payload"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	ResponseStatus int            `db:"response_status"`
+	LastError      string         `db:"last_error"`
+	ReplayOf       string         `db:"replay_of"`
+	CreatedAt      time.Time      `db:"created_at"`
+	DeliveredAt    *time.Time     `db:"delivered_at"`
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+const auditEventColumns = `id, occurred_at, action, outcome, reason, actor_id, actor_type, target_id, org_id, ip, user_agent, request_id, details`
+
+type AuditEventRepo struct {
+	Repo
+}
+
+func NewAuditEventRepo(r Repo) *AuditEventRepo {
+	return &AuditEventRepo{Repo: r}
+}
+
+func (r *AuditEventRepo) AppendAuditEvent(ctx context.Context, ev *model.AuditEvent) (*model.AuditEvent, error) {
+	details := ev.Details.String()
+	if details == "" {
+		details = "{}"
+	}
+	created := &model.AuditEvent{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO audit_events (action, outcome, reason, actor_id, actor_type, target_id, org_id, ip, user_agent, request_id, details)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING `+auditEventColumns,
+		ev.Action, ev.Outcome, ev.Reason, ev.ActorID, ev.ActorType, ev.TargetID, ev.OrgID, ev.IP, ev.UserAgent, ev.RequestID, details)
+	if err != nil {
+		return nil, fmt.Errorf("append audit event: %w", err)
+	}
+	return created, nil
+}
+
+func (r *AuditEventRepo) ListAuditEvents(ctx context.Context, q entity.AuditQuery) ([]*model.AuditEvent, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.ActorID != "" {
+		add("actor_id = $%d", q.ActorID)
+	}
+	if q.TargetID != "" {
+		add("target_id = $%d", q.TargetID)
+	}
+	if q.OrgID != "" {
+		add("org_id = $%d", q.OrgID)
+	}
+	if q.Action != "" {
+		add("action = $%d", q.Action)
+	}
+	if q.Outcome != "" {
+		add("outcome = $%d", q.Outcome)
+	}
+	if q.IP != "" {
+		add("ip = $%d", q.IP)
+	}
+	if !q.Since.IsZero() {
+		add("occurred_at >= $%d", q.Since)
+	}
+	if !q.Until.IsZero() {
+		add("occurred_at < $%d", q.Until)
+	}
+	if q.BeforeID > 0 {
+		add("id < $%d", q.BeforeID)
+	}
+
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	var events []*model.AuditEvent
+	if err := r.conn(ctx).SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// PurgeAuditEvents deletes events older than before. The append-only
+// trigger only lets deletes through in a transaction that set audit.purge.
+func (r *AuditEventRepo) PurgeAuditEvents(ctx context.Context, before time.Time) (int, error) {
+	var n int64
+	err := r.InTx(ctx, func(ctx context.Context) error {
+		if _, err := r.conn(ctx).ExecContext(ctx, `SELECT set_config('audit.purge', 'on', true)`); err != nil {
+			return err
+		}
+		res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM audit_events WHERE occurred_at < $1`, before)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const clientAppColumns = `id, org_id, client_id, name, verify_url, reset_url, email_change_url, invitation_url, magic_link_url,
+	COALESCE(created_by::text, '') AS created_by, created_at, updated_at`
+
+type ClientAppRepo struct {
+	Repo
+}
+
+func NewClientAppRepo(r Repo) *ClientAppRepo {
+	return &ClientAppRepo{Repo: r}
+}
+
+func (r *ClientAppRepo) CreateClientApp(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error) {
+	created := &model.ClientApp{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO client_apps (org_id, client_id, name, verify_url, reset_url, email_change_url, invitation_url, magic_link_url, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)
+		 RETURNING `+clientAppColumns,
+		app.OrgID, app.ClientID, app.Name, app.VerifyURL, app.ResetURL, app.EmailChangeURL, app.InvitationURL, app.MagicLinkURL, app.CreatedBy)
+	if isUniqueViolation(err) {
+		return nil, ErrClientIDTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert client app: %w", err)
+	}
+	return created, nil
+}
+
+func (r *ClientAppRepo) UpdateClientApp(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error) {
+	updated := &model.ClientApp{}
+	err := r.conn(ctx).GetContext(ctx, updated,
+		`UPDATE client_apps
+		 SET name=$2, verify_url=$3, reset_url=$4, email_change_url=$5, invitation_url=$6, magic_link_url=$7, updated_at=now()
+		 WHERE client_id=$1 AND org_id=$8
+		 RETURNING `+clientAppColumns,
+		app.ClientID, app.Name, app.VerifyURL, app.ResetURL, app.EmailChangeURL, app.InvitationURL, app.MagicLinkURL, app.OrgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update client app: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *ClientAppRepo) GetClientApp(ctx context.Context, clientID string) (*model.ClientApp, error) {
+	app := &model.ClientApp{}
+	err := r.conn(ctx).GetContext(ctx, app, `SELECT `+clientAppColumns+` FROM client_apps WHERE client_id=$1`, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("get client app: %w", err)
+	}
+	return app, nil
+}
+
+func (r *ClientAppRepo) ListClientApps(ctx context.Context, orgID string) ([]*model.ClientApp, error) {
+	var apps []*model.ClientApp
+	err := r.conn(ctx).SelectContext(ctx, &apps,
+		`SELECT `+clientAppColumns+` FROM client_apps WHERE org_id=$1 ORDER BY client_id`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list client apps: %w", err)
+	}
+	return apps, nil
+}
+
+func (r *ClientAppRepo) DeleteClientApp(ctx context.Context, orgID, clientID string) error {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM client_apps WHERE client_id=$1 AND org_id=$2`, clientID, orgID)
+	if err != nil {
+		return fmt.Errorf("delete client app: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
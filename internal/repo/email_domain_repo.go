@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const emailDomainColumns = `domain, action, note, COALESCE(created_by::text, '') AS created_by, created_at`
+
+type EmailDomainRepo struct {
+	Repo
+}
+
+func NewEmailDomainRepo(r Repo) *EmailDomainRepo {
+	return &EmailDomainRepo{Repo: r}
+}
+
+func (r *EmailDomainRepo) ListEmailDomainRules(ctx context.Context) ([]*model.EmailDomainRule, error) {
+	var rules []*model.EmailDomainRule
+	err := r.conn(ctx).SelectContext(ctx, &rules, `SELECT `+emailDomainColumns+` FROM email_domain_rules ORDER BY domain`)
+	if err != nil {
+		return nil, fmt.Errorf("list email domain rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *EmailDomainRepo) FindEmailDomainRules(ctx context.Context, domains []string) ([]*model.EmailDomainRule, error) {
+	var rules []*model.EmailDomainRule
+	err := r.conn(ctx).SelectContext(ctx, &rules,
+		`SELECT `+emailDomainColumns+` FROM email_domain_rules WHERE domain = ANY($1)`, pq.Array(domains))
+	if err != nil {
+		return nil, fmt.Errorf("find email domain rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *EmailDomainRepo) UpsertEmailDomainRule(ctx context.Context, rule *model.EmailDomainRule) (*model.EmailDomainRule, error) {
+	saved := &model.EmailDomainRule{}
+	err := r.conn(ctx).GetContext(ctx, saved,
+		`INSERT INTO email_domain_rules (domain, action, note, created_by)
+		 VALUES ($1, $2, $3, NULLIF($4, '')::uuid)
+		 ON CONFLICT (domain) DO UPDATE SET action=EXCLUDED.action, note=EXCLUDED.note, created_by=EXCLUDED.created_by
+		 RETURNING `+emailDomainColumns,
+		rule.Domain, rule.Action, rule.Note, rule.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("upsert email domain rule: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *EmailDomainRepo) DeleteEmailDomainRule(ctx context.Context, domain string) error {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM email_domain_rules WHERE domain=$1`, domain)
+	if err != nil {
+		return fmt.Errorf("delete email domain rule: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
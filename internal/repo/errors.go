@@ -19,6 +19,9 @@ package repo
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 type ErrUserExists struct {
@@ -34,3 +37,26 @@ func NewErrUserExists(email string) error {
 }
 
 var ErrNotFound = errors.New("not found")
+var ErrUsernameTaken = errors.New("username already taken")
+var ErrExternalIDTaken = errors.New("external id already taken")
+var ErrGroupNameTaken = errors.New("group name already taken")
+var ErrGroupCycle = errors.New("group nesting would create a cycle")
+var ErrOrganizationSlugTaken = errors.New("organization slug already taken")
+var ErrClientIDTaken = errors.New("client id already taken")
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isUsernameViolation reports whether err is a unique violation on one of
+// the username constraints.
+func isUsernameViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && strings.Contains(pqErr.Constraint, "username")
+}
+
+func isConstraintViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+const groupColumns = `id, org_id, display_name, COALESCE(external_id, '') AS external_id, roles, created_at, updated_at`
+
+type GroupRepo struct {
+	Repo
+}
+
+func NewGroupRepo(r Repo) *GroupRepo {
+	return &GroupRepo{Repo: r}
+}
+
+func (r *GroupRepo) CreateGroup(ctx context.Context, orgID, displayName, externalID string, roles, memberIDs []string) (*model.Group, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	g := &model.Group{}
+	err = tx.GetContext(ctx, g,
+		`INSERT INTO groups (org_id, display_name, external_id, roles) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING `+groupColumns,
+		orgID, displayName, externalID, pq.StringArray(append([]string{}, roles...)))
+	if isUniqueViolation(err) {
+		return nil, ErrGroupNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert group: %w", err)
+	}
+	if err := addGroupMembers(ctx, tx, orgID, g.ID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return g, nil
+}
+
+func (r *GroupRepo) GetGroup(ctx context.Context, orgID, id string) (*model.Group, error) {
+	g := &model.Group{}
+	err := r.conn(ctx).GetContext(ctx, g, `SELECT `+groupColumns+` FROM groups WHERE id=$1 AND org_id=$2`, id, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *GroupRepo) QueryGroups(ctx context.Context, q entity.ListQuery) ([]*model.Group, int, error) {
+	where, args, err := whereClause("org_id = $1", []interface{}{q.OrgID}, q.Filter, groupFilterColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.conn(ctx).GetContext(ctx, &total, `SELECT COUNT(*) FROM groups WHERE `+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("count groups: %w", err)
+	}
+	if q.Limit <= 0 || total == 0 {
+		return nil, total, nil
+	}
+
+	var groups []*model.Group
+	query := fmt.Sprintf(`SELECT %s FROM groups WHERE %s ORDER BY created_at, id LIMIT %d OFFSET %d`,
+		groupColumns, where, q.Limit, max(q.Offset, 0))
+	if err := r.conn(ctx).SelectContext(ctx, &groups, query, args...); err != nil {
+		return nil, 0, fmt.Errorf("query groups: %w", err)
+	}
+	return groups, total, nil
+}
+
+func (r *GroupRepo) UpdateGroup(ctx context.Context, orgID, id, displayName, externalID string, memberIDs []string) (*model.Group, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	g := &model.Group{}
+	err = tx.GetContext(ctx, g,
+		`UPDATE groups SET display_name=$1, external_id=NULLIF($2, ''), updated_at=now() WHERE id=$3 AND org_id=$4 RETURNING `+groupColumns,
+		displayName, externalID, id, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrGroupNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update group: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM group_members WHERE group_id=$1`, id); err != nil {
+		return nil, fmt.Errorf("clear group members: %w", err)
+	}
+	if err := addGroupMembers(ctx, tx, orgID, id, memberIDs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return g, nil
+}
+
+// addGroupMembers inserts memberships, silently skipping IDs that are not
+// live members of the group's organization.
+func addGroupMembers(ctx context.Context, tx *repoTx, orgID, groupID string, memberIDs []string) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO group_members (group_id, user_id)
+		 SELECT $1, u.id FROM users u
+		 JOIN organization_members m ON m.user_id = u.id AND m.org_id = $3
+		 WHERE u.id::text = ANY($2) AND u.deleted_at IS NULL
+		 ON CONFLICT DO NOTHING`,
+		groupID, pq.StringArray(memberIDs), orgID)
+	if err != nil {
+		return fmt.Errorf("insert group members: %w", err)
+	}
+	return nil
+}
+
+func (r *GroupRepo) UpdateGroupDetails(ctx context.Context, orgID, id, displayName string, roles []string) (*model.Group, error) {
+	g := &model.Group{}
+	err := r.conn(ctx).GetContext(ctx, g,
+		`UPDATE groups SET display_name=$1, roles=$2, updated_at=now() WHERE id=$3 AND org_id=$4 RETURNING `+groupColumns,
+		displayName, pq.StringArray(append([]string{}, roles...)), id, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrGroupNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update group: %w", err)
+	}
+	return g, nil
+}
+
+func (r *GroupRepo) DeleteGroup(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM groups WHERE id=$1 AND org_id=$2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("delete group: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const groupMemberQuery = `SELECT gm.group_id, g.display_name, gm.user_id, u.username
+	FROM group_members gm
+	JOIN groups g ON g.id = gm.group_id
+	JOIN users u ON u.id = gm.user_id AND u.deleted_at IS NULL`
+
+func (r *GroupRepo) ListGroupMembers(ctx context.Context, orgID string, groupIDs []string) ([]*model.GroupMember, error) {
+	var members []*model.GroupMember
+	err := r.conn(ctx).SelectContext(ctx, &members,
+		groupMemberQuery+` WHERE g.org_id = $1 AND gm.group_id::text = ANY($2) ORDER BY u.username`,
+		orgID, pq.StringArray(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list group members: %w", err)
+	}
+	return members, nil
+}
+
+// ListUserGroups returns the memberships of several users at once, so a
+// page of users needs a single query.
+func (r *GroupRepo) ListUserGroups(ctx context.Context, orgID string, userIDs []string) ([]*model.GroupMember, error) {
+	var members []*model.GroupMember
+	err := r.conn(ctx).SelectContext(ctx, &members,
+		groupMemberQuery+` WHERE g.org_id = $1 AND gm.user_id::text = ANY($2) ORDER BY g.display_name`,
+		orgID, pq.StringArray(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list user groups: %w", err)
+	}
+	return members, nil
+}
+
+// AddGroupMembers adds users to a group of orgID. IDs that are not live
+// members of the organization are skipped.
+func (r *GroupRepo) AddGroupMembers(ctx context.Context, orgID, groupID string, userIDs []string) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockGroup(ctx, tx, orgID, groupID); err != nil {
+		return err
+	}
+	if err := addGroupMembers(ctx, tx, orgID, groupID, userIDs); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE groups SET updated_at=now() WHERE id=$1`, groupID); err != nil {
+		return fmt.Errorf("touch group: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (r *GroupRepo) RemoveGroupMember(ctx context.Context, orgID, groupID, userID string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM group_members gm USING groups g
+		 WHERE g.id = gm.group_id AND g.org_id=$1 AND gm.group_id=$2 AND gm.user_id=$3`,
+		orgID, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("remove group member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddSubgroup nests memberGroupID in groupID. Nesting changes in one
+// organization are serialized by an advisory lock so two concurrent
+// inserts cannot close a cycle between them.
+func (r *GroupRepo) AddSubgroup(ctx context.Context, orgID, groupID, memberGroupID string) error {
+	if groupID == memberGroupID {
+		return ErrGroupCycle
+	}
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('group_subgroups:' || $1))`, orgID); err != nil {
+		return fmt.Errorf("lock group nesting: %w", err)
+	}
+	var found int
+	err = tx.GetContext(ctx, &found, `SELECT COUNT(*) FROM groups WHERE org_id=$1 AND id::text = ANY($2)`,
+		orgID, pq.StringArray{groupID, memberGroupID})
+	if err != nil {
+		return fmt.Errorf("load groups: %w", err)
+	}
+	if found != 2 {
+		return ErrNotFound
+	}
+
+	// groupID 若已是 memberGroupID 的后代，再加这条边就成环了
+	var cycle bool
+	err = tx.GetContext(ctx, &cycle,
+		`WITH RECURSIVE descendants(id) AS (
+			SELECT member_group_id FROM group_subgroups WHERE group_id = $1
+			UNION
+			SELECT s.member_group_id FROM group_subgroups s JOIN descendants d ON s.group_id = d.id
+		)
+		SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $2)`,
+		memberGroupID, groupID)
+	if err != nil {
+		return fmt.Errorf("check group cycle: %w", err)
+	}
+	if cycle {
+		return ErrGroupCycle
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO group_subgroups (group_id, member_group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		groupID, memberGroupID)
+	if err != nil {
+		return fmt.Errorf("insert subgroup: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (r *GroupRepo) RemoveSubgroup(ctx context.Context, orgID, groupID, memberGroupID string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM group_subgroups s USING groups g
+		 WHERE g.id = s.group_id AND g.org_id=$1 AND s.group_id=$2 AND s.member_group_id=$3`,
+		orgID, groupID, memberGroupID)
+	if err != nil {
+		return fmt.Errorf("remove subgroup: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) ListSubgroups(ctx context.Context, orgID string, groupIDs []string) ([]*model.Subgroup, error) {
+	var subgroups []*model.Subgroup
+	err := r.conn(ctx).SelectContext(ctx, &subgroups,
+		`SELECT s.group_id, s.member_group_id, m.display_name
+		 FROM group_subgroups s
+		 JOIN groups g ON g.id = s.group_id
+		 JOIN groups m ON m.id = s.member_group_id
+		 WHERE g.org_id = $1 AND s.group_id::text = ANY($2)
+		 ORDER BY m.display_name`,
+		orgID, pq.StringArray(groupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list subgroups: %w", err)
+	}
+	return subgroups, nil
+}
+
+// ListEffectiveGroups walks up from the user's direct groups through every
+// enclosing group. UNION drops repeated rows, so the walk ends even if a
+// cycle slipped in.
+func (r *GroupRepo) ListEffectiveGroups(ctx context.Context, orgID, userID string) ([]*model.Group, error) {
+	var groups []*model.Group
+	err := r.conn(ctx).SelectContext(ctx, &groups,
+		`WITH RECURSIVE reach(id) AS (
+			SELECT gm.group_id FROM group_members gm
+			JOIN groups g ON g.id = gm.group_id
+			WHERE g.org_id = $1 AND gm.user_id = $2
+			UNION
+			SELECT s.group_id FROM group_subgroups s JOIN reach r ON s.member_group_id = r.id
+		)
+		SELECT `+groupColumns+` FROM groups WHERE org_id = $1 AND id IN (SELECT id FROM reach)
+		ORDER BY display_name`,
+		orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list effective groups: %w", err)
+	}
+	return groups, nil
+}
+
+// lockGroup checks that the group belongs to orgID and holds its row until
+// the transaction ends.
+func lockGroup(ctx context.Context, tx *repoTx, orgID, groupID string) error {
+	var id string
+	err := tx.GetContext(ctx, &id, `SELECT id FROM groups WHERE id=$1 AND org_id=$2 FOR UPDATE`, groupID, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock group: %w", err)
+	}
+	return nil
+}
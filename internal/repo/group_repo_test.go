@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// testRepo connects to TEST_DATABASE_URL, a migrated database the tests may
+// write to. Tests using it are skipped when the variable is unset.
+func testRepo(t *testing.T) Repo {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return Repo{db: db}
+}
+
+// testOrganization creates an organization that is deleted, together with
+// its groups, when the test ends.
+func testOrganization(t *testing.T, r Repo) string {
+	t.Helper()
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	slug := "test-" + hex.EncodeToString(b)
+	var id string
+	if err := r.db.Get(&id, `INSERT INTO organizations (slug, name) VALUES ($1, $1) RETURNING id`, slug); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = r.db.Exec(`DELETE FROM groups WHERE org_id=$1`, id)
+		_, _ = r.db.Exec(`DELETE FROM organizations WHERE id=$1`, id)
+	})
+	return id
+}
+
+func TestAddSubgroupRejectsCycles(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	orgID := testOrganization(t, r)
+	groups := NewGroupRepo(r)
+
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g, err := groups.CreateGroup(ctx, orgID, name, "", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = g.ID
+	}
+	other := testOrganization(t, r)
+	foreign, err := groups.CreateGroup(ctx, other, "a", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids["foreign"] = foreign.ID
+
+	// Steps run in order and build on each other: a contains b contains c.
+	tests := []struct {
+		name          string
+		parent, child string
+		wantErr       error
+	}{
+		{"a contains b", "a", "b", nil},
+		{"b contains c", "b", "c", nil},
+		{"self nesting", "a", "a", ErrGroupCycle},
+		{"direct cycle", "b", "a", ErrGroupCycle},
+		{"indirect cycle", "c", "a", ErrGroupCycle},
+		{"shortcut to a descendant", "a", "c", nil},
+		{"adding the same edge again", "a", "b", nil},
+		{"unrelated group", "c", "d", nil},
+		{"cycle through the new edge", "d", "b", ErrGroupCycle},
+		{"group of another organization", "a", "foreign", ErrNotFound},
+	}
+	for _, tt := range tests {
+		err := groups.AddSubgroup(ctx, orgID, ids[tt.parent], ids[tt.child])
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: AddSubgroup(%s, %s) = %v, want %v", tt.name, tt.parent, tt.child, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvitationNotPending = errors.New("invitation is no longer pending")
+
+const invitationColumns = `id, org_id, email, roles, COALESCE(invited_by::text, '') AS invited_by, COALESCE(user_id::text, '') AS user_id,
+	expires_at, accepted_at, revoked_at, created_at`
+
+type InvitationRepo struct {
+	Repo
+}
+
+func NewInvitationRepo(r Repo) *InvitationRepo {
+	return &InvitationRepo{Repo: r}
+}
+
+func (r *InvitationRepo) CreateInvitation(ctx context.Context, inv *model.Invitation) (*model.Invitation, error) {
+	created := &model.Invitation{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO invitations (org_id, email, roles, invited_by, expires_at)
+		 VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5)
+		 RETURNING `+invitationColumns,
+		inv.OrgID, inv.Email, inv.Roles, inv.InvitedBy, inv.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert invitation: %w", err)
+	}
+	return created, nil
+}
+
+func (r *InvitationRepo) GetInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+	err := r.conn(ctx).GetContext(ctx, inv, `SELECT `+invitationColumns+` FROM invitations WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *InvitationRepo) ListInvitations(ctx context.Context, orgID string, pendingOnly bool) ([]*model.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations WHERE org_id=$1`
+	if pendingOnly {
+		query += ` AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	var invitations []*model.Invitation
+	if err := r.conn(ctx).SelectContext(ctx, &invitations, query, orgID); err != nil {
+		return nil, fmt.Errorf("list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (r *InvitationRepo) ListUserInvitations(ctx context.Context, userID, email string) ([]*model.Invitation, error) {
+	var invitations []*model.Invitation
+	err := r.conn(ctx).SelectContext(ctx, &invitations,
+		`SELECT `+invitationColumns+` FROM invitations WHERE user_id=$1 OR lower(email)=lower($2) ORDER BY created_at DESC`, userID, email)
+	if err != nil {
+		return nil, fmt.Errorf("list user invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (r *InvitationRepo) RevokeInvitation(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE invitations SET revoked_at=now() WHERE id=$1 AND org_id=$2 AND accepted_at IS NULL AND revoked_at IS NULL`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("revoke invitation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrInvitationNotPending
+	}
+	return nil
+}
+
+func (r *InvitationRepo) AcceptInvitation(ctx context.Context, id, username, password string) (entity.UserEntity, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	inv := &model.Invitation{}
+	err = tx.GetContext(ctx, inv, `SELECT `+invitationColumns+` FROM invitations WHERE id=$1 FOR UPDATE`, id)
+	if err != nil {
+		return nil, fmt.Errorf("load invitation: %w", err)
+	}
+	if inv.Status(time.Now()) != model.InvitationPending {
+		return nil, ErrInvitationNotPending
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	// invitation roles apply inside the organization, not service-wide
+	user := &model.User{
+		Email:         inv.Email,
+		Username:      username,
+		PasswordHash:  string(hash),
+		EmailVerified: true,
+		Status:        entity.UserStatusActive,
+	}
+	err = tx.GetContext(ctx, &user.ID,
+		`INSERT INTO users (email, username, password_hash, email_verified) VALUES ($1, $2, $3, true) RETURNING id`,
+		user.Email, user.Username, user.PasswordHash)
+	if isUsernameViolation(err) {
+		return nil, ErrUsernameTaken
+	}
+	if isUniqueViolation(err) {
+		return nil, NewErrUserExists(inv.Email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert user: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO organization_members (org_id, user_id, roles) VALUES ($1, $2, $3)`, inv.OrgID, user.ID, inv.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("insert organization member: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE invitations SET accepted_at=now(), user_id=$1 WHERE id=$2`, user.ID, id)
+	if err != nil {
+		return nil, fmt.Errorf("mark invitation accepted: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return user, nil
+}
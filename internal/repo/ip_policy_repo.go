@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const ipPolicyColumns = `id, COALESCE(org_id::text, '') AS org_id, method, action, cidr::text AS cidr, description,
+	COALESCE(created_by::text, '') AS created_by, created_at`
+
+type IPPolicyRepo struct {
+	Repo
+}
+
+// NewIPPolicyRepo shares the connection pool of an existing repository.
+func NewIPPolicyRepo(r Repo) *IPPolicyRepo {
+	return &IPPolicyRepo{Repo: r}
+}
+
+func (r *IPPolicyRepo) ListIPPolicies(ctx context.Context) ([]*model.IPPolicy, error) {
+	var policies []*model.IPPolicy
+	err := r.conn(ctx).SelectContext(ctx, &policies,
+		`SELECT `+ipPolicyColumns+` FROM ip_policies ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("list ip policies: %w", err)
+	}
+	return policies, nil
+}
+
+func (r *IPPolicyRepo) CreateIPPolicy(ctx context.Context, p *model.IPPolicy) (*model.IPPolicy, error) {
+	created := &model.IPPolicy{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO ip_policies (org_id, method, action, cidr, description, created_by)
+		 VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, NULLIF($6, '')::uuid)
+		 RETURNING `+ipPolicyColumns,
+		p.OrgID, p.Method, p.Action, p.CIDR, p.Description, p.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("insert ip policy: %w", err)
+	}
+	return created, nil
+}
+
+func (r *IPPolicyRepo) DeleteIPPolicy(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM ip_policies WHERE id=$1 AND org_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("delete ip policy: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const oneTimeTokenColumns = `t.id, t.user_id, t.purpose, t.token_hash, t.expires_at, t.attempts, t.consumed_at, t.created_at`
+
+// liveOneTimeToken matches unused, unexpired tokens of live users. Queries
+// using it alias one_time_tokens as t and join users as u.
+const liveOneTimeToken = `t.consumed_at IS NULL AND t.expires_at > now() AND u.id = t.user_id AND u.deleted_at IS NULL`
+
+type OneTimeTokenRepo struct {
+	Repo
+}
+
+func NewOneTimeTokenRepo(r Repo) *OneTimeTokenRepo {
+	return &OneTimeTokenRepo{Repo: r}
+}
+
+func (r *OneTimeTokenRepo) CreateOneTimeToken(ctx context.Context, t *model.OneTimeToken) (*model.OneTimeToken, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM one_time_tokens WHERE user_id=$1 AND purpose=$2`, t.UserID, t.Purpose)
+	if err != nil {
+		return nil, fmt.Errorf("delete earlier tokens: %w", err)
+	}
+
+	created := &model.OneTimeToken{}
+	err = tx.GetContext(ctx, created,
+		`INSERT INTO one_time_tokens AS t (user_id, purpose, token_hash, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+oneTimeTokenColumns,
+		t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert one-time token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return created, nil
+}
+
+func (r *OneTimeTokenRepo) ConsumeOneTimeToken(ctx context.Context, purpose, hash string) (*model.OneTimeToken, error) {
+	t := &model.OneTimeToken{}
+	err := r.conn(ctx).GetContext(ctx, t,
+		`UPDATE one_time_tokens t SET consumed_at=now()
+		 FROM users u
+		 WHERE t.token_hash=$1 AND t.purpose=$2 AND `+liveOneTimeToken+`
+		 RETURNING `+oneTimeTokenColumns,
+		hash, purpose)
+	if err != nil {
+		return nil, fmt.Errorf("consume one-time token: %w", err)
+	}
+	return t, nil
+}
+
+func (r *OneTimeTokenRepo) ConsumeUserOneTimeToken(ctx context.Context, userID, purpose, hash string, maxAttempts int) (*model.OneTimeToken, error) {
+	t := &model.OneTimeToken{}
+	// 同一条语句里判断并计数，并发猜测也无法超过 maxAttempts
+	err := r.conn(ctx).GetContext(ctx, t,
+		`UPDATE one_time_tokens t
+		 SET consumed_at = CASE WHEN t.token_hash=$3 THEN now() END,
+		     attempts = t.attempts + CASE WHEN t.token_hash=$3 THEN 0 ELSE 1 END
+		 FROM users u
+		 WHERE t.user_id=$1 AND t.purpose=$2 AND t.attempts < $4 AND `+liveOneTimeToken+`
+		 RETURNING `+oneTimeTokenColumns,
+		userID, purpose, hash, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("consume one-time token: %w", err)
+	}
+	if t.ConsumedAt == nil {
+		return nil, fmt.Errorf("one-time token mismatch: %w", sql.ErrNoRows)
+	}
+	return t, nil
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const organizationColumns = `id, slug, name, COALESCE(registration_mode, '') AS registration_mode,
+	registration_allowed_domains, registration_require_approval,
+	COALESCE(access_token_ttl_seconds, 0) AS access_token_ttl_seconds,
+	COALESCE(refresh_token_ttl_seconds, 0) AS refresh_token_ttl_seconds, created_at, updated_at`
+
+const organizationMemberQuery = `SELECT m.org_id, o.slug AS org_slug, o.name AS org_name, m.user_id, u.username, u.email, m.roles, m.created_at
+	FROM organization_members m
+	JOIN organizations o ON o.id = m.org_id
+	JOIN users u ON u.id = m.user_id AND u.deleted_at IS NULL`
+
+type OrganizationRepo struct {
+	Repo
+}
+
+func NewOrganizationRepo(r Repo) *OrganizationRepo {
+	return &OrganizationRepo{Repo: r}
+}
+
+func (r *OrganizationRepo) CreateOrganization(ctx context.Context, org *model.Organization, ownerID string, ownerRoles []string) (*model.Organization, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	created := &model.Organization{}
+	err = tx.GetContext(ctx, created,
+		`INSERT INTO organizations (slug, name, registration_mode, registration_allowed_domains, registration_require_approval,
+		 access_token_ttl_seconds, refresh_token_ttl_seconds)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, 0), NULLIF($7, 0))
+		 RETURNING `+organizationColumns,
+		org.Slug, org.Name, org.RegistrationMode, pq.StringArray(append([]string{}, org.RegistrationAllowedDomains...)),
+		org.RegistrationRequireApproval, org.AccessTokenTTLSeconds, org.RefreshTokenTTLSeconds)
+	if isUniqueViolation(err) {
+		return nil, ErrOrganizationSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert organization: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO organization_members (org_id, user_id, roles) VALUES ($1, $2, $3)`,
+		created.ID, ownerID, pq.StringArray(append([]string{}, ownerRoles...)))
+	if err != nil {
+		return nil, fmt.Errorf("insert owner membership: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return created, nil
+}
+
+func (r *OrganizationRepo) GetOrganization(ctx context.Context, id string) (*model.Organization, error) {
+	org := &model.Organization{}
+	err := r.conn(ctx).GetContext(ctx, org, `SELECT `+organizationColumns+` FROM organizations WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepo) GetOrganizationBySlug(ctx context.Context, slug string) (*model.Organization, error) {
+	org := &model.Organization{}
+	err := r.conn(ctx).GetContext(ctx, org, `SELECT `+organizationColumns+` FROM organizations WHERE slug=lower($1)`, slug)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepo) ListOrganizations(ctx context.Context) ([]*model.Organization, error) {
+	var orgs []*model.Organization
+	if err := r.conn(ctx).SelectContext(ctx, &orgs, `SELECT `+organizationColumns+` FROM organizations ORDER BY slug`); err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+func (r *OrganizationRepo) UpdateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error) {
+	updated := &model.Organization{}
+	err := r.conn(ctx).GetContext(ctx, updated,
+		`UPDATE organizations SET name=$1, registration_mode=NULLIF($2, ''), registration_allowed_domains=$3,
+		 registration_require_approval=$4, access_token_ttl_seconds=NULLIF($5, 0),
+		 refresh_token_ttl_seconds=NULLIF($6, 0), updated_at=now()
+		 WHERE id=$7
+		 RETURNING `+organizationColumns,
+		org.Name, org.RegistrationMode, pq.StringArray(append([]string{}, org.RegistrationAllowedDomains...)),
+		org.RegistrationRequireApproval, org.AccessTokenTTLSeconds, org.RefreshTokenTTLSeconds, org.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update organization: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *OrganizationRepo) GetMembership(ctx context.Context, orgID, userID string) (*model.OrganizationMember, error) {
+	m := &model.OrganizationMember{}
+	err := r.conn(ctx).GetContext(ctx, m, organizationMemberQuery+` WHERE m.org_id=$1 AND m.user_id=$2`, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context, orgID string) ([]*model.OrganizationMember, error) {
+	var members []*model.OrganizationMember
+	err := r.conn(ctx).SelectContext(ctx, &members, organizationMemberQuery+` WHERE m.org_id=$1 ORDER BY u.username`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list organization members: %w", err)
+	}
+	return members, nil
+}
+
+func (r *OrganizationRepo) ListUserMemberships(ctx context.Context, userID string) ([]*model.OrganizationMember, error) {
+	var members []*model.OrganizationMember
+	err := r.conn(ctx).SelectContext(ctx, &members, organizationMemberQuery+` WHERE m.user_id=$1 ORDER BY m.created_at, o.slug`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user memberships: %w", err)
+	}
+	return members, nil
+}
+
+func (r *OrganizationRepo) SetMember(ctx context.Context, orgID, userID string, roles []string) (*model.OrganizationMember, error) {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`INSERT INTO organization_members (org_id, user_id, roles)
+		 SELECT $1, u.id, $3 FROM users u WHERE u.id=$2 AND u.deleted_at IS NULL
+		 ON CONFLICT (org_id, user_id) DO UPDATE SET roles=EXCLUDED.roles`,
+		orgID, userID, pq.StringArray(append([]string{}, roles...)))
+	if err != nil {
+		return nil, fmt.Errorf("set organization member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return r.GetMembership(ctx, orgID, userID)
+}
+
+// RemoveMember drops the membership together with the user's place in the
+// organization's groups.
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, orgID, userID string) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM organization_members WHERE org_id=$1 AND user_id=$2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("remove organization member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM group_members gm USING groups g WHERE g.id = gm.group_id AND g.org_id=$1 AND gm.user_id=$2`,
+		orgID, userID)
+	if err != nil {
+		return fmt.Errorf("remove group members: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+const outboxColumns = `id, topic, payload, status, attempts, next_attempt_at, locked_until, last_error, created_at, processed_at`
+
+type OutboxRepo struct {
+	Repo
+}
+
+func NewOutboxRepo(r Repo) *OutboxRepo {
+	return &OutboxRepo{Repo: r}
+}
+
+func (r *OutboxRepo) EnqueueOutbox(ctx context.Context, topic string, payload json.RawMessage) (*model.OutboxMessage, error) {
+	m := &model.OutboxMessage{}
+	err := r.conn(ctx).GetContext(ctx, m,
+		`INSERT INTO outbox (topic, payload) VALUES ($1, $2) RETURNING `+outboxColumns,
+		topic, string(payload))
+	if err != nil {
+		return nil, fmt.Errorf("enqueue outbox: %w", err)
+	}
+	return m, nil
+}
+
+func (r *OutboxRepo) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxMessage, error) {
+	var msgs []*model.OutboxMessage
+	err := r.conn(ctx).SelectContext(ctx, &msgs,
+		`UPDATE outbox SET locked_until = now() + make_interval(secs => $2), attempts = attempts + 1
+		 WHERE id IN (
+		     SELECT id FROM outbox
+		     WHERE status = $3 AND next_attempt_at <= now()
+		       AND (locked_until IS NULL OR locked_until < now())
+		     ORDER BY next_attempt_at
+		     LIMIT $1
+		     FOR UPDATE SKIP LOCKED)
+		 RETURNING `+outboxColumns,
+		limit, lease.Seconds(), entity.OutboxPending)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox: %w", err)
+	}
+	return msgs, nil
+}
+
+func (r *OutboxRepo) MarkOutboxSent(ctx context.Context, id string) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE outbox SET status=$2, payload='{}', processed_at=now(), locked_until=NULL, last_error=NULL WHERE id=$1`,
+		id, entity.OutboxSent)
+	if err != nil {
+		return fmt.Errorf("mark outbox sent: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepo) RetryOutbox(ctx context.Context, id string, next time.Time, lastError string) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE outbox SET next_attempt_at=$2, last_error=$3, locked_until=NULL WHERE id=$1`,
+		id, next, lastError)
+	if err != nil {
+		return fmt.Errorf("retry outbox: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepo) DeadOutbox(ctx context.Context, id string, lastError string) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE outbox SET status=$2, last_error=$3, processed_at=now(), locked_until=NULL WHERE id=$1`,
+		id, entity.OutboxDead, lastError)
+	if err != nil {
+		return fmt.Errorf("dead-letter outbox: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepo) CountOutbox(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err := r.conn(ctx).SelectContext(ctx, &rows, `SELECT status, count(*) AS count FROM outbox GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("count outbox: %w", err)
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *OutboxRepo) PurgeOutbox(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM outbox WHERE status=$1 AND processed_at < $2`, entity.OutboxSent, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const personalAccessTokenColumns = `id, user_id, org_id, name, prefix, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+type PersonalAccessTokenRepo struct {
+	Repo
+}
+
+func NewPersonalAccessTokenRepo(r Repo) *PersonalAccessTokenRepo {
+	return &PersonalAccessTokenRepo{Repo: r}
+}
+
+func (r *PersonalAccessTokenRepo) CreatePersonalAccessToken(ctx context.Context, t *model.PersonalAccessToken) (*model.PersonalAccessToken, error) {
+	created := &model.PersonalAccessToken{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO personal_access_tokens (user_id, org_id, name, prefix, token_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+personalAccessTokenColumns,
+		t.UserID, t.OrgID, t.Name, t.Prefix, t.TokenHash, t.Scopes, t.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert personal access token: %w", err)
+	}
+	return created, nil
+}
+
+func (r *PersonalAccessTokenRepo) GetPersonalAccessTokenByHash(ctx context.Context, hash string) (*model.PersonalAccessToken, error) {
+	t := &model.PersonalAccessToken{}
+	err := r.conn(ctx).GetContext(ctx, t,
+		`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens WHERE token_hash=$1`, hash)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *PersonalAccessTokenRepo) ListPersonalAccessTokens(ctx context.Context, userID string) ([]*model.PersonalAccessToken, error) {
+	var tokens []*model.PersonalAccessToken
+	err := r.conn(ctx).SelectContext(ctx, &tokens,
+		`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *PersonalAccessTokenRepo) RevokePersonalAccessToken(ctx context.Context, userID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE personal_access_tokens SET revoked_at=now() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return fmt.Errorf("revoke personal access token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepo) TouchPersonalAccessToken(ctx context.Context, id string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE personal_access_tokens SET last_used_at=now() WHERE id=$1`, id)
+	return err
+}
@@ -18,6 +18,8 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -33,7 +35,7 @@ func (r *RedisCache) SetBlacklist(ctx context.Context, token string, ttl time.Du
 }
 
 func (r *RedisCache) DeleteRefreshToken(ctx context.Context, userID string) error {
-	key := "refresh_token:" + userID
+	key := "token:" + userID
 	return r.client.Del(ctx, key).Err()
 }
 
@@ -53,6 +55,89 @@ func (r *RedisCache) GetToken(ctx context.Context, userID string) (string, error
 	return r.client.Get(ctx, "token:"+userID).Result()
 }
 
+func (r *RedisCache) GetProfile(ctx context.Context, userID string) (string, error) {
+	return r.client.Get(ctx, "profile:"+userID).Result()
+}
+
+func (r *RedisCache) StoreProfile(ctx context.Context, userID, profile string, ttl time.Duration) error {
+	return r.client.Set(ctx, "profile:"+userID, profile, ttl).Err()
+}
+
+func (r *RedisCache) DeleteProfile(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, "profile:"+userID).Err()
+}
+
+// RevokeUserTokens marks every token of the user issued up to at as revoked.
+// The time is stored in Unix milliseconds, the precision of the iat claim.
+// ttl should cover the longest token lifetime.
+func (r *RedisCache) RevokeUserTokens(ctx context.Context, userID string, at time.Time, ttl time.Duration) error {
+	return r.client.Set(ctx, "revoked_before:"+userID, at.UnixMilli(), ttl).Err()
+}
+
+// GetTokensRevokedAt returns the time set by RevokeUserTokens, or the zero
+// time if the user's tokens were never revoked.
+func (r *RedisCache) GetTokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	ms, err := r.client.Get(ctx, "revoked_before:"+userID).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// GetEffectiveRoles returns the cached effective roles of userID in orgID
+// together with the organization's cache version. Entries are keyed by
+// that version, so bumping it with InvalidateEffectiveRoles drops every
+// entry of the organization at once.
+func (r *RedisCache) GetEffectiveRoles(ctx context.Context, orgID, userID string) (string, int64, error) {
+	version, err := r.client.Get(ctx, "effective_roles_version:"+orgID).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", 0, err
+	}
+	data, err := r.client.Get(ctx, effectiveRolesKey(orgID, version, userID)).Result()
+	return data, version, err
+}
+
+// StoreEffectiveRoles caches data under the version GetEffectiveRoles
+// returned, so a value computed before an invalidation is never served
+// after it.
+func (r *RedisCache) StoreEffectiveRoles(ctx context.Context, orgID, userID string, version int64, data string, ttl time.Duration) error {
+	return r.client.Set(ctx, effectiveRolesKey(orgID, version, userID), data, ttl).Err()
+}
+
+func (r *RedisCache) InvalidateEffectiveRoles(ctx context.Context, orgID string) error {
+	return r.client.Incr(ctx, "effective_roles_version:"+orgID).Err()
+}
+
+func effectiveRolesKey(orgID string, version int64, userID string) string {
+	return fmt.Sprintf("effective_roles:%s:%d:%s", orgID, version, userID)
+}
+
+// CountVerificationResend records a verification resend to addr and
+// returns how many were made in the current window, which starts with the
+// first one.
+func (r *RedisCache) CountVerificationResend(ctx context.Context, addr string, window time.Duration) (int64, error) {
+	key := "verify_resend:" + addr
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// MarkAssertionUsed records a client assertion jti. It reports false when
+// the jti was already seen, i.e. the assertion is being replayed.
+func (r *RedisCache) MarkAssertionUsed(ctx context.Context, jti string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, "client_assertion:"+jti, "1", ttl).Result()
+}
+
 func (r *RedisCache) Close() error {
 	return r.client.Close()
 }
@@ -16,7 +16,13 @@
 
 package repo
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
 
 type Repo struct {
 	db *sqlx.DB
@@ -28,3 +34,76 @@ func (r *Repo) Close() {
 		return
 	}
 }
+
+type txKey struct{}
+
+// querier is what *sqlx.DB and *sqlx.Tx have in common.
+type querier interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// InTx runs fn in a transaction. Repository calls made with the context
+// passed to fn, from any repo on the same database, join that transaction,
+// so a domain change and the outbox rows describing it commit together.
+// Nested calls reuse the outer transaction.
+func (r *Repo) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// conn returns the transaction started by InTx, or the pool.
+func (r *Repo) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// repoTx is a transaction a repository method needs for itself. Inside
+// InTx it is the caller's transaction, and Commit and Rollback are left to
+// InTx.
+type repoTx struct {
+	*sqlx.Tx
+	joined bool
+}
+
+func (r *Repo) begin(ctx context.Context) (*repoTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return &repoTx{Tx: tx, joined: true}, nil
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &repoTx{Tx: tx}, nil
+}
+
+func (t *repoTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *repoTx) Rollback() error {
+	if t.joined {
+		return sql.ErrTxDone
+	}
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const scimClientColumns = `id, org_id, name, prefix, token_hash, COALESCE(created_by::text, '') AS created_by, last_used_at, revoked_at, created_at`
+
+type ScimClientRepo struct {
+	Repo
+}
+
+func NewScimClientRepo(r Repo) *ScimClientRepo {
+	return &ScimClientRepo{Repo: r}
+}
+
+func (r *ScimClientRepo) CreateScimClient(ctx context.Context, c *model.ScimClient) (*model.ScimClient, error) {
+	created := &model.ScimClient{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO scim_clients (org_id, name, prefix, token_hash, created_by)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid)
+		 RETURNING `+scimClientColumns,
+		c.OrgID, c.Name, c.Prefix, c.TokenHash, c.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("insert scim client: %w", err)
+	}
+	return created, nil
+}
+
+func (r *ScimClientRepo) GetScimClientByHash(ctx context.Context, hash string) (*model.ScimClient, error) {
+	c := &model.ScimClient{}
+	err := r.conn(ctx).GetContext(ctx, c,
+		`SELECT `+scimClientColumns+` FROM scim_clients WHERE token_hash=$1 AND revoked_at IS NULL`, hash)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *ScimClientRepo) ListScimClients(ctx context.Context, orgID string) ([]*model.ScimClient, error) {
+	var clients []*model.ScimClient
+	err := r.conn(ctx).SelectContext(ctx, &clients,
+		`SELECT `+scimClientColumns+` FROM scim_clients WHERE org_id=$1 ORDER BY created_at DESC`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list scim clients: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *ScimClientRepo) RevokeScimClient(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE scim_clients SET revoked_at=now() WHERE id=$1 AND org_id=$2 AND revoked_at IS NULL`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("revoke scim client: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *ScimClientRepo) TouchScimClient(ctx context.Context, id string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE scim_clients SET last_used_at=now() WHERE id=$1`, id)
+	return err
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+type columnKind int
+
+const (
+	colString columnKind = iota
+	// colFolded compares case-insensitively; expr must already be lower().
+	colFolded
+	colBool
+	colTime
+	// colArray is a text[] expression; only eq, ne and pr are supported.
+	colArray
+)
+
+type filterColumn struct {
+	expr string
+	kind columnKind
+}
+
+// userFilterColumns maps SCIM User attributes onto the users table. Roles
+// are those held in the organization bound to $1.
+var userFilterColumns = map[string]filterColumn{
+	"id":                {"id::text", colString},
+	"username":          {"lower(username)", colFolded},
+	"externalid":        {"external_id", colString},
+	"name.givenname":    {"given_name", colString},
+	"name.familyname":   {"family_name", colString},
+	"emails":            {"lower(email)", colFolded},
+	"emails.value":      {"lower(email)", colFolded},
+	"emails.type":       {"'work'", colString},
+	"emails.primary":    {"true", colBool},
+	"active":            {"(status = 'active')", colBool},
+	"roles":             {orgRolesExpr, colArray},
+	"roles.value":       {orgRolesExpr, colArray},
+	"groups":            {"ARRAY(SELECT gm.group_id::text FROM group_members gm WHERE gm.user_id = users.id)", colArray},
+	"groups.value":      {"ARRAY(SELECT gm.group_id::text FROM group_members gm WHERE gm.user_id = users.id)", colArray},
+	"meta.created":      {"created_at", colTime},
+	"meta.lastmodified": {"updated_at", colTime},
+}
+
+const orgRolesExpr = "COALESCE((SELECT m.roles FROM organization_members m WHERE m.user_id = users.id AND m.org_id = $1), '{}')"
+
+// groupFilterColumns maps SCIM Group attributes onto the groups table.
+var groupFilterColumns = map[string]filterColumn{
+	"id":                {"id::text", colString},
+	"displayname":       {"lower(display_name)", colFolded},
+	"externalid":        {"external_id", colString},
+	"members":           {"ARRAY(SELECT gm.user_id::text FROM group_members gm WHERE gm.group_id = groups.id)", colArray},
+	"members.value":     {"ARRAY(SELECT gm.user_id::text FROM group_members gm WHERE gm.group_id = groups.id)", colArray},
+	"meta.created":      {"created_at", colTime},
+	"meta.lastmodified": {"updated_at", colTime},
+}
+
+// filterSQL renders a SCIM filter as a WHERE fragment. Values are appended
+// to args and referenced as $n, so args may already hold earlier parameters.
+type filterSQL struct {
+	columns map[string]filterColumn
+	args    []interface{}
+}
+
+func (b *filterSQL) render(f scim.Filter, prefix string) (string, error) {
+	switch f := f.(type) {
+	case *scim.Logical:
+		left, err := b.render(f.Left, prefix)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.render(f.Right, prefix)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + strings.ToUpper(f.Op) + " " + right + ")", nil
+	case *scim.Not:
+		inner, err := b.render(f.Filter, prefix)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + inner, nil
+	case *scim.ValuePath:
+		return b.render(f.Filter, prefix+f.Attr+".")
+	case *scim.Present:
+		col, err := b.column(prefix + f.Attr)
+		if err != nil {
+			return "", err
+		}
+		switch col.kind {
+		case colString, colFolded:
+			return "(" + col.expr + " IS NOT NULL AND " + col.expr + " <> '')", nil
+		case colArray:
+			return "(cardinality(" + col.expr + ") > 0)", nil
+		default:
+			return "(" + col.expr + " IS NOT NULL)", nil
+		}
+	case *scim.Compare:
+		return b.compare(prefix+f.Attr, f.Op, f.Value)
+	}
+	return "", fmt.Errorf("%w: unsupported expression", scim.ErrInvalidFilter)
+}
+
+func (b *filterSQL) column(attr string) (filterColumn, error) {
+	col, ok := b.columns[attr]
+	if !ok {
+		return filterColumn{}, fmt.Errorf("%w: unsupported attribute %q", scim.ErrInvalidFilter, attr)
+	}
+	return col, nil
+}
+
+func (b *filterSQL) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *filterSQL) compare(attr, op string, value interface{}) (string, error) {
+	col, err := b.column(attr)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		switch op {
+		case "eq":
+			return "(" + col.expr + " IS NULL)", nil
+		case "ne":
+			return "(" + col.expr + " IS NOT NULL)", nil
+		}
+		return "", fmt.Errorf("%w: null only supports eq and ne", scim.ErrInvalidFilter)
+	}
+
+	switch col.kind {
+	case colBool:
+		v, ok := value.(bool)
+		if !ok || (op != "eq" && op != "ne") {
+			return "", fmt.Errorf("%w: %s is boolean", scim.ErrInvalidFilter, attr)
+		}
+		if op == "ne" {
+			v = !v
+		}
+		return "(" + col.expr + " = " + b.arg(v) + ")", nil
+
+	case colTime:
+		s, ok := value.(string)
+		t, err := time.Parse(time.RFC3339, s)
+		if !ok || err != nil {
+			return "", fmt.Errorf("%w: %s must be an RFC 3339 timestamp", scim.ErrInvalidFilter, attr)
+		}
+		sqlOp, ok := orderingOps[op]
+		if !ok {
+			return "", fmt.Errorf("%w: %s does not support %s", scim.ErrInvalidFilter, attr, op)
+		}
+		return "(" + col.expr + " " + sqlOp + " " + b.arg(t) + ")", nil
+
+	case colArray:
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: %s must be compared with a string", scim.ErrInvalidFilter, attr)
+		}
+		switch op {
+		case "eq":
+			return "(" + b.arg(s) + " = ANY(" + col.expr + "))", nil
+		case "ne":
+			return "(NOT " + b.arg(s) + " = ANY(" + col.expr + "))", nil
+		}
+		return "", fmt.Errorf("%w: %s only supports eq, ne and pr", scim.ErrInvalidFilter, attr)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be compared with a string", scim.ErrInvalidFilter, attr)
+	}
+	if col.kind == colFolded {
+		s = strings.ToLower(s)
+	}
+	p := b.arg(s)
+	switch op {
+	case "eq":
+		return "(" + col.expr + " = " + p + ")", nil
+	case "ne":
+		return "(" + col.expr + " IS DISTINCT FROM " + p + ")", nil
+	case "co":
+		return "(strpos(" + col.expr + ", " + p + ") > 0)", nil
+	case "sw":
+		return "(left(" + col.expr + ", length(" + p + ")) = " + p + ")", nil
+	case "ew":
+		return "(right(" + col.expr + ", length(" + p + ")) = " + p + ")", nil
+	}
+	return "(" + col.expr + " " + orderingOps[op] + " " + p + ")", nil
+}
+
+var orderingOps = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+// whereClause combines a fixed condition, whose parameters are args, with an
+// optional SCIM filter.
+func whereClause(base string, args []interface{}, f scim.Filter, columns map[string]filterColumn) (string, []interface{}, error) {
+	if f == nil {
+		return base, args, nil
+	}
+	b := &filterSQL{columns: columns, args: args}
+	cond, err := b.render(f, "")
+	if err != nil {
+		return "", nil, err
+	}
+	return base + " AND " + cond, b.args, nil
+}
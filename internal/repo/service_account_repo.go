@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+const serviceAccountColumns = `id, org_id, client_id, name, COALESCE(secret_hash, '') AS secret_hash, COALESCE(public_key, '') AS public_key,
+	scopes, audiences, COALESCE(created_by::text, '') AS created_by, disabled_at, created_at`
+
+type ServiceAccountRepo struct {
+	Repo
+}
+
+func NewServiceAccountRepo(r Repo) *ServiceAccountRepo {
+	return &ServiceAccountRepo{Repo: r}
+}
+
+func (r *ServiceAccountRepo) CreateServiceAccount(ctx context.Context, sa *model.ServiceAccount) (*model.ServiceAccount, error) {
+	created := &model.ServiceAccount{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO service_accounts (org_id, client_id, name, secret_hash, public_key, scopes, audiences, created_by)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, NULLIF($8, '')::uuid)
+		 RETURNING `+serviceAccountColumns,
+		sa.OrgID, sa.ClientID, sa.Name, sa.SecretHash, sa.PublicKey, sa.Scopes, sa.Audiences, sa.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("insert service account: %w", err)
+	}
+	return created, nil
+}
+
+func (r *ServiceAccountRepo) GetServiceAccountByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error) {
+	sa := &model.ServiceAccount{}
+	err := r.conn(ctx).GetContext(ctx, sa,
+		`SELECT `+serviceAccountColumns+` FROM service_accounts WHERE client_id=$1 AND disabled_at IS NULL`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return sa, nil
+}
+
+func (r *ServiceAccountRepo) ListServiceAccounts(ctx context.Context, orgID string) ([]*model.ServiceAccount, error) {
+	var accounts []*model.ServiceAccount
+	err := r.conn(ctx).SelectContext(ctx, &accounts,
+		`SELECT `+serviceAccountColumns+` FROM service_accounts WHERE org_id=$1 ORDER BY created_at DESC`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list service accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+func (r *ServiceAccountRepo) DisableServiceAccount(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE service_accounts SET disabled_at=now() WHERE id=$1 AND org_id=$2 AND disabled_at IS NULL`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("disable service account: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -18,14 +18,33 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/shinoda4/sd-svc-auth/internal/model"
 	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const userColumns = `id, email, username, password_hash, email_verified, roles, status,
+	COALESCE(pending_email, '') AS pending_email, COALESCE(external_id, '') AS external_id,
+	COALESCE(given_name, '') AS given_name, COALESCE(family_name, '') AS family_name,
+	user_metadata, app_metadata, created_at, updated_at`
+
+// orgUserColumns is userColumns with roles replaced by those held in the
+// organization bound to $1.
+const orgUserColumns = `id, email, username, password_hash, email_verified, ` + orgRolesExpr + ` AS roles, status,
+	COALESCE(pending_email, '') AS pending_email, COALESCE(external_id, '') AS external_id,
+	COALESCE(given_name, '') AS given_name, COALESCE(family_name, '') AS family_name,
+	user_metadata, app_metadata, created_at, updated_at`
+
+const orgMemberCondition = `deleted_at IS NULL
+	AND EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = users.id AND m.org_id = $1)`
+
 type UserRepo struct {
 	Repo
 }
@@ -38,24 +57,15 @@ func NewUserRepo(dsn string) (*UserRepo, error) {
 	return &UserRepo{Repo: *repo}, nil
 }
 
-func (r *UserRepo) GetUserByVerifyToken(ctx context.Context, token string) (entity.UserEntity, error) {
-	u := &model.User{}
-	err := r.db.GetContext(ctx, u,
-		`SELECT id, email, username, password_hash FROM users WHERE verify_token=$1`, token)
-	if err != nil {
-		return nil, fmt.Errorf("query user failed: %w", err)
-	}
-	return u, nil
-}
 func (r *UserRepo) SetEmailVerified(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE users SET email_verified=true, verify_token=NULL WHERE id=$1`, userID)
+	_, err := r.conn(ctx).ExecContext(ctx, `UPDATE users SET email_verified=true WHERE id=$1`, userID)
 	return err
 }
 
-func (r *UserRepo) CreateUser(ctx context.Context, email, username, password string) (entity.UserEntity, error) {
+func (r *UserRepo) CreateUser(ctx context.Context, email, username, password, status string) (entity.UserEntity, error) {
 	var exists bool
 
-	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`, email)
+	err := r.conn(ctx).GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`, email)
 	if err != nil {
 		return nil, fmt.Errorf("check user existence: %w", err)
 	}
@@ -63,15 +73,23 @@ func (r *UserRepo) CreateUser(ctx context.Context, email, username, password str
 		return nil, NewErrUserExists(email)
 	}
 
+	err = r.conn(ctx).GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE lower(username) = lower($1))`, username)
+	if err != nil {
+		return nil, fmt.Errorf("check username existence: %w", err)
+	}
+	if exists {
+		return nil, ErrUsernameTaken
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
 	var id string
-	err = r.db.GetContext(ctx, &id,
-		`INSERT INTO users (email, username, password_hash) VALUES ($1, $2, $3) RETURNING id`,
-		email, username, string(hash))
+	err = r.conn(ctx).GetContext(ctx, &id,
+		`INSERT INTO users (email, username, password_hash, status) VALUES ($1, $2, $3, $4) RETURNING id`,
+		email, username, string(hash), status)
 	if err != nil {
 		return nil, fmt.Errorf("insert user: %w", err)
 	}
@@ -81,58 +99,343 @@ func (r *UserRepo) CreateUser(ctx context.Context, email, username, password str
 		Email:        email,
 		Username:     username,
 		PasswordHash: string(hash),
+		Status:       status,
 	}
 	return user, nil
 }
 
 func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (entity.UserEntity, error) {
 	u := &model.User{}
-	err := r.db.GetContext(ctx, u, `SELECT id, email, username, password_hash, email_verified FROM users WHERE email=$1`, email)
+	err := r.conn(ctx).GetContext(ctx, u, `SELECT `+userColumns+` FROM users WHERE email=$1 AND deleted_at IS NULL`, email)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
-func (r *UserRepo) SetVerifyToken(ctx context.Context, userID, token string) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE users SET verify_token=$1 WHERE id=$2`, token, userID)
-	return err
+// GetUserByUsername matches usernames case-insensitively.
+func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (entity.UserEntity, error) {
+	u := &model.User{}
+	err := r.conn(ctx).GetContext(ctx, u,
+		`SELECT `+userColumns+` FROM users WHERE lower(username)=lower($1) AND deleted_at IS NULL`,
+		username)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
-func (r *UserRepo) SaveResetToken(ctx context.Context, userID, token string, expire time.Time) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE users SET reset_token=$1, reset_token_expire=$2 WHERE id=$3`,
-		token, expire, userID,
-	)
-	return err
+func (r *UserRepo) GetUserByID(ctx context.Context, userID string) (entity.UserEntity, error) {
+	u := &model.User{}
+	err := r.conn(ctx).GetContext(ctx, u,
+		`SELECT `+userColumns+` FROM users WHERE id=$1 AND deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
-func (r *UserRepo) GetUserByResetToken(ctx context.Context, token string) (entity.UserEntity, error) {
+// GetOrgUserByID returns the user if it is a member of orgID, with the roles
+// held there. sql.ErrNoRows is returned for users of other organizations.
+func (r *UserRepo) GetOrgUserByID(ctx context.Context, orgID, userID string) (entity.UserEntity, error) {
 	u := &model.User{}
-	err := r.db.GetContext(ctx, u,
-		`SELECT id, email, reset_token_expire FROM users WHERE reset_token=$1`,
-		token,
-	)
+	err := r.conn(ctx).GetContext(ctx, u,
+		`SELECT `+orgUserColumns+` FROM users WHERE id=$2 AND `+orgMemberCondition, orgID, userID)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
+func (r *UserRepo) UpdateUsername(ctx context.Context, userID, username string) (entity.UserEntity, error) {
+	u := &model.User{}
+	err := r.conn(ctx).GetContext(ctx, u,
+		`UPDATE users SET username=$1, updated_at=now() WHERE id=$2 RETURNING id, email, username`,
+		username, userID)
+	if isUniqueViolation(err) {
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update username: %w", err)
+	}
+	return u, nil
+}
+
+// SetPendingEmail stores the requested address next to the current one and
+// returns the user with its still-active email.
+func (r *UserRepo) SetPendingEmail(ctx context.Context, userID, email string) (entity.UserEntity, error) {
+	u := &model.User{}
+	err := r.conn(ctx).GetContext(ctx, u,
+		`UPDATE users SET pending_email=$1 WHERE id=$2
+		 RETURNING id, email, username, pending_email`,
+		email, userID)
+	if err != nil {
+		return nil, fmt.Errorf("set pending email: %w", err)
+	}
+	return u, nil
+}
+
+// ConfirmEmailChange swaps in the pending address, provided it is still the
+// one that was confirmed.
+func (r *UserRepo) ConfirmEmailChange(ctx context.Context, userID, email string) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE users
+		 SET email=pending_email, pending_email=NULL, email_verified=true, updated_at=now()
+		 WHERE id=$1 AND pending_email=$2`, userID, email)
+	if isUniqueViolation(err) {
+		return NewErrUserExists(email)
+	}
+	if err != nil {
+		return fmt.Errorf("confirm email change: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (r *UserRepo) UpdatePassword(ctx context.Context, userID, newPassword string) error {
 	hashed, _ := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 
-	_, err := r.db.ExecContext(ctx,
+	_, err := r.conn(ctx).ExecContext(ctx,
 		`UPDATE users SET password_hash=$1 WHERE id=$2`,
 		hashed, userID,
 	)
 	return err
 }
 
-func (r *UserRepo) ClearResetToken(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE users SET reset_token=NULL, reset_token_expire=NULL WHERE id=$1`,
-		userID,
+// SoftDeleteUser hides the account from every lookup and schedules the row
+// for removal by PurgeDeletedUsers once deleteAfter has passed.
+func (r *UserRepo) SoftDeleteUser(ctx context.Context, userID string, deleteAfter time.Time) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`WITH tokens AS (DELETE FROM one_time_tokens WHERE user_id=$2)
+		 UPDATE users SET deleted_at=now(), delete_after=$1 WHERE id=$2 AND deleted_at IS NULL`,
+		deleteAfter, userID,
 	)
-	return err
+	if err != nil {
+		return fmt.Errorf("soft delete user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) PurgeDeletedUsers(ctx context.Context, before time.Time) (map[string][]string, error) {
+	// 同一语句的快照中仍能看到被级联删除的成员关系
+	var rows []struct {
+		ID     string         `db:"id"`
+		OrgIDs pq.StringArray `db:"org_ids"`
+	}
+	err := r.conn(ctx).SelectContext(ctx, &rows,
+		`WITH purged AS (
+		     DELETE FROM users WHERE delete_after IS NOT NULL AND delete_after <= $1 RETURNING id
+		 )
+		 SELECT p.id, COALESCE(array_agg(m.org_id::text) FILTER (WHERE m.org_id IS NOT NULL), '{}') AS org_ids
+		 FROM purged p LEFT JOIN organization_members m ON m.user_id = p.id
+		 GROUP BY p.id`, before)
+	if err != nil {
+		return nil, fmt.Errorf("purge deleted users: %w", err)
+	}
+	purged := make(map[string][]string, len(rows))
+	for _, row := range rows {
+		purged[row.ID] = row.OrgIDs
+	}
+	return purged, nil
+}
+
+// ListUsersByStatus lists the members of orgID in status.
+func (r *UserRepo) ListUsersByStatus(ctx context.Context, orgID, status string) ([]entity.UserEntity, error) {
+	var users []*model.User
+	err := r.conn(ctx).SelectContext(ctx, &users,
+		`SELECT `+orgUserColumns+` FROM users WHERE status=$2 AND `+orgMemberCondition+` ORDER BY created_at`,
+		orgID, status)
+	if err != nil {
+		return nil, fmt.Errorf("list users by status: %w", err)
+	}
+
+	result := make([]entity.UserEntity, len(users))
+	for i, u := range users {
+		result[i] = u
+	}
+	return result, nil
+}
+
+// UpdateUserStatus moves a user from one status to another and returns the
+// updated user. ErrNotFound is returned when the user is not in status from.
+func (r *UserRepo) UpdateUserStatus(ctx context.Context, userID, from, to string) (entity.UserEntity, error) {
+	u := &model.User{}
+	err := r.conn(ctx).GetContext(ctx, u,
+		`UPDATE users SET status=$1, updated_at=now() WHERE id=$2 AND status=$3 AND deleted_at IS NULL
+		 RETURNING `+userColumns, to, userID, from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update user status: %w", err)
+	}
+	return u, nil
+}
+
+// QueryUsers returns one page of q.OrgID's members matching q.Filter and
+// the total number of matches.
+func (r *UserRepo) QueryUsers(ctx context.Context, q entity.ListQuery) ([]entity.UserEntity, int, error) {
+	where, args, err := whereClause(orgMemberCondition, []interface{}{q.OrgID}, q.Filter, userFilterColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.conn(ctx).GetContext(ctx, &total, `SELECT COUNT(*) FROM users WHERE `+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+	if q.Limit <= 0 || total == 0 {
+		return nil, total, nil
+	}
+
+	var rows []*model.User
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE %s ORDER BY created_at, id LIMIT %d OFFSET %d`,
+		orgUserColumns, where, q.Limit, max(q.Offset, 0))
+	if err := r.conn(ctx).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, fmt.Errorf("query users: %w", err)
+	}
+	users := make([]entity.UserEntity, 0, len(rows))
+	for _, u := range rows {
+		users = append(users, u)
+	}
+	return users, total, nil
+}
+
+// CreateProvisionedUser inserts a user managed by an identity provider and
+// makes it a member of orgID. The password hash is not a valid bcrypt hash,
+// so password login stays impossible until the user resets it.
+func (r *UserRepo) CreateProvisionedUser(ctx context.Context, orgID string, p entity.ProvisionedUser) (entity.UserEntity, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	u := &model.User{}
+	err = tx.GetContext(ctx, u,
+		`INSERT INTO users (email, username, password_hash, email_verified, status, external_id, given_name, family_name)
+		 VALUES ($1, $2, '!', true, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+		 RETURNING `+userColumns,
+		p.Email, p.Username, provisionedStatus(p.Active), p.ExternalID, p.GivenName, p.FamilyName)
+	if err != nil {
+		return nil, provisioningError(err, p.Email)
+	}
+	u.Roles = pq.StringArray(append([]string{}, p.Roles...))
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO organization_members (org_id, user_id, roles) VALUES ($1, $2, $3)`, orgID, u.ID, u.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("insert organization member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return u, nil
+}
+
+// UpdateProvisionedUser replaces the roles the user holds in orgID. The
+// profile is only rewritten when orgID is the user's sole organization, so
+// one tenant's identity provider cannot rename a user shared with another.
+func (r *UserRepo) UpdateProvisionedUser(ctx context.Context, orgID, userID string, p entity.ProvisionedUser) (entity.UserEntity, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE organization_members m SET roles=$1 FROM users u
+		 WHERE m.org_id=$2 AND m.user_id=$3 AND u.id = m.user_id AND u.deleted_at IS NULL`,
+		pq.StringArray(append([]string{}, p.Roles...)), orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("update organization member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET email=$1, username=$2, status=$3, external_id=NULLIF($4, ''),
+		 given_name=NULLIF($5, ''), family_name=NULLIF($6, ''), email_verified=true, updated_at=now()
+		 WHERE id=$7 AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = users.id AND m.org_id <> $8)`,
+		p.Email, p.Username, provisionedStatus(p.Active), p.ExternalID, p.GivenName, p.FamilyName, userID, orgID)
+	if err != nil {
+		return nil, provisioningError(err, p.Email)
+	}
+
+	u := &model.User{}
+	if err := tx.GetContext(ctx, u, `SELECT `+orgUserColumns+` FROM users WHERE id=$2`, orgID, userID); err != nil {
+		return nil, fmt.Errorf("reload user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return u, nil
+}
+
+// UpdateMetadata replaces the user's field document with fn's result,
+// holding the row lock while fn runs. An error from fn is returned as is
+// and nothing is written.
+func (r *UserRepo) UpdateMetadata(ctx context.Context, userID, field string,
+	fn func(current json.RawMessage) (json.RawMessage, error)) (entity.UserEntity, error) {
+	if field != entity.MetadataUser && field != entity.MetadataApp {
+		return nil, fmt.Errorf("unknown metadata field %q", field)
+	}
+
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current []byte
+	err = tx.GetContext(ctx, &current,
+		`SELECT `+field+` FROM users WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock user: %w", err)
+	}
+
+	next, err := fn(current)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &model.User{}
+	err = tx.GetContext(ctx, u,
+		`UPDATE users SET `+field+`=$1, updated_at=now() WHERE id=$2 RETURNING `+userColumns,
+		string(next), userID)
+	if err != nil {
+		return nil, fmt.Errorf("update %s: %w", field, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return u, nil
+}
+
+func provisionedStatus(active bool) string {
+	if active {
+		return entity.UserStatusActive
+	}
+	return entity.UserStatusDisabled
+}
+
+func provisioningError(err error, email string) error {
+	switch {
+	case isUsernameViolation(err):
+		return ErrUsernameTaken
+	case isConstraintViolation(err, "idx_users_external_id"):
+		return ErrExternalIDTaken
+	case isUniqueViolation(err):
+		return NewErrUserExists(email)
+	}
+	return fmt.Errorf("save provisioned user: %w", err)
 }
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+const webhookColumns = `id, org_id, url, events, description, secret, disabled,
+	COALESCE(created_by::text, '') AS created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event, payload, status, attempts, response_status, last_error,
+	COALESCE(replay_of::text, '') AS replay_of, created_at, delivered_at`
+
+type WebhookRepo struct {
+	Repo
+}
+
+func NewWebhookRepo(r Repo) *WebhookRepo {
+	return &WebhookRepo{Repo: r}
+}
+
+func (r *WebhookRepo) CreateWebhook(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	created := &model.WebhookSubscription{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO webhook_subscriptions (org_id, url, events, description, secret, disabled, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid)
+		 RETURNING `+webhookColumns,
+		sub.OrgID, sub.URL, pq.StringArray(sub.Events), sub.Description, sub.Secret, sub.Disabled, sub.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateWebhook replaces the URL, events, description and disabled flag.
+// The secret is kept.
+func (r *WebhookRepo) UpdateWebhook(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	updated := &model.WebhookSubscription{}
+	err := r.conn(ctx).GetContext(ctx, updated,
+		`UPDATE webhook_subscriptions
+		 SET url=$2, events=$3, description=$4, disabled=$5, updated_at=now()
+		 WHERE id=$1 AND org_id=$6
+		 RETURNING `+webhookColumns,
+		sub.ID, sub.URL, pq.StringArray(sub.Events), sub.Description, sub.Disabled, sub.OrgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *WebhookRepo) GetWebhook(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	sub := &model.WebhookSubscription{}
+	err := r.conn(ctx).GetContext(ctx, sub, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id=$1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *WebhookRepo) ListWebhooks(ctx context.Context, orgID string) ([]*model.WebhookSubscription, error) {
+	var subs []*model.WebhookSubscription
+	err := r.conn(ctx).SelectContext(ctx, &subs,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE org_id=$1 ORDER BY created_at`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *WebhookRepo) ListWebhooksForEvent(ctx context.Context, event string, orgIDs []string) ([]*model.WebhookSubscription, error) {
+	var subs []*model.WebhookSubscription
+	err := r.conn(ctx).SelectContext(ctx, &subs,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions
+		 WHERE NOT disabled AND org_id::text = ANY($2) AND ($1 = ANY(events) OR '*' = ANY(events))`,
+		event, pq.StringArray(orgIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks for event: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *WebhookRepo) DeleteWebhook(ctx context.Context, orgID, id string) error {
+	res, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id=$1 AND org_id=$2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepo) CreateWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	created := &model.WebhookDelivery{}
+	err := r.conn(ctx).GetContext(ctx, created,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id, event, payload, replay_of)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid)
+		 RETURNING `+webhookDeliveryColumns,
+		d.SubscriptionID, d.EventID, d.Event, string(d.Payload), d.ReplayOf)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return created, nil
+}
+
+func (r *WebhookRepo) GetWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	d := &model.WebhookDelivery{}
+	err := r.conn(ctx).GetContext(ctx, d, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id=$1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*model.WebhookDelivery, error) {
+	var ds []*model.WebhookDelivery
+	err := r.conn(ctx).SelectContext(ctx, &ds,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE subscription_id=$1 ORDER BY created_at DESC LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	return ds, nil
+}
+
+func (r *WebhookRepo) RecordWebhookAttempt(ctx context.Context, id, status string, responseStatus int, lastError string) error {
+	_, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		 SET status=$2, attempts=attempts+1, response_status=$3, last_error=$4,
+		     delivered_at=CASE WHEN $2=$5 THEN now() ELSE delivered_at END
+		 WHERE id=$1`,
+		id, status, responseStatus, lastError, entity.WebhookDeliverySucceeded)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import "context"
+
+// AuditNote collects what only the handling code learns about a call that
+// is being audited, such as the account a login attempt was for.
+type AuditNote struct {
+	ActorID   string
+	ActorType string
+	TargetID  string
+	OrgID     string
+	Details   map[string]string
+	// Always records the call even when its method is not audited, e.g. a
+	// read denied by an IP policy or made while impersonating.
+	Always bool
+}
+
+// WithAuditNote attaches an empty note to ctx for the audit recorder to
+// read once the call returns.
+func WithAuditNote(ctx context.Context) (context.Context, *AuditNote) {
+	note := &AuditNote{}
+	return context.WithValue(ctx, "audit_note", note), note
+}
+
+func auditNote(ctx context.Context) *AuditNote {
+	note, _ := ctx.Value("audit_note").(*AuditNote)
+	return note
+}
+
+// AuditActor records who is making the call. It is a no-op outside an
+// audited call.
+func AuditActor(ctx context.Context, actorID, actorType, orgID string) {
+	if note := auditNote(ctx); note != nil {
+		note.ActorID, note.ActorType = actorID, actorType
+		if orgID != "" {
+			note.OrgID = orgID
+		}
+	}
+}
+
+// AuditTarget records the account or object the call acted on.
+func AuditTarget(ctx context.Context, targetID string) {
+	if note := auditNote(ctx); note != nil {
+		note.TargetID = targetID
+	}
+}
+
+// AuditDetail adds a key/value pair to the audit event of the call.
+func AuditDetail(ctx context.Context, key, value string) {
+	if note := auditNote(ctx); note != nil {
+		if note.Details == nil {
+			note.Details = map[string]string{}
+		}
+		note.Details[key] = value
+	}
+}
+
+// AuditAlways makes sure the call is recorded whatever its method.
+func AuditAlways(ctx context.Context) {
+	if note := auditNote(ctx); note != nil {
+		note.Always = true
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit keeps the security audit log: an append-only record of
+// who did what, to whom, from where and with which outcome.
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/siem"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxReasonBytes  = 512
+)
+
+type Service struct {
+	repo      entity.AuditRepository
+	retention time.Duration
+	exporters []Exporter
+}
+
+// Exporter forwards recorded events to an external system, typically a
+// *siem.Queue. Export must not block for long; it runs before the audited
+// call returns.
+type Exporter interface {
+	Export(ctx context.Context, ev *siem.Event) error
+}
+
+type Option func(*Service)
+
+// WithRetention sets how long events are kept. Zero keeps them forever.
+func WithRetention(d time.Duration) Option {
+	return func(s *Service) {
+		s.retention = d
+	}
+}
+
+// WithExporter copies every recorded event to e.
+func WithExporter(e Exporter) Option {
+	return func(s *Service) {
+		s.exporters = append(s.exporters, e)
+	}
+}
+
+func NewService(repo entity.AuditRepository, opts ...Option) *Service {
+	s := &Service{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Record appends ev to the log. The note, if any, fills in what the
+// handling code learned about the call.
+func (s *Service) Record(ctx context.Context, ev *model.AuditEvent, note *service.AuditNote) error {
+	if note != nil {
+		if note.ActorID != "" {
+			ev.ActorID, ev.ActorType = note.ActorID, note.ActorType
+		}
+		if note.TargetID != "" {
+			ev.TargetID = note.TargetID
+		}
+		if note.OrgID != "" {
+			ev.OrgID = note.OrgID
+		}
+		if len(note.Details) > 0 {
+			details, err := json.Marshal(note.Details)
+			if err != nil {
+				return err
+			}
+			ev.Details = details
+		}
+	}
+	if len(ev.Reason) > maxReasonBytes {
+		// 按字符边界截断，避免写入不完整的 UTF-8
+		n := maxReasonBytes
+		for n > 0 && !utf8.RuneStart(ev.Reason[n]) {
+			n--
+		}
+		ev.Reason = ev.Reason[:n]
+	}
+	created, err := s.repo.AppendAuditEvent(ctx, ev)
+	if err != nil {
+		return err
+	}
+	s.export(ctx, created)
+	return nil
+}
+
+// export copies ev to the exporters. The database stays the record of
+// truth, so export failures are only logged.
+func (s *Service) export(ctx context.Context, ev *model.AuditEvent) {
+	if len(s.exporters) == 0 {
+		return
+	}
+	out := &siem.Event{
+		ID:        ev.ID,
+		Time:      ev.OccurredAt,
+		Action:    ev.Action,
+		Outcome:   ev.Outcome,
+		Reason:    ev.Reason,
+		ActorID:   ev.ActorID,
+		ActorType: ev.ActorType,
+		TargetID:  ev.TargetID,
+		OrgID:     ev.OrgID,
+		IP:        ev.IP,
+		UserAgent: ev.UserAgent,
+		RequestID: ev.RequestID,
+		Details:   json.RawMessage(ev.Details),
+	}
+	for _, e := range s.exporters {
+		// 丢弃已由队列记录日志
+		if err := e.Export(ctx, out); err != nil && !errors.Is(err, siem.ErrDropped) {
+			log.Printf("[audit] export event %d: %v", ev.ID, err)
+		}
+	}
+}
+
+// List returns one page of events matching q, newest first, and the token
+// of the next page ("" on the last page). pageToken comes from an earlier
+// call.
+func (s *Service) List(ctx context.Context, q entity.AuditQuery, pageToken string) ([]*model.AuditEvent, string, error) {
+	if pageToken != "" {
+		id, err := decodeCursor(pageToken)
+		if err != nil {
+			return nil, "", &service.FieldError{Field: "page_token", Reason: "INVALID", Message: "page_token is not valid"}
+		}
+		q.BeforeID = id
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	q.Limit = min(q.Limit, maxPageSize)
+	if q.Outcome != "" && q.Outcome != entity.AuditSuccess && q.Outcome != entity.AuditFailure {
+		return nil, "", &service.FieldError{Field: "outcome", Reason: "INVALID", Message: "outcome must be success or failure"}
+	}
+
+	// 多取一条，用来判断是否还有下一页
+	size := q.Limit
+	q.Limit++
+	events, err := s.repo.ListAuditEvents(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(events) <= size {
+		return events, "", nil
+	}
+	events = events[:size]
+	return events, encodeCursor(events[size-1].ID), nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("a:" + strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(token string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 3 || string(raw[:2]) != "a:" {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	return strconv.ParseInt(string(raw[2:]), 10, 64)
+}
+
+// Purge deletes events older than the retention period.
+func (s *Service) Purge(ctx context.Context) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.PurgeAuditEvents(ctx, time.Now().Add(-s.retention))
+}
+
+// RunRetention purges expired events every interval until ctx ends.
+func (s *Service) RunRetention(ctx context.Context, interval time.Duration) {
+	if s.retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Purge(ctx)
+			if err != nil {
+				log.Printf("purge audit events: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("purged %d audit events", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+// DeleteAccount re-checks the password, hides the account immediately and
+// schedules the hard delete after the grace period. All tokens issued so far
+// stop working right away.
+func (s *Service) DeleteAccount(ctx context.Context, userID, password string) (deleteAfter time.Time, err error) {
+	u, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !u.CheckPassword(password) {
+		return time.Time{}, service.ErrInvalidPassword
+	}
+
+	deleteAfter = time.Now().Add(s.deletionGrace)
+	if err := s.db.SoftDeleteUser(ctx, userID, deleteAfter); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.RevokeAllTokens(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	s.invalidateProfile(ctx, userID)
+	return deleteAfter, nil
+}
+
+// RevokeAllTokens drops the cached refresh token and rejects every access
+// token of the user issued up to now.
+func (s *Service) RevokeAllTokens(ctx context.Context, userID string) error {
+	if err := s.cache.DeleteRefreshToken(ctx, userID); err != nil {
+		return err
+	}
+	return s.cache.RevokeUserTokens(ctx, userID, time.Now(), max(token.AccessTTL(), token.RefreshTTL()))
+}
+
+// TokenRevoked reports whether the claims predate a user-wide revocation.
+// Tokens are treated as revoked while the revocation state can't be read.
+func (s *Service) TokenRevoked(ctx context.Context, claims *token.Claims) bool {
+	revokedAt, err := s.cache.GetTokensRevokedAt(ctx, claims.UserID)
+	if err != nil {
+		log.Printf("read token revocation of %s: %v", claims.UserID, err)
+		return true
+	}
+	if claims.IssuedAt == nil {
+		return false
+	}
+	return !claims.IssuedAt.Time.After(revokedAt)
+}
+
+// PurgeDeletedAccounts removes accounts whose grace period has ended.
+func (s *Service) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	purged, err := s.db.PurgeDeletedUsers(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for id, orgIDs := range purged {
+		s.invalidateProfile(ctx, id)
+		if s.events == nil {
+			continue
+		}
+		// 成员关系已随用户删除，使用删除前的组织
+		if err := s.events.Publish(ctx, entity.EventUserDeleted, orgIDs, UserEvent{UserID: id}); err != nil {
+			log.Printf("publish %s for user %s: %v", entity.EventUserDeleted, id, err)
+		}
+	}
+	return len(purged), nil
+}
+
+func (s *Service) RunAccountPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeDeletedAccounts(ctx)
+			if err != nil {
+				log.Printf("purge deleted accounts: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("purged %d deleted accounts", n)
+			}
+		}
+	}
+}
+
+type Session struct {
+	Type      string    `json:"type"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ExportedToken struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ExportedMembership struct {
+	OrgID    string    `json:"org_id"`
+	OrgSlug  string    `json:"org_slug"`
+	OrgName  string    `json:"org_name"`
+	Roles    []string  `json:"roles"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type ExportedGroup struct {
+	OrgID       string `json:"org_id"`
+	GroupID     string `json:"group_id"`
+	DisplayName string `json:"display_name"`
+}
+
+type ExportedInvitation struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	Email      string     `json:"email"`
+	Roles      []string   `json:"roles"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DataExport is everything the service stores about a user.
+type DataExport struct {
+	GeneratedAt          time.Time            `json:"generated_at"`
+	Profile              *Profile             `json:"profile"`
+	UserMetadata         json.RawMessage      `json:"user_metadata"`
+	AppMetadata          json.RawMessage      `json:"app_metadata"`
+	Sessions             []Session            `json:"sessions"`
+	LoginHistory         []*model.AuditEvent  `json:"login_history"`
+	AuditEvents          []*model.AuditEvent  `json:"audit_events"`
+	PersonalAccessTokens []ExportedToken      `json:"personal_access_tokens"`
+	Organizations        []ExportedMembership `json:"organizations"`
+	Groups               []ExportedGroup      `json:"groups"`
+	Invitations          []ExportedInvitation `json:"invitations"`
+}
+
+func (s *Service) ExportMyData(ctx context.Context, userID string) ([]byte, error) {
+	u, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &DataExport{
+		GeneratedAt:          time.Now().UTC(),
+		Profile:              newProfile(u),
+		UserMetadata:         u.GetUserMetadata(),
+		AppMetadata:          u.GetAppMetadata(),
+		Sessions:             []Session{},
+		LoginHistory:         []*model.AuditEvent{},
+		AuditEvents:          []*model.AuditEvent{},
+		PersonalAccessTokens: []ExportedToken{},
+		Organizations:        []ExportedMembership{},
+		Groups:               []ExportedGroup{},
+		Invitations:          []ExportedInvitation{},
+	}
+
+	if refresh, err := s.cache.GetToken(ctx, userID); err == nil {
+		if claims, err := token.ParseToken(refresh); err == nil {
+			export.Sessions = append(export.Sessions, Session{
+				Type:      claims.TokenType,
+				IssuedAt:  claims.IssuedAt.Time,
+				ExpiresAt: claims.ExpiresAt.Time,
+			})
+		}
+	}
+
+	if err := s.exportAuditTrail(ctx, export, userID); err != nil {
+		return nil, err
+	}
+
+	if s.pats != nil {
+		pats, err := s.pats.ListPersonalAccessTokens(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range pats {
+			export.PersonalAccessTokens = append(export.PersonalAccessTokens, ExportedToken{
+				ID:         t.ID,
+				OrgID:      t.OrgID,
+				Name:       t.Name,
+				Prefix:     t.Prefix,
+				Scopes:     t.Scopes,
+				ExpiresAt:  t.ExpiresAt,
+				LastUsedAt: t.LastUsedAt,
+				RevokedAt:  t.RevokedAt,
+				CreatedAt:  t.CreatedAt,
+			})
+		}
+	}
+
+	if s.orgs != nil {
+		memberships, err := s.orgs.ListUserMemberships(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range memberships {
+			export.Organizations = append(export.Organizations, ExportedMembership{
+				OrgID:    m.OrgID,
+				OrgSlug:  m.OrgSlug,
+				OrgName:  m.OrgName,
+				Roles:    m.Roles,
+				JoinedAt: m.CreatedAt,
+			})
+			if s.groups == nil {
+				continue
+			}
+			groups, err := s.groups.ListUserGroups(ctx, m.OrgID, []string{userID})
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range groups {
+				export.Groups = append(export.Groups, ExportedGroup{OrgID: m.OrgID, GroupID: g.GroupID, DisplayName: g.DisplayName})
+			}
+		}
+	}
+
+	if s.invitations != nil {
+		invitations, err := s.invitations.ListUserInvitations(ctx, userID, u.GetEmail())
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, inv := range invitations {
+			export.Invitations = append(export.Invitations, ExportedInvitation{
+				ID:         inv.ID,
+				OrgID:      inv.OrgID,
+				Email:      inv.Email,
+				Roles:      inv.Roles,
+				Status:     inv.Status(now),
+				ExpiresAt:  inv.ExpiresAt,
+				AcceptedAt: inv.AcceptedAt,
+				CreatedAt:  inv.CreatedAt,
+			})
+		}
+	}
+
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// exportAuditPage is how many audit events are read per query while
+// building an export.
+const exportAuditPage = 500
+
+// exportAuditTrail adds the audit events the user caused or was the target
+// of. Logins, successful or not, are also listed on their own.
+func (s *Service) exportAuditTrail(ctx context.Context, export *DataExport, userID string) error {
+	if s.auditEvents == nil {
+		return nil
+	}
+	seen := map[int64]bool{}
+	for _, q := range []entity.AuditQuery{{ActorID: userID}, {TargetID: userID}} {
+		for {
+			q.Limit = exportAuditPage
+			events, err := s.auditEvents.ListAuditEvents(ctx, q)
+			if err != nil {
+				return err
+			}
+			for _, ev := range events {
+				if seen[ev.ID] {
+					continue
+				}
+				seen[ev.ID] = true
+				export.AuditEvents = append(export.AuditEvents, ev)
+				if ev.Action == "login" {
+					export.LoginHistory = append(export.LoginHistory, ev)
+				}
+			}
+			if len(events) < exportAuditPage {
+				break
+			}
+			q.BeforeID = events[len(events)-1].ID
+		}
+	}
+	// 两次查询的结果合并后按时间倒序
+	slices.SortFunc(export.AuditEvents, func(a, b *model.AuditEvent) int { return cmp.Compare(b.ID, a.ID) })
+	slices.SortFunc(export.LoginHistory, func(a, b *model.AuditEvent) int { return cmp.Compare(b.ID, a.ID) })
+	return nil
+}
@@ -17,14 +17,163 @@
 package auth
 
 import (
+	"context"
+	"net/mail"
+	"time"
+
 	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/jsonschema"
 )
 
+// EmailDomainChecker vets addresses used for new accounts and email
+// changes. Rejections are reported as *service.FieldError.
+type EmailDomainChecker interface {
+	Check(ctx context.Context, field, addr string) error
+}
+
+// OneTimeTokens issues and redeems the single-use tokens in emailed links.
+// Consume returns service.ErrInvalidToken for unknown, expired or used
+// tokens.
+type OneTimeTokens interface {
+	Issue(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error)
+	Consume(ctx context.Context, purpose, raw string) (userID string, err error)
+}
+
+// MailQueue stores emails for background delivery. Enqueueing inside
+// Transactor.InTx commits or rolls back with the rest of the transaction.
+type MailQueue interface {
+	EnqueueMail(ctx context.Context, msg *email.Message) error
+}
+
+// Transactor runs fn in a database transaction that repository calls made
+// with fn's context join.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type Service struct {
-	db    entity.UserRepository
-	cache entity.CacheRepository
+	db          entity.UserRepository
+	cache       entity.CacheRepository
+	tokens      OneTimeTokens
+	mailer      email.Mailer
+	templates   *email.Templates
+	mailQueue   MailQueue
+	tx          Transactor
+	events      EventPublisher
+	invitations entity.InvitationRepository
+	pats        entity.PersonalAccessTokenRepository
+	orgs        entity.OrganizationRepository
+	groups      entity.GroupRepository
+	auditEvents entity.AuditRepository
+
+	deletionGrace  time.Duration
+	verifyTokenTTL time.Duration
+	registration   RegistrationPolicy
+	emailDomains   EmailDomainChecker
+	groupsClaim    bool
+
+	userMetadataSchema *jsonschema.Schema
+	appMetadataSchema  *jsonschema.Schema
+	metadataClaims     []MetadataClaim
+}
+
+// Option configures optional behaviour of the Service.
+type Option func(*Service)
+
+// WithDeletionGrace sets how long a deleted account is kept before it is
+// purged for good.
+func WithDeletionGrace(d time.Duration) Option {
+	return func(s *Service) {
+		s.deletionGrace = d
+	}
+}
+
+// WithVerifyTokenTTL sets how long email verification and email change
+// links stay valid.
+func WithVerifyTokenTTL(d time.Duration) Option {
+	return func(s *Service) {
+		s.verifyTokenTTL = d
+	}
+}
+
+// WithMailer sets how emails to users are delivered. Without it they are
+// only logged.
+func WithMailer(m email.Mailer) Option {
+	return func(s *Service) {
+		s.mailer = m
+	}
+}
+
+// WithMailQueue makes emails go through q instead of being sent while the
+// request waits.
+func WithMailQueue(q MailQueue) Option {
+	return func(s *Service) {
+		s.mailQueue = q
+	}
+}
+
+// WithTransactor makes flows that change data and send email commit both
+// together.
+func WithTransactor(t Transactor) Option {
+	return func(s *Service) {
+		s.tx = t
+	}
+}
+
+// WithMailTemplates replaces the embedded email templates.
+func WithMailTemplates(t *email.Templates) Option {
+	return func(s *Service) {
+		s.templates = t
+	}
+}
+
+func WithInvitations(repo entity.InvitationRepository) Option {
+	return func(s *Service) {
+		s.invitations = repo
+	}
+}
+
+// WithAuditEvents lets data exports include the user's audit trail.
+func WithAuditEvents(repo entity.AuditRepository) Option {
+	return func(s *Service) {
+		s.auditEvents = repo
+	}
+}
+
+func WithPersonalAccessTokens(repo entity.PersonalAccessTokenRepository) Option {
+	return func(s *Service) {
+		s.pats = repo
+	}
+}
+
+func WithEmailDomainChecker(c EmailDomainChecker) Option {
+	return func(s *Service) {
+		s.emailDomains = c
+	}
+}
+
+// checkEmailDomain runs the configured domain policy, if any.
+func (s *Service) checkEmailDomain(ctx context.Context, field, addr string) error {
+	if s.emailDomains == nil {
+		return nil
+	}
+	return s.emailDomains.Check(ctx, field, addr)
 }
 
-func NewAuthService(db entity.UserRepository, cache entity.CacheRepository) *Service {
-	return &Service{db: db, cache: cache}
+func NewAuthService(db entity.UserRepository, cache entity.CacheRepository, tokens OneTimeTokens, opts ...Option) *Service {
+	s := &Service{
+		db:             db,
+		cache:          cache,
+		tokens:         tokens,
+		mailer:         email.NewLogMailer(mail.Address{}),
+		templates:      email.DefaultTemplates(),
+		deletionGrace:  30 * 24 * time.Hour,
+		verifyTokenTTL: 24 * time.Hour,
+		registration:   RegistrationPolicy{Mode: RegistrationOpen},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// EventPublisher announces user lifecycle events (entity.EventUser*) to
+// the subscribers of orgIDs, the organizations the user belongs to.
+// Publishing inside Transactor.InTx commits or rolls back with the change
+// the event reports.
+type EventPublisher interface {
+	Publish(ctx context.Context, event string, orgIDs []string, data interface{}) error
+}
+
+// WithEvents publishes user lifecycle events through p.
+func WithEvents(p EventPublisher) Option {
+	return func(s *Service) {
+		s.events = p
+	}
+}
+
+// UserEvent is the data of every user lifecycle event. Deleted users only
+// carry their ID.
+type UserEvent struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+	Status   string `json:"status,omitempty"`
+	OrgID    string `json:"org_id,omitempty"`
+}
+
+func userEvent(u entity.UserEntity) UserEvent {
+	return UserEvent{UserID: u.GetID(), Email: u.GetEmail(), Username: u.GetUsername(), Status: u.GetStatus()}
+}
+
+// publish announces event to the organizations of data.UserID, in the
+// transaction on ctx if there is one.
+func (s *Service) publish(ctx context.Context, event string, data UserEvent) error {
+	if s.events == nil {
+		return nil
+	}
+	var orgIDs []string
+	if data.OrgID != "" {
+		orgIDs = append(orgIDs, data.OrgID)
+	}
+	if s.orgs != nil {
+		memberships, err := s.orgs.ListUserMemberships(ctx, data.UserID)
+		if err != nil {
+			return err
+		}
+		for _, m := range memberships {
+			if !slices.Contains(orgIDs, m.OrgID) {
+				orgIDs = append(orgIDs, m.OrgID)
+			}
+		}
+	}
+	return s.events.Publish(ctx, event, orgIDs, data)
+}
+
+// UserDisabled ends the sessions of a user whose account was just
+// disabled, e.g. by a provisioning client, and announces it.
+func (s *Service) UserDisabled(ctx context.Context, userID string) error {
+	if err := s.RevokeAllTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.invalidateProfile(ctx, userID)
+	if err := s.publish(ctx, entity.EventUserDisabled, UserEvent{UserID: userID, Status: entity.UserStatusDisabled}); err != nil {
+		log.Printf("publish %s for user %s: %v", entity.EventUserDisabled, userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+var errGroupsDisabled = errors.New("groups are not configured")
+
+// effectiveRolesTTL bounds how long a cached entry can outlive a change
+// that bypassed invalidation, such as a user being purged.
+const effectiveRolesTTL = 5 * time.Minute
+
+const (
+	defaultGroupPageSize = 100
+	maxGroupPageSize     = 500
+)
+
+// WithGroups enables group-based role assignment.
+func WithGroups(repo entity.GroupRepository) Option {
+	return func(s *Service) {
+		s.groups = repo
+	}
+}
+
+// WithGroupsClaim adds the names of the subject's groups to access and
+// refresh tokens.
+func WithGroupsClaim(enabled bool) Option {
+	return func(s *Service) {
+		s.groupsClaim = enabled
+	}
+}
+
+// GroupDetails is a group with its direct members.
+type GroupDetails struct {
+	Group     *model.Group
+	Members   []*model.GroupMember
+	Subgroups []*model.Subgroup
+}
+
+// groupAccess is what a user's groups grant in one organization.
+type groupAccess struct {
+	Roles  []string `json:"roles"`
+	Groups []string `json:"groups"`
+}
+
+// orgAccess returns the roles member holds in its organization, directly
+// and through groups, and the names of those groups.
+func (s *Service) orgAccess(ctx context.Context, member *model.OrganizationMember) (roles, groups []string, err error) {
+	roles = append([]string{}, member.Roles...)
+	if s.groups == nil {
+		return roles, nil, nil
+	}
+	access, err := s.groupAccess(ctx, member.OrgID, member.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range access.Roles {
+		if !slices.Contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles, access.Groups, nil
+}
+
+// orgTokenOptions binds a token to member's organization.
+func (s *Service) orgTokenOptions(ctx context.Context, member *model.OrganizationMember) ([]token.Option, error) {
+	roles, groups, err := s.orgAccess(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	opts := []token.Option{token.WithOrganization(member.OrgID, roles)}
+	if s.groupsClaim && len(groups) > 0 {
+		opts = append(opts, token.WithGroups(groups))
+	}
+	return opts, nil
+}
+
+// groupAccess resolves the nested groups of userID, served from Redis when
+// cached. Cache failures fall back to PostgreSQL.
+func (s *Service) groupAccess(ctx context.Context, orgID, userID string) (*groupAccess, error) {
+	cached, version, err := s.cache.GetEffectiveRoles(ctx, orgID, userID)
+	if err == nil {
+		access := &groupAccess{}
+		if err := json.Unmarshal([]byte(cached), access); err == nil {
+			return access, nil
+		}
+	}
+
+	groups, err := s.groups.ListEffectiveGroups(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	access := &groupAccess{Roles: []string{}, Groups: []string{}}
+	for _, g := range groups {
+		access.Groups = append(access.Groups, g.DisplayName)
+		for _, r := range g.Roles {
+			if !slices.Contains(access.Roles, r) {
+				access.Roles = append(access.Roles, r)
+			}
+		}
+	}
+
+	if data, err := json.Marshal(access); err == nil {
+		if err := s.cache.StoreEffectiveRoles(ctx, orgID, userID, version, string(data), effectiveRolesTTL); err != nil {
+			log.Printf("cache effective roles %s/%s: %v", orgID, userID, err)
+		}
+	}
+	return access, nil
+}
+
+// InvalidateGroupRoles drops the cached group roles of every user in orgID.
+// Any change to groups, their roles or their members must call it.
+func (s *Service) InvalidateGroupRoles(ctx context.Context, orgID string) {
+	if err := s.cache.InvalidateEffectiveRoles(ctx, orgID); err != nil {
+		log.Printf("invalidate effective roles of org %s: %v", orgID, err)
+	}
+}
+
+// GetEffectiveRoles returns the roles userID holds in orgID, directly and
+// through groups, and the groups they belong to.
+func (s *Service) GetEffectiveRoles(ctx context.Context, orgID, userID string) (roles, groups []string, err error) {
+	if s.orgs == nil {
+		return nil, nil, errOrganizationsDisabled
+	}
+	member, err := s.orgs.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.orgAccess(ctx, member)
+}
+
+func (s *Service) CreateGroup(ctx context.Context, orgID, name string, roles []string) (*model.Group, error) {
+	if s.groups == nil {
+		return nil, errGroupsDisabled
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &service.FieldError{Field: "display_name", Reason: "REQUIRED", Message: "display_name is required"}
+	}
+	g, err := s.groups.CreateGroup(ctx, orgID, name, "", cleanRoles(roles), nil)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, g.ID)
+	service.AuditDetail(ctx, "roles", strings.Join(g.Roles, ","))
+	return g, nil
+}
+
+// ListGroups returns a page of the organization's groups and their total
+// number.
+func (s *Service) ListGroups(ctx context.Context, orgID string, offset, limit int) ([]*model.Group, int, error) {
+	if s.groups == nil {
+		return nil, 0, errGroupsDisabled
+	}
+	if limit <= 0 {
+		limit = defaultGroupPageSize
+	}
+	return s.groups.QueryGroups(ctx, entity.ListQuery{OrgID: orgID, Offset: offset, Limit: min(limit, maxGroupPageSize)})
+}
+
+func (s *Service) GetGroup(ctx context.Context, orgID, id string) (*GroupDetails, error) {
+	if s.groups == nil {
+		return nil, errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", id); err != nil {
+		return nil, err
+	}
+	g, err := s.groups.GetGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groups.ListGroupMembers(ctx, orgID, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	subgroups, err := s.groups.ListSubgroups(ctx, orgID, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	return &GroupDetails{Group: g, Members: members, Subgroups: subgroups}, nil
+}
+
+// UpdateGroup renames the group and replaces the roles it grants.
+func (s *Service) UpdateGroup(ctx context.Context, orgID, id, name string, roles []string) (*model.Group, error) {
+	if s.groups == nil {
+		return nil, errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", id); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &service.FieldError{Field: "display_name", Reason: "REQUIRED", Message: "display_name is required"}
+	}
+	g, err := s.groups.UpdateGroupDetails(ctx, orgID, id, name, cleanRoles(roles))
+	if err != nil {
+		return nil, err
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, g.ID)
+	service.AuditDetail(ctx, "roles", strings.Join(g.Roles, ","))
+	return g, nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, orgID, id string) error {
+	if s.groups == nil {
+		return errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", id); err != nil {
+		return err
+	}
+	if err := s.groups.DeleteGroup(ctx, orgID, id); err != nil {
+		return err
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, id)
+	return nil
+}
+
+// AddGroupMembers adds users and nested groups to groupID. Users that are
+// not members of the organization are skipped.
+func (s *Service) AddGroupMembers(ctx context.Context, orgID, groupID string, userIDs, groupIDs []string) error {
+	if s.groups == nil {
+		return errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", groupID); err != nil {
+		return err
+	}
+	for _, id := range userIDs {
+		if !uuidPattern.MatchString(id) {
+			return &service.FieldError{Field: "user_ids", Reason: "ID_INVALID", Message: "invalid user id " + id}
+		}
+	}
+	for _, id := range groupIDs {
+		if err := checkGroupID("member_group_ids", id); err != nil {
+			return err
+		}
+	}
+
+	if len(userIDs) > 0 {
+		if err := s.groups.AddGroupMembers(ctx, orgID, groupID, userIDs); err != nil {
+			return err
+		}
+	}
+	for _, id := range groupIDs {
+		if err := s.groups.AddSubgroup(ctx, orgID, groupID, id); err != nil {
+			s.InvalidateGroupRoles(ctx, orgID)
+			return err
+		}
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, groupID)
+	service.AuditDetail(ctx, "users_added", strings.Join(userIDs, ","))
+	service.AuditDetail(ctx, "groups_added", strings.Join(groupIDs, ","))
+	return nil
+}
+
+func (s *Service) RemoveGroupMember(ctx context.Context, orgID, groupID, userID string) error {
+	if s.groups == nil {
+		return errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", groupID); err != nil {
+		return err
+	}
+	if err := s.groups.RemoveGroupMember(ctx, orgID, groupID, userID); err != nil {
+		return err
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, groupID)
+	service.AuditDetail(ctx, "user_removed", userID)
+	return nil
+}
+
+func (s *Service) RemoveSubgroup(ctx context.Context, orgID, groupID, memberGroupID string) error {
+	if s.groups == nil {
+		return errGroupsDisabled
+	}
+	if err := checkGroupID("group_id", groupID); err != nil {
+		return err
+	}
+	if err := checkGroupID("member_group_id", memberGroupID); err != nil {
+		return err
+	}
+	if err := s.groups.RemoveSubgroup(ctx, orgID, groupID, memberGroupID); err != nil {
+		return err
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, groupID)
+	service.AuditDetail(ctx, "group_removed", memberGroupID)
+	return nil
+}
+
+// checkGroupID keeps malformed IDs away from PostgreSQL, which rejects
+// them with an error rather than returning no rows.
+func checkGroupID(field, id string) error {
+	if !uuidPattern.MatchString(id) {
+		return &service.FieldError{Field: field, Reason: "ID_INVALID", Message: "invalid group id " + id}
+	}
+	return nil
+}
+
+// cleanRoles trims roles and drops empty and repeated entries.
+func cleanRoles(roles []string) []string {
+	cleaned := []string{}
+	for _, r := range roles {
+		if r = strings.TrimSpace(r); r != "" && !slices.Contains(cleaned, r) {
+			cleaned = append(cleaned, r)
+		}
+	}
+	return cleaned
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// IdentifierKind tells how a login identifier should be interpreted.
+type IdentifierKind int
+
+const (
+	// IdentifierAuto treats identifiers containing "@" as emails and
+	// everything else as usernames. Usernames may not contain "@".
+	IdentifierAuto IdentifierKind = iota
+	IdentifierEmail
+	IdentifierUsername
+)
+
+// normalizeUsername lower-cases and trims a username so lookups and
+// uniqueness are case-insensitive.
+func normalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" || strings.Contains(username, "@") {
+		return "", service.ErrUsernameNotValid
+	}
+	return username, nil
+}
+
+func (s *Service) lookupUser(ctx context.Context, identifier string, kind IdentifierKind) (entity.UserEntity, error) {
+	identifier = strings.TrimSpace(identifier)
+	if kind == IdentifierAuto {
+		kind = IdentifierUsername
+		if strings.Contains(identifier, "@") {
+			kind = IdentifierEmail
+		}
+	}
+
+	if kind == IdentifierEmail {
+		return s.db.GetUserByEmail(ctx, identifier)
+	}
+	username, err := normalizeUsername(identifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetUserByUsername(ctx, username)
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/linktemplate"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+var errInvitationsDisabled = errors.New("invitations are not configured")
+
+// CreateInvitation records an invitation and emails a signed link that lets
+// the recipient create an already verified account that joins orgID with
+// the given roles.
+func (s *Service) CreateInvitation(ctx context.Context, orgID, invitedBy, userEmail string, roles []string, ttl time.Duration, acceptLink string) (*model.Invitation, error) {
+	if s.invitations == nil {
+		return nil, errInvitationsDisabled
+	}
+	userEmail = strings.TrimSpace(userEmail)
+	if userEmail == "" {
+		return nil, errors.New("email is required")
+	}
+
+	_, err := s.db.GetUserByEmail(ctx, userEmail)
+	if err == nil {
+		return nil, service.ErrEmailInUse
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	data := email.Data{}
+	if s.orgs != nil {
+		if org, err := s.orgs.GetOrganization(ctx, orgID); err == nil {
+			data.Organization = org.Name
+		}
+	}
+
+	var inv *model.Invitation
+	err = s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		inv, err = s.invitations.CreateInvitation(ctx, &model.Invitation{
+			OrgID:     orgID,
+			Email:     userEmail,
+			Roles:     roles,
+			InvitedBy: invitedBy,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		if err != nil {
+			return err
+		}
+
+		inviteToken, err := token.GenerateInvitationToken(inv.ID, inv.Email, inv.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		data.Link = linktemplate.Expand(acceptLink, inviteToken)
+		data.ExpiresAt = inv.ExpiresAt
+
+		// 受邀人还没有账号，也不知道其语言，用默认 locale
+		return s.sendTemplate(ctx, inv.Email, email.TemplateInvitation, nil, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *Service) ListInvitations(ctx context.Context, orgID string, pendingOnly bool) ([]*model.Invitation, error) {
+	if s.invitations == nil {
+		return nil, errInvitationsDisabled
+	}
+	return s.invitations.ListInvitations(ctx, orgID, pendingOnly)
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, orgID, id string) error {
+	if s.invitations == nil {
+		return errInvitationsDisabled
+	}
+	return s.invitations.RevokeInvitation(ctx, orgID, id)
+}
+
+// AcceptInvitation creates the invited account. The email comes from the
+// invitation, never from the caller.
+func (s *Service) AcceptInvitation(ctx context.Context, inviteToken, username, password string) (entity.UserEntity, error) {
+	if s.invitations == nil {
+		return nil, errInvitationsDisabled
+	}
+
+	claims, err := token.ParseInvitationToken(inviteToken)
+	if err != nil {
+		return nil, service.ErrInvitationInvalid
+	}
+	username, err = normalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, errors.New("password is required")
+	}
+
+	inv, err := s.invitations.GetInvitation(ctx, claims.ID)
+	if err != nil || !strings.EqualFold(inv.Email, claims.Email) {
+		return nil, service.ErrInvitationInvalid
+	}
+	if inv.Status(time.Now()) != model.InvitationPending {
+		return nil, service.ErrInvitationInvalid
+	}
+
+	var user entity.UserEntity
+	err = s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.invitations.AcceptInvitation(ctx, inv.ID, username, password)
+		if err != nil {
+			return err
+		}
+		service.AuditTarget(ctx, user.GetID())
+		event := userEvent(user)
+		event.OrgID = inv.OrgID
+		return s.publish(ctx, entity.EventUserRegistered, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -21,35 +21,40 @@ import (
 	"time"
 
 	"github.com/shinoda4/sd-svc-auth/internal/service"
-	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 )
 
-func (s *Service) Login(ctx context.Context, email, password string) (accessToken string, refreshToken string, accessTTL, refreshTTL time.Duration, err error) {
-	u, err := s.db.GetUserByEmail(ctx, email)
+// Login authenticates the user and binds the session to org (slug or ID),
+// or to the user's oldest membership when org is empty.
+func (s *Service) Login(ctx context.Context, identifier string, kind IdentifierKind, password, org string) (accessToken string, refreshToken string, accessTTL, refreshTTL time.Duration, err error) {
+	u, err := s.lookupUser(ctx, identifier, kind)
 	if err != nil {
 		return "", "", 0, 0, err
 	}
+	service.AuditTarget(ctx, u.GetID())
 	if !u.CheckPassword(password) {
 		return "", "", 0, 0, service.ErrInvalidPassword
 	}
+	switch u.GetStatus() {
+	case entity.UserStatusPendingApproval:
+		return "", "", 0, 0, service.ErrPendingApproval
+	case entity.UserStatusRejected:
+		return "", "", 0, 0, service.ErrAccountRejected
+	case entity.UserStatusDisabled:
+		return "", "", 0, 0, service.ErrAccountDisabled
+	}
 	if !u.GetEmailVerified() {
 		return "", "", 0, 0, service.ErrEmailNotVerified
 	}
 
-	accessToken, accessTTL, err = token.GenerateJWT(u.GetID(), u.GetEmail())
-	if err != nil {
-		return "", "", 0, 0, err
-	}
-
-	refreshToken, refreshTTL, err = token.GenerateRefreshJWT(u.GetID(), u.GetEmail())
+	activeOrg, member, err := s.resolveMembership(ctx, u.GetID(), org)
 	if err != nil {
 		return "", "", 0, 0, err
 	}
-
-	// 只缓存 refresh token
-	if err := s.cache.StoreToken(ctx, u.GetID(), refreshToken, refreshTTL); err != nil {
-		return "", "", 0, 0, err
+	orgID := ""
+	if activeOrg != nil {
+		orgID = activeOrg.ID
 	}
-
-	return accessToken, refreshToken, accessTTL, refreshTTL, nil
+	service.AuditActor(ctx, u.GetID(), "user", orgID)
+	return s.issueTokens(ctx, u, activeOrg, member)
 }
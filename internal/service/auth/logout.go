@@ -27,7 +27,7 @@ import (
 
 func (s *Service) Logout(ctx context.Context, tokenStr string) error {
 	// 解析 token 类型
-	claims, err := token.ParseAndValidate(tokenStr)
+	claims, err := token.ParseToken(tokenStr)
 	if err != nil {
 		log.Println("Error validating token:", err)
 		return err
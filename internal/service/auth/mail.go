@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+)
+
+// sendTemplate renders the named template in the first of locales that has
+// it and mails it to a single recipient. With a mail queue the message is
+// only enqueued, in the transaction on ctx if there is one.
+func (s *Service) sendTemplate(ctx context.Context, to, name string, locales []string, data email.Data) error {
+	msg, err := s.templates.Render(name, locales, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	if s.mailQueue != nil {
+		return s.mailQueue.EnqueueMail(ctx, msg)
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// inTx runs fn in a transaction when a Transactor is configured.
+func (s *Service) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.InTx(ctx, fn)
+}
+
+// requestLocales is the locale preference for mail the user triggered
+// themselves: the locale saved in their metadata, then the request's
+// Accept-Language.
+func requestLocales(ctx context.Context, user entity.UserEntity) []string {
+	acceptLanguage, _ := ctx.Value("accept_language").(string)
+	return email.PreferredLocales(userLocale(user), acceptLanguage)
+}
+
+// userLocales is the locale preference for mail triggered by someone
+// else, whose Accept-Language says nothing about the recipient.
+func userLocales(user entity.UserEntity) []string {
+	return email.PreferredLocales(userLocale(user), "")
+}
+
+// userLocale reads the "locale" key of the user's metadata.
+func userLocale(user entity.UserEntity) string {
+	var doc struct {
+		Locale string `json:"locale"`
+	}
+	if err := json.Unmarshal(user.GetUserMetadata(), &doc); err != nil {
+		return ""
+	}
+	return doc.Locale
+}
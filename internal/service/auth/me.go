@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// profileTTL keeps Me cheap without letting edits stay invisible for long.
+const profileTTL = 30 * time.Second
+
+type Profile struct {
+	UserID           string           `json:"user_id"`
+	Email            string           `json:"email"`
+	Username         string           `json:"username"`
+	EmailVerified    bool             `json:"email_verified"`
+	PendingEmail     string           `json:"pending_email,omitempty"`
+	Status           string           `json:"status"`
+	Roles            []string         `json:"roles"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	LinkedIdentities []LinkedIdentity `json:"linked_identities"`
+}
+
+// LinkedIdentity is an external account the user is known by. Users
+// provisioned over SCIM are linked to their identity provider's externalId.
+type LinkedIdentity struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+}
+
+// Me returns the caller's profile, served from Redis when a fresh copy is
+// cached. Cache failures fall back to PostgreSQL.
+func (s *Service) Me(ctx context.Context, userID string) (*Profile, error) {
+	if cached, err := s.cache.GetProfile(ctx, userID); err == nil {
+		p := &Profile{}
+		if err := json.Unmarshal([]byte(cached), p); err == nil {
+			return p, nil
+		}
+	}
+
+	u, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	p := newProfile(u)
+
+	if data, err := json.Marshal(p); err == nil {
+		if err := s.cache.StoreProfile(ctx, userID, string(data), profileTTL); err != nil {
+			log.Printf("cache profile %s: %v", userID, err)
+		}
+	}
+	return p, nil
+}
+
+func newProfile(u entity.UserEntity) *Profile {
+	return &Profile{
+		UserID:           u.GetID(),
+		Email:            u.GetEmail(),
+		Username:         u.GetUsername(),
+		EmailVerified:    u.GetEmailVerified(),
+		PendingEmail:     u.GetPendingEmail(),
+		Status:           u.GetStatus(),
+		Roles:            u.GetRoles(),
+		CreatedAt:        u.GetCreatedAt(),
+		UpdatedAt:        u.GetUpdatedAt(),
+		LinkedIdentities: linkedIdentities(u),
+	}
+}
+
+func linkedIdentities(u entity.UserEntity) []LinkedIdentity {
+	identities := []LinkedIdentity{}
+	if id := u.GetExternalID(); id != "" {
+		identities = append(identities, LinkedIdentity{Provider: "scim", Subject: id})
+	}
+	return identities
+}
+
+// invalidateProfile drops the cached profile after the user row changed.
+func (s *Service) invalidateProfile(ctx context.Context, userID string) {
+	if err := s.cache.DeleteProfile(ctx, userID); err != nil {
+		log.Printf("invalidate profile %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/jsonschema"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+// maxMetadataBytes caps each metadata document. Documents end up in
+// profile caches and possibly in tokens, so they must stay small.
+const maxMetadataBytes = 16 << 10
+
+// MetadataClaim copies the value at Path in the user's app_metadata into
+// the token's metadata claim under Claim.
+type MetadataClaim struct {
+	Claim string
+	Path  []string
+}
+
+// ParseMetadataClaims parses a comma-separated list of
+// claim=app_metadata.path.to.value mappings, e.g.
+// "plan=app_metadata.plan,tier=app_metadata.billing.tier". user_metadata is
+// refused as a source: users edit it themselves, and downstream services
+// trust what a signed token says.
+func ParseMetadataClaims(spec string) ([]MetadataClaim, error) {
+	var claims []MetadataClaim
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		name, source, ok := strings.Cut(raw, "=")
+		name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+		if !ok || name == "" || source == "" {
+			return nil, fmt.Errorf("invalid metadata claim %q, expected claim=%s.path", raw, entity.MetadataApp)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate metadata claim %q", name)
+		}
+		path := strings.Split(source, ".")
+		if path[0] != entity.MetadataApp || len(path) < 2 {
+			return nil, fmt.Errorf("metadata claim %q: source must be a path in %s", name, entity.MetadataApp)
+		}
+		for _, p := range path[1:] {
+			if p == "" {
+				return nil, fmt.Errorf("metadata claim %q: empty path segment in %q", name, source)
+			}
+		}
+		seen[name] = true
+		claims = append(claims, MetadataClaim{Claim: name, Path: path[1:]})
+	}
+	return claims, nil
+}
+
+// WithMetadataSchemas validates metadata documents after every update.
+// Either schema may be nil to accept any object.
+func WithMetadataSchemas(user, app *jsonschema.Schema) Option {
+	return func(s *Service) {
+		s.userMetadataSchema = user
+		s.appMetadataSchema = app
+	}
+}
+
+// WithMetadataClaims maps metadata values into access and refresh tokens.
+func WithMetadataClaims(claims []MetadataClaim) Option {
+	return func(s *Service) {
+		s.metadataClaims = claims
+	}
+}
+
+// GetMetadata returns the user, whose GetUserMetadata and GetAppMetadata
+// hold the documents. A non-empty orgID limits the lookup to members of
+// that organization, for admins reading other users.
+func (s *Service) GetMetadata(ctx context.Context, orgID, userID string) (entity.UserEntity, error) {
+	if orgID != "" {
+		return s.db.GetOrgUserByID(ctx, orgID, userID)
+	}
+	return s.db.GetUserByID(ctx, userID)
+}
+
+// UpdateUserMetadata applies a JSON merge patch (RFC 7396) to the user's
+// own metadata. A null member removes the key. orgID is as in GetMetadata.
+func (s *Service) UpdateUserMetadata(ctx context.Context, orgID, userID string, patch json.RawMessage) (entity.UserEntity, error) {
+	return s.updateMetadata(ctx, orgID, userID, entity.MetadataUser, s.userMetadataSchema, patch)
+}
+
+// UpdateAppMetadata applies a JSON merge patch to the admin-managed
+// metadata of a member of orgID. Callers must have checked that the caller
+// is an admin.
+func (s *Service) UpdateAppMetadata(ctx context.Context, orgID, userID string, patch json.RawMessage) (entity.UserEntity, error) {
+	return s.updateMetadata(ctx, orgID, userID, entity.MetadataApp, s.appMetadataSchema, patch)
+}
+
+func (s *Service) updateMetadata(ctx context.Context, orgID, userID, field string, schema *jsonschema.Schema,
+	patch json.RawMessage) (entity.UserEntity, error) {
+	if orgID != "" {
+		if _, err := s.db.GetOrgUserByID(ctx, orgID, userID); err != nil {
+			return nil, err
+		}
+	}
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, &service.FieldError{Field: "patch", Reason: "INVALID", Message: "patch is not valid JSON"}
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return nil, &service.FieldError{Field: "patch", Reason: "INVALID", Message: "patch must be a JSON object"}
+	}
+
+	user, err := s.db.UpdateMetadata(ctx, userID, field, func(current json.RawMessage) (json.RawMessage, error) {
+		var doc interface{}
+		if err := json.Unmarshal(current, &doc); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", field, err)
+		}
+		doc = mergePatch(doc, patchDoc)
+
+		next, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		if len(next) > maxMetadataBytes {
+			return nil, &service.FieldError{Field: "patch", Reason: "TOO_LARGE",
+				Message: fmt.Sprintf("%s must not exceed %d bytes", field, maxMetadataBytes)}
+		}
+		if schema != nil {
+			if err := schema.Validate(doc); err != nil {
+				var verr *jsonschema.ValidationError
+				if errors.As(err, &verr) {
+					return nil, &service.FieldError{Field: "patch", Reason: "SCHEMA_VIOLATION",
+						Message: fmt.Sprintf("%s%s: %s", field, verr.Path, verr.Message)}
+				}
+				return nil, err
+			}
+		}
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateProfile(ctx, userID)
+
+	service.AuditTarget(ctx, userID)
+	service.AuditDetail(ctx, "field", field)
+	return user, nil
+}
+
+// mergePatch applies patch to target as described in RFC 7396.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// metadataTokenOptions returns the metadata claim of u, if any mapped
+// value is set.
+func (s *Service) metadataTokenOptions(u entity.UserEntity) []token.Option {
+	if len(s.metadataClaims) == 0 {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(u.GetAppMetadata(), &doc); err != nil {
+		log.Printf("decode %s of user %s: %v", entity.MetadataApp, u.GetID(), err)
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, c := range s.metadataClaims {
+		v := doc
+		for _, key := range c.Path {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = obj[key]
+		}
+		if v != nil {
+			values[c.Claim] = v
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return []token.Option{token.WithMetadata(values)}
+}
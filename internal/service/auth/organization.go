@@ -0,0 +1,315 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+var errOrganizationsDisabled = errors.New("organizations are not configured")
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,61}[a-z0-9]$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Upper bounds for the token lifetimes an organization may configure.
+const (
+	maxOrgAccessTTL  = 24 * time.Hour
+	maxOrgRefreshTTL = 90 * 24 * time.Hour
+)
+
+func WithOrganizations(repo entity.OrganizationRepository) Option {
+	return func(s *Service) {
+		s.orgs = repo
+	}
+}
+
+// CreateOrganization creates a tenant and makes ownerID its first admin.
+func (s *Service) CreateOrganization(ctx context.Context, ownerID, slug, name string) (*model.Organization, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if !slugPattern.MatchString(slug) || uuidPattern.MatchString(slug) {
+		return nil, &service.FieldError{Field: "slug", Reason: "SLUG_INVALID", Message: "slug must be 2-63 lower-case letters, digits or hyphens"}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+
+	org, err := s.orgs.CreateOrganization(ctx, &model.Organization{Slug: slug, Name: name}, ownerID, []string{roleAdmin})
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, org.ID)
+	service.AuditDetail(ctx, "slug", org.Slug)
+	return org, nil
+}
+
+func (s *Service) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	org, err := s.orgs.GetOrganization(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+// ListOrganizations returns the organizations userID belongs to.
+func (s *Service) ListOrganizations(ctx context.Context, userID string) ([]*model.OrganizationMember, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	return s.orgs.ListUserMemberships(ctx, userID)
+}
+
+// UpdateOrganization replaces the name and settings of orgID with those in
+// update. Zero values fall back to the service-wide defaults.
+func (s *Service) UpdateOrganization(ctx context.Context, orgID string, update *model.Organization) (*model.Organization, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	org := *update
+	org.ID = orgID
+	org.Name = strings.TrimSpace(org.Name)
+	if org.Name == "" {
+		return nil, &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+	switch org.RegistrationMode {
+	case "", RegistrationOpen, RegistrationClosed, RegistrationInviteOnly, RegistrationDomains:
+	default:
+		return nil, &service.FieldError{Field: "registration_mode", Reason: "REGISTRATION_MODE_INVALID", Message: "unknown registration mode " + org.RegistrationMode}
+	}
+	var domains []string
+	for _, d := range org.RegistrationAllowedDomains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" && !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	org.RegistrationAllowedDomains = domains
+	if org.RegistrationMode == RegistrationDomains && len(domains) == 0 {
+		return nil, &service.FieldError{Field: "registration_allowed_domains", Reason: "REQUIRED", Message: "domains mode needs at least one domain"}
+	}
+	if org.AccessTokenTTLSeconds < 0 || time.Duration(org.AccessTokenTTLSeconds)*time.Second > maxOrgAccessTTL {
+		return nil, &service.FieldError{Field: "access_token_ttl_seconds", Reason: "OUT_OF_RANGE", Message: "access token lifetime must be between 0 and 24 hours"}
+	}
+	if org.RefreshTokenTTLSeconds < 0 || time.Duration(org.RefreshTokenTTLSeconds)*time.Second > maxOrgRefreshTTL {
+		return nil, &service.FieldError{Field: "refresh_token_ttl_seconds", Reason: "OUT_OF_RANGE", Message: "refresh token lifetime must be between 0 and 90 days"}
+	}
+
+	updated, err := s.orgs.UpdateOrganization(ctx, &org)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, orgID)
+	return updated, nil
+}
+
+func (s *Service) ListOrganizationMembers(ctx context.Context, orgID string) ([]*model.OrganizationMember, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	return s.orgs.ListMembers(ctx, orgID)
+}
+
+// SetOrganizationMember replaces the roles of a member of orgID, or adds a
+// user with a pending invitation to orgID. New roles show up in the user's
+// next access token.
+func (s *Service) SetOrganizationMember(ctx context.Context, orgID, userID string, roles []string) (*model.OrganizationMember, error) {
+	if s.orgs == nil {
+		return nil, errOrganizationsDisabled
+	}
+	_, err := s.orgs.GetMembership(ctx, orgID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.checkInvited(ctx, orgID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cleaned := cleanRoles(roles)
+	m, err := s.orgs.SetMember(ctx, orgID, userID, cleaned)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, userID)
+	service.AuditDetail(ctx, "roles", strings.Join(cleaned, ","))
+	return m, nil
+}
+
+// checkInvited fails unless userID holds a pending invitation to orgID, so
+// admins cannot pull arbitrary accounts into their organization.
+func (s *Service) checkInvited(ctx context.Context, orgID, userID string) error {
+	notInvited := &service.FieldError{Field: "user_id", Reason: "NOT_INVITED", Message: "user is not a member of the organization and has no pending invitation"}
+	if s.invitations == nil {
+		return notInvited
+	}
+	u, err := s.db.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notInvited
+	}
+	if err != nil {
+		return err
+	}
+	invitations, err := s.invitations.ListUserInvitations(ctx, userID, u.GetEmail())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, inv := range invitations {
+		if inv.OrgID == orgID && inv.Status(now) == model.InvitationPending {
+			return nil
+		}
+	}
+	return notInvited
+}
+
+// RemoveOrganizationMember removes userID from orgID and revokes their
+// tokens, which may still name the organization.
+func (s *Service) RemoveOrganizationMember(ctx context.Context, orgID, userID string) error {
+	if s.orgs == nil {
+		return errOrganizationsDisabled
+	}
+	if err := s.orgs.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	s.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, userID)
+	if err := s.RevokeAllTokens(ctx, userID); err != nil {
+		log.Printf("revoke tokens of removed member %s: %v", userID, err)
+	}
+	return nil
+}
+
+// SwitchOrganization issues a new token pair for the caller with ref
+// (slug or ID) as the active organization.
+func (s *Service) SwitchOrganization(ctx context.Context, userID, ref string) (accessToken, refreshToken string, accessTTL, refreshTTL time.Duration, err error) {
+	if s.orgs == nil {
+		return "", "", 0, 0, errOrganizationsDisabled
+	}
+	if strings.TrimSpace(ref) == "" {
+		return "", "", 0, 0, &service.FieldError{Field: "organization", Reason: "REQUIRED", Message: "organization is required"}
+	}
+	u, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+	if u.GetStatus() != entity.UserStatusActive {
+		return "", "", 0, 0, service.ErrInvalidToken
+	}
+	org, member, err := s.resolveMembership(ctx, userID, ref)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+	return s.issueTokens(ctx, u, org, member)
+}
+
+// findOrganization looks an organization up by ID or slug.
+func (s *Service) findOrganization(ctx context.Context, ref string) (*model.Organization, error) {
+	ref = strings.TrimSpace(ref)
+	var (
+		org *model.Organization
+		err error
+	)
+	if uuidPattern.MatchString(ref) {
+		org, err = s.orgs.GetOrganization(ctx, ref)
+	} else {
+		org, err = s.orgs.GetOrganizationBySlug(ctx, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+// resolveMembership picks the organization a session is bound to. An empty
+// ref selects the user's oldest membership; users without any get a token
+// without organization.
+func (s *Service) resolveMembership(ctx context.Context, userID, ref string) (*model.Organization, *model.OrganizationMember, error) {
+	if s.orgs == nil {
+		return nil, nil, nil
+	}
+	var org *model.Organization
+	if strings.TrimSpace(ref) == "" {
+		memberships, err := s.orgs.ListUserMemberships(ctx, userID)
+		if err != nil || len(memberships) == 0 {
+			return nil, nil, err
+		}
+		if org, err = s.orgs.GetOrganization(ctx, memberships[0].OrgID); err != nil {
+			return nil, nil, err
+		}
+		return org, memberships[0], nil
+	}
+
+	org, err := s.findOrganization(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.orgs.GetMembership(ctx, org.ID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, service.ErrNotOrganizationMember
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, member, nil
+}
+
+// issueTokens signs an access and refresh token for u in org and caches
+// the refresh token. Organization lifetimes override the defaults.
+func (s *Service) issueTokens(ctx context.Context, u entity.UserEntity, org *model.Organization, member *model.OrganizationMember) (accessToken, refreshToken string, accessTTL, refreshTTL time.Duration, err error) {
+	opts := append([]token.Option{token.WithRoles(u.GetRoles())}, s.metadataTokenOptions(u)...)
+	accessTTL, refreshTTL = token.AccessTTL(), token.RefreshTTL()
+	if org != nil {
+		orgOpts, err := s.orgTokenOptions(ctx, member)
+		if err != nil {
+			return "", "", 0, 0, err
+		}
+		opts = append(opts, orgOpts...)
+		accessTTL, refreshTTL = org.AccessTTL(accessTTL), org.RefreshTTL(refreshTTL)
+	}
+
+	accessToken, accessTTL, err = token.GenerateJWTWithTTL(u.GetID(), u.GetEmail(), accessTTL, opts...)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+	refreshToken, refreshTTL, err = token.GenerateRefreshJWTWithTTL(u.GetID(), u.GetEmail(), refreshTTL, opts...)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+
+	// 只缓存 refresh token
+	if err := s.cache.StoreToken(ctx, u.GetID(), refreshToken, refreshTTL); err != nil {
+		return "", "", 0, 0, err
+	}
+	return accessToken, refreshToken, accessTTL, refreshTTL, nil
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+// Scopes a personal access token can be granted.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var knownScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// roleAdmin is required to grant ScopeAdmin.
+const roleAdmin = "admin"
+
+// patTouchInterval limits how often last_used_at is written for a busy token.
+const patTouchInterval = time.Minute
+
+var errPATsDisabled = errors.New("personal access tokens are not configured")
+
+// CreatePersonalAccessToken issues a token for userID that acts in orgID.
+// The raw token is returned once and never stored.
+func (s *Service) CreatePersonalAccessToken(ctx context.Context, userID, orgID, name string, scopes []string, ttl time.Duration) (*model.PersonalAccessToken, string, error) {
+	if s.pats == nil {
+		return nil, "", errPATsDisabled
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+	if len(scopes) == 0 {
+		return nil, "", &service.FieldError{Field: "scopes", Reason: "REQUIRED", Message: "at least one scope is required"}
+	}
+
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	isAdmin := slices.Contains(user.GetRoles(), roleAdmin)
+	if !isAdmin && s.orgs != nil && orgID != "" {
+		if m, err := s.orgs.GetMembership(ctx, orgID, userID); err == nil {
+			roles, _, err := s.orgAccess(ctx, m)
+			if err != nil {
+				return nil, "", err
+			}
+			isAdmin = slices.Contains(roles, roleAdmin)
+		}
+	}
+
+	var granted []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(knownScopes, scope) {
+			return nil, "", &service.FieldError{Field: "scopes", Reason: "SCOPE_INVALID", Message: "unknown scope " + scope}
+		}
+		if scope == ScopeAdmin && !isAdmin {
+			return nil, "", &service.FieldError{Field: "scopes", Reason: "SCOPE_NOT_ALLOWED", Message: "admin scope requires the admin role"}
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	raw, prefix := token.GeneratePersonalAccessToken()
+	pat, err := s.pats.CreatePersonalAccessToken(ctx, &model.PersonalAccessToken{
+		UserID:    userID,
+		OrgID:     orgID,
+		Name:      name,
+		Prefix:    prefix,
+		TokenHash: token.HashPersonalAccessToken(raw),
+		Scopes:    granted,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	service.AuditTarget(ctx, pat.ID)
+	service.AuditDetail(ctx, "prefix", pat.Prefix)
+	return pat, raw, nil
+}
+
+func (s *Service) ListPersonalAccessTokens(ctx context.Context, userID string) ([]*model.PersonalAccessToken, error) {
+	if s.pats == nil {
+		return nil, errPATsDisabled
+	}
+	return s.pats.ListPersonalAccessTokens(ctx, userID)
+}
+
+func (s *Service) RevokePersonalAccessToken(ctx context.Context, userID, id string) error {
+	if s.pats == nil {
+		return errPATsDisabled
+	}
+	if err := s.pats.RevokePersonalAccessToken(ctx, userID, id); err != nil {
+		return err
+	}
+	service.AuditTarget(ctx, id)
+	return nil
+}
+
+// AuthenticatePersonalAccessToken resolves a raw token to claims shaped like
+// those of an access JWT. Roles and organization membership are read on
+// every call, so a removed role takes effect immediately.
+func (s *Service) AuthenticatePersonalAccessToken(ctx context.Context, raw string) (*token.Claims, error) {
+	if s.pats == nil {
+		return nil, service.ErrInvalidToken
+	}
+
+	pat, err := s.pats.GetPersonalAccessTokenByHash(ctx, token.HashPersonalAccessToken(raw))
+	if err != nil {
+		return nil, service.ErrInvalidToken
+	}
+	now := time.Now()
+	if !pat.Active(now) {
+		return nil, service.ErrInvalidToken
+	}
+
+	user, err := s.db.GetUserByID(ctx, pat.UserID)
+	if err != nil || user.GetStatus() != entity.UserStatusActive {
+		return nil, service.ErrInvalidToken
+	}
+	var orgRoles []string
+	if pat.OrgID != "" && s.orgs != nil {
+		m, err := s.orgs.GetMembership(ctx, pat.OrgID, pat.UserID)
+		if err != nil {
+			return nil, service.ErrInvalidToken
+		}
+		if orgRoles, _, err = s.orgAccess(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > patTouchInterval {
+		if err := s.pats.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+			log.Printf("touch personal access token %s: %v", pat.ID, err)
+		}
+	}
+
+	return &token.Claims{
+		TokenType: token.TokenTypePAT,
+		UserID:    user.GetID(),
+		Email:     user.GetEmail(),
+		Roles:     user.GetRoles(),
+		Scopes:    pat.Scopes,
+		OrgID:     pat.OrgID,
+		OrgRoles:  orgRoles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        pat.ID,
+			IssuedAt:  jwt.NewNumericDate(pat.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(pat.ExpiresAt),
+		},
+	}, nil
+}
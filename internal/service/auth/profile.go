@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/linktemplate"
+)
+
+func (s *Service) UpdateProfile(ctx context.Context, userID, username string) (entity.UserEntity, error) {
+	username, err := normalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.db.UpdateUsername(ctx, userID, username)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateProfile(ctx, userID)
+	return user, nil
+}
+
+// RequestEmailChange parks newEmail on the account and mails a confirmation
+// link to it. The current address keeps working until the link is used and
+// only receives a notice.
+func (s *Service) RequestEmailChange(ctx context.Context, userID, newEmail, confirmLink string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if newEmail == "" {
+		return &service.FieldError{Field: "new_email", Reason: "REQUIRED", Message: "new email is required"}
+	}
+
+	if err := s.checkEmailDomain(ctx, "new_email", newEmail); err != nil {
+		return err
+	}
+
+	_, err := s.db.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return service.ErrEmailInUse
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	err = s.inTx(ctx, func(ctx context.Context) error {
+		user, err := s.db.SetPendingEmail(ctx, userID, newEmail)
+		if err != nil {
+			return err
+		}
+		changeToken, err := s.tokens.Issue(ctx, userID, entity.TokenPurposeEmailChange, s.verifyTokenTTL)
+		if err != nil {
+			return err
+		}
+
+		locales := requestLocales(ctx, user)
+		if err := s.sendTemplate(ctx, newEmail, email.TemplateEmailChange, locales, email.Data{
+			Username:  user.GetUsername(),
+			Link:      linktemplate.Expand(confirmLink, changeToken),
+			ExpiresAt: time.Now().Add(s.verifyTokenTTL),
+		}); err != nil {
+			return err
+		}
+
+		return s.sendTemplate(ctx, user.GetEmail(), email.TemplateEmailChangeNotice, locales, email.Data{
+			Username: user.GetUsername(),
+			NewEmail: newEmail,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidateProfile(ctx, userID)
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending address. The link is only used
+// up if the swap succeeds, so e.g. an address taken in the meantime leaves
+// it valid for another try.
+func (s *Service) ConfirmEmailChange(ctx context.Context, changeToken string) error {
+	var userID string
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		userID, err = s.tokens.Consume(ctx, entity.TokenPurposeEmailChange, changeToken)
+		if err != nil {
+			return err
+		}
+		service.AuditTarget(ctx, userID)
+		user, err := s.db.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.db.ConfirmEmailChange(ctx, user.GetID(), user.GetPendingEmail()); err != nil {
+			return err
+		}
+		event := userEvent(user)
+		event.Email = user.GetPendingEmail()
+		return s.publish(ctx, entity.EventUserEmailChanged, event)
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidateProfile(ctx, userID)
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"github.com/shinoda4/sd-svc-auth/pkg/token"
 )
 
@@ -36,8 +37,33 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (newAccessTo
 		return "", 0, service.ErrInvalidToken
 	}
 
+	// 平台角色、状态和 metadata 以数据库为准，已删除或停用的账号不能刷新
+	user, err := s.db.GetUserByID(ctx, claims.UserID)
+	if err != nil || user.GetStatus() != entity.UserStatusActive {
+		return "", 0, service.ErrInvalidToken
+	}
+	opts := append([]token.Option{token.WithRoles(user.GetRoles())}, s.metadataTokenOptions(user)...)
+	ttl := token.AccessTTL()
+	if claims.OrgID != "" && s.orgs != nil {
+		// 组织角色以数据库为准，被移出组织后不能再刷新
+		org, err := s.orgs.GetOrganization(ctx, claims.OrgID)
+		if err != nil {
+			return "", 0, service.ErrInvalidToken
+		}
+		member, err := s.orgs.GetMembership(ctx, claims.OrgID, claims.UserID)
+		if err != nil {
+			return "", 0, service.ErrInvalidToken
+		}
+		orgOpts, err := s.orgTokenOptions(ctx, member)
+		if err != nil {
+			return "", 0, err
+		}
+		opts = append(opts, orgOpts...)
+		ttl = org.AccessTTL(ttl)
+	}
+
 	// 生成新的 access token
-	newAccessToken, accessTTL, err = token.GenerateJWT(claims.UserID, claims.Email)
+	newAccessToken, accessTTL, err = token.GenerateJWTWithTTL(user.GetID(), user.GetEmail(), ttl, opts...)
 	if err != nil {
 		return "", 0, err
 	}
@@ -18,39 +18,124 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"fmt"
-	"os"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/shinoda4/sd-svc-auth/internal/service"
 	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"github.com/shinoda4/sd-svc-auth/pkg/email"
-	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"github.com/shinoda4/sd-svc-auth/pkg/linktemplate"
 )
 
-func (s *Service) Register(ctx context.Context, userEmail, username, password string, sendEmail bool, verifyLink string) (entity.UserEntity, string, error) {
-	user, err := s.db.CreateUser(ctx, userEmail, username, password)
+// Verification emails may be resent resendVerificationLimit times per
+// address within resendVerificationWindow.
+const (
+	resendVerificationLimit  = 3
+	resendVerificationWindow = time.Hour
+)
+
+// Register creates an account that joins org (slug or ID, default
+// organization when empty), subject to that organization's policy.
+func (s *Service) Register(ctx context.Context, userEmail, username, password, org string, sendEmail bool, verifyLink string) (entity.UserEntity, error) {
+	joinOrg, err := s.registrationOrganization(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	initialStatus, err := s.policyFor(joinOrg).checkRegistration(userEmail)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	verifyToken := token.GenerateVerifyToken()
-	if err := s.db.SetVerifyToken(ctx, user.GetID(), verifyToken); err != nil {
-		return nil, "", err
+	if err := s.checkEmailDomain(ctx, "email", userEmail); err != nil {
+		return nil, err
 	}
-	if sendEmail {
-		subject := "Verify your email!"
-		fullLink := fmt.Sprintf("%s?token=%s", verifyLink, verifyToken)
-		body := fmt.Sprintf("Dear <b>%s</b>, please finish your account validation by clicking the following link: <a href='%s'>Verify Email</a>", user.GetUsername(), fullLink)
 
-		emailAddress := os.Getenv("EMAIL_ADDRESS")
-		if emailAddress == "" {
-			return user, "", errors.New("EMAIL_ADDRESS environment variable not set")
+	username, err = normalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 用户、验证 token 和验证邮件一起提交，邮件由 outbox 在后台发送
+	var user entity.UserEntity
+	err = s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.db.CreateUser(ctx, userEmail, username, password, initialStatus)
+		if err != nil {
+			return err
 		}
+		service.AuditTarget(ctx, user.GetID())
 
-		err := email.SendEmail(emailAddress, user.GetEmail(), subject, body)
+		event := userEvent(user)
+		if joinOrg != nil {
+			if _, err := s.orgs.SetMember(ctx, joinOrg.ID, user.GetID(), nil); err != nil {
+				return err
+			}
+			event.OrgID = joinOrg.ID
+		}
+		if err := s.publish(ctx, entity.EventUserRegistered, event); err != nil {
+			return err
+		}
+
+		verifyToken, err := s.tokens.Issue(ctx, user.GetID(), entity.TokenPurposeEmailVerify, s.verifyTokenTTL)
 		if err != nil {
-			return user, "", err
+			return err
+		}
+		if sendEmail {
+			return s.sendVerificationEmail(ctx, user, verifyToken, verifyLink)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return user, verifyToken, nil
+	return user, nil
+}
+
+// ResendVerification mails a new verification link to addr if it belongs
+// to an unverified account, invalidating the previous link. It succeeds
+// for unknown or verified addresses too, so callers cannot probe which
+// addresses are registered.
+func (s *Service) ResendVerification(ctx context.Context, addr, verifyLink string) error {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return &service.FieldError{Field: "email", Reason: "REQUIRED", Message: "email is required"}
+	}
+
+	// 按地址限流，未知地址同样计数以免泄露是否已注册
+	n, err := s.cache.CountVerificationResend(ctx, strings.ToLower(addr), resendVerificationWindow)
+	if err != nil {
+		log.Printf("count verification resends for %s: %v", addr, err)
+	} else if n > resendVerificationLimit {
+		return service.ErrTooManyRequests
+	}
+
+	user, err := s.db.GetUserByEmail(ctx, addr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.GetEmailVerified() || user.GetStatus() == entity.UserStatusRejected || user.GetStatus() == entity.UserStatusDisabled {
+		return nil
+	}
+
+	return s.inTx(ctx, func(ctx context.Context) error {
+		verifyToken, err := s.tokens.Issue(ctx, user.GetID(), entity.TokenPurposeEmailVerify, s.verifyTokenTTL)
+		if err != nil {
+			return err
+		}
+		return s.sendVerificationEmail(ctx, user, verifyToken, verifyLink)
+	})
+}
+
+func (s *Service) sendVerificationEmail(ctx context.Context, user entity.UserEntity, verifyToken, verifyLink string) error {
+	return s.sendTemplate(ctx, user.GetEmail(), email.TemplateVerifyEmail, requestLocales(ctx, user), email.Data{
+		Username:  user.GetUsername(),
+		Link:      linktemplate.Expand(verifyLink, verifyToken),
+		ExpiresAt: time.Now().Add(s.verifyTokenTTL),
+	})
 }
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+)
+
+// Registration modes accepted by RegistrationPolicy.Mode.
+const (
+	RegistrationOpen       = "open"
+	RegistrationClosed     = "closed"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationDomains    = "domains"
+)
+
+// RegistrationPolicy controls who may call Register. Invitations are not
+// affected by it.
+type RegistrationPolicy struct {
+	Mode string
+	// AllowedDomains is consulted in RegistrationDomains mode.
+	AllowedDomains []string
+	// RequireApproval parks new accounts in pending_approval until an
+	// admin approves them.
+	RequireApproval bool
+}
+
+func WithRegistrationPolicy(p RegistrationPolicy) Option {
+	return func(s *Service) {
+		s.registration = p
+	}
+}
+
+func emailDomain(addr string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(addr[at+1:])
+}
+
+// checkRegistration returns the status new accounts should start with, or
+// an error when the policy refuses the registration.
+func (p RegistrationPolicy) checkRegistration(userEmail string) (string, error) {
+	switch p.Mode {
+	case RegistrationClosed:
+		return "", service.ErrRegistrationClosed
+	case RegistrationInviteOnly:
+		return "", service.ErrInviteOnly
+	case RegistrationDomains:
+		domain := emailDomain(userEmail)
+		allowed := false
+		for _, d := range p.AllowedDomains {
+			if strings.EqualFold(d, domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "", service.ErrEmailDomainNotAllowed
+		}
+	}
+
+	if p.RequireApproval {
+		return entity.UserStatusPendingApproval, nil
+	}
+	return entity.UserStatusActive, nil
+}
+
+// policyFor returns the registration policy of org. An organization that
+// sets a mode replaces the service-wide mode and domains; approval is
+// required if either asks for it.
+func (s *Service) policyFor(org *model.Organization) RegistrationPolicy {
+	p := s.registration
+	if org == nil {
+		return p
+	}
+	if org.RegistrationMode != "" {
+		p.Mode = org.RegistrationMode
+		p.AllowedDomains = org.RegistrationAllowedDomains
+	}
+	p.RequireApproval = p.RequireApproval || org.RegistrationRequireApproval
+	return p
+}
+
+// registrationOrganization resolves the organization a self-registered user
+// joins: ref (slug or ID), or the default organization.
+func (s *Service) registrationOrganization(ctx context.Context, ref string) (*model.Organization, error) {
+	if s.orgs == nil {
+		return nil, nil
+	}
+	if strings.TrimSpace(ref) == "" {
+		ref = model.DefaultOrganizationID
+	}
+	return s.findOrganization(ctx, ref)
+}
+
+// ListPendingRegistrations lists the members of orgID waiting for approval.
+func (s *Service) ListPendingRegistrations(ctx context.Context, orgID string) ([]entity.UserEntity, error) {
+	return s.db.ListUsersByStatus(ctx, orgID, entity.UserStatusPendingApproval)
+}
+
+// ApproveRegistration activates a pending account that joined orgID.
+func (s *Service) ApproveRegistration(ctx context.Context, orgID, userID string) error {
+	return s.decideRegistration(ctx, orgID, userID, entity.UserStatusActive, email.TemplateRegistrationApproved, "")
+}
+
+// RejectRegistration refuses a pending account that joined orgID.
+func (s *Service) RejectRegistration(ctx context.Context, orgID, userID, reason string) error {
+	return s.decideRegistration(ctx, orgID, userID, entity.UserStatusRejected, email.TemplateRegistrationRejected, reason)
+}
+
+func (s *Service) decideRegistration(ctx context.Context, orgID, userID, to, template, reason string) error {
+	// 只能处理本组织的注册申请
+	if _, err := s.db.GetOrgUserByID(ctx, orgID, userID); err != nil {
+		return err
+	}
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		user, err := s.db.UpdateUserStatus(ctx, userID, entity.UserStatusPendingApproval, to)
+		if err != nil {
+			return err
+		}
+		data := email.Data{Username: user.GetUsername(), Reason: reason}
+		return s.sendTemplate(ctx, user.GetEmail(), template, userLocales(user), data)
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidateProfile(ctx, userID)
+	return nil
+}
@@ -18,70 +18,54 @@ package auth
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
-	"fmt"
-	"os"
 	"time"
 
 	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"github.com/shinoda4/sd-svc-auth/pkg/email"
+	"github.com/shinoda4/sd-svc-auth/pkg/linktemplate"
 )
 
-func (s *Service) PasswordReset(ctx context.Context, emailAddr string, username string) error {
+// resetTokenTTL is how long password reset links stay valid.
+const resetTokenTTL = time.Hour
 
-	user, err := s.db.GetUserByEmail(ctx, emailAddr)
-	if err != nil {
-		return err
-	}
-
-	if username != user.GetUsername() {
-		return service.ErrUsernameNotValid
-	}
-
-	emailAddress := os.Getenv("EMAIL_ADDRESS")
-	if emailAddress == "" {
-		return errors.New("EMAIL_ADDRESS environment variable not set")
+// PasswordReset mails a reset link built from the resetLink template, see
+// pkg/linktemplate.
+func (s *Service) PasswordReset(ctx context.Context, identifier string, kind IdentifierKind, resetLink string) error {
+	if resetLink == "" {
+		return errors.New("no password reset URL configured")
 	}
 
-	tokenBytes := make([]byte, 32)
-	_, _ = rand.Read(tokenBytes)
-	resetToken := hex.EncodeToString(tokenBytes)
-	expire := time.Now().Add(time.Hour * 1)
-
-	if err := s.db.SaveResetToken(ctx, user.GetID(), resetToken, expire); err != nil {
+	user, err := s.lookupUser(ctx, identifier, kind)
+	if err != nil {
 		return err
 	}
-
-	baseURL := os.Getenv("RESET_PASSWORD_URL")
-	if baseURL == "" {
-		return errors.New("RESET_PASSWORD_URL environment variable not set")
-	}
-	fullLink := fmt.Sprintf("%s?token=%s", baseURL, resetToken)
-
-	body := fmt.Sprintf(
-		"Dear <b>%s</b>,<br><br>Please click the following link to reset your password:<br><a href='%s'>Reset Password</a><br><br>If you did not request this, please ignore this email.",
-		username, fullLink,
-	)
-
-	return email.SendEmail(emailAddress, emailAddr, "Reset your password!", body)
+	service.AuditTarget(ctx, user.GetID())
+
+	return s.inTx(ctx, func(ctx context.Context) error {
+		resetToken, err := s.tokens.Issue(ctx, user.GetID(), entity.TokenPurposePasswordReset, resetTokenTTL)
+		if err != nil {
+			return err
+		}
+		return s.sendTemplate(ctx, user.GetEmail(), email.TemplatePasswordReset, requestLocales(ctx, user), email.Data{
+			Username:  user.GetUsername(),
+			Link:      linktemplate.Expand(resetLink, resetToken),
+			ExpiresAt: time.Now().Add(resetTokenTTL),
+		})
+	})
 }
 
 func (s *Service) PasswordResetConfirm(ctx context.Context, token, newPassword string) error {
-	user, err := s.db.GetUserByResetToken(ctx, token)
-	if err != nil {
-		return err
-	}
-
-	if time.Now().After(user.GetResetTokenExpire()) {
-		return errors.New("Token expired!")
-	}
-
-	err = s.db.UpdatePassword(ctx, user.GetID(), newPassword)
-	if err != nil {
-		return err
-	}
-
-	return s.db.ClearResetToken(ctx, user.GetID())
+	return s.inTx(ctx, func(ctx context.Context) error {
+		userID, err := s.tokens.Consume(ctx, entity.TokenPurposePasswordReset, token)
+		if err != nil {
+			return err
+		}
+		service.AuditTarget(ctx, userID)
+		if err := s.db.UpdatePassword(ctx, userID, newPassword); err != nil {
+			return err
+		}
+		return s.publish(ctx, entity.EventUserPasswordChanged, UserEvent{UserID: userID})
+	})
 }
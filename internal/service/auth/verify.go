@@ -18,45 +18,58 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"os"
 
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"github.com/shinoda4/sd-svc-auth/pkg/email"
 	"github.com/shinoda4/sd-svc-auth/pkg/token"
 )
 
 func (s *Service) ValidateToken(ctx context.Context, tokenStr string) (*token.Claims, error) {
+	if token.IsPersonalAccessToken(tokenStr) {
+		return s.AuthenticatePersonalAccessToken(ctx, tokenStr)
+	}
 	claims, err := token.ParseAndValidate(tokenStr)
 	if err != nil {
 		return nil, err
 	}
+	if s.TokenRevoked(ctx, claims) {
+		return nil, service.ErrInvalidToken
+	}
 	return claims, nil
 }
 
 func (s *Service) VerifyEmail(ctx context.Context, token string, sendEmail bool) error {
-	user, err := s.db.GetUserByVerifyToken(ctx, token)
-	if err != nil {
-		return err
-	}
-
-	if err := s.db.SetEmailVerified(ctx, user.GetID()); err != nil {
-		return fmt.Errorf("failed to verify email: %w", err)
-	}
+	var userID string
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		userID, err = s.tokens.Consume(ctx, entity.TokenPurposeEmailVerify, token)
+		if err != nil {
+			return err
+		}
+		service.AuditTarget(ctx, userID)
+		user, err := s.db.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
 
-	if sendEmail {
-		subject := fmt.Sprintf("Welcome! %s", user.GetUsername())
-		body := "Dear <b>" + user.GetUsername() + "</b>, you are already verified! Welcome to our system!"
-		emailAddress := os.Getenv("EMAIL_ADDRESS")
-		if emailAddress == "" {
-			return errors.New("EMAIL_ADDRESS environment variable not set")
+		if err := s.db.SetEmailVerified(ctx, user.GetID()); err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		if err := s.publish(ctx, entity.EventUserVerified, userEvent(user)); err != nil {
+			return err
 		}
 
-		welcomeErr := email.SendEmail(emailAddress, user.GetEmail(), subject, body)
-		if welcomeErr != nil {
-			return welcomeErr
+		if sendEmail {
+			data := email.Data{Username: user.GetUsername()}
+			return s.sendTemplate(ctx, user.GetEmail(), email.TemplateWelcome, requestLocales(ctx, user), data)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-
+	s.invalidateProfile(ctx, userID)
 	return nil
 }
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clientapp resolves which frontend the links in an email point
+// at. Each registered app carries URL templates for the links the service
+// mails; a request picks its app by client ID and can never supply a URL
+// itself, so emailed links cannot be turned into an open redirect.
+package clientapp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/linktemplate"
+)
+
+// Links are the templates of one app, see pkg/linktemplate. Empty entries
+// mean no link of that kind can be built.
+type Links struct {
+	Verify      string
+	Reset       string
+	EmailChange string
+	Invitation  string
+	MagicLink   string
+}
+
+// or fills empty entries from defaults.
+func (l Links) or(defaults Links) Links {
+	pick := func(v, def string) string {
+		if v != "" {
+			return v
+		}
+		return def
+	}
+	return Links{
+		Verify:      pick(l.Verify, defaults.Verify),
+		Reset:       pick(l.Reset, defaults.Reset),
+		EmailChange: pick(l.EmailChange, defaults.EmailChange),
+		Invitation:  pick(l.Invitation, defaults.Invitation),
+		MagicLink:   pick(l.MagicLink, defaults.MagicLink),
+	}
+}
+
+func linksOf(app *model.ClientApp) Links {
+	return Links{
+		Verify:      app.VerifyURL,
+		Reset:       app.ResetURL,
+		EmailChange: app.EmailChangeURL,
+		Invitation:  app.InvitationURL,
+		MagicLink:   app.MagicLinkURL,
+	}
+}
+
+var clientIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,63}$`)
+
+type Service struct {
+	repo     entity.ClientAppRepository
+	defaults Links
+}
+
+// NewService resolves apps from repo. defaults serve requests that name no
+// app and fill templates an app leaves empty.
+func NewService(repo entity.ClientAppRepository, defaults Links) *Service {
+	return &Service{repo: repo, defaults: defaults}
+}
+
+// Links returns the templates of the app registered as clientID, or the
+// defaults when clientID is empty. Unknown IDs are rejected as a
+// *service.FieldError on client_id.
+func (s *Service) Links(ctx context.Context, clientID string) (Links, error) {
+	if clientID == "" {
+		return s.defaults, nil
+	}
+	app, err := s.repo.GetClientApp(ctx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Links{}, &service.FieldError{Field: "client_id", Reason: "UNKNOWN_CLIENT", Message: "client " + clientID + " is not registered"}
+	}
+	if err != nil {
+		return Links{}, err
+	}
+	return linksOf(app).or(s.defaults), nil
+}
+
+// List returns the apps managed by orgID.
+func (s *Service) List(ctx context.Context, orgID string) ([]*model.ClientApp, error) {
+	return s.repo.ListClientApps(ctx, orgID)
+}
+
+func (s *Service) Create(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error) {
+	if err := validate(app); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateClientApp(ctx, app)
+}
+
+// Update replaces the name and every template of the app registered as
+// app.ClientID by app.OrgID.
+func (s *Service) Update(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error) {
+	if err := validate(app); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateClientApp(ctx, app)
+}
+
+func (s *Service) Delete(ctx context.Context, orgID, clientID string) error {
+	return s.repo.DeleteClientApp(ctx, orgID, clientID)
+}
+
+func validate(app *model.ClientApp) error {
+	app.ClientID = strings.TrimSpace(app.ClientID)
+	app.Name = strings.TrimSpace(app.Name)
+	if !clientIDPattern.MatchString(app.ClientID) {
+		return &service.FieldError{Field: "client_id", Reason: "CLIENT_ID_INVALID",
+			Message: "client_id must be 2-64 lower-case letters, digits, dots, underscores or hyphens"}
+	}
+	if app.Name == "" {
+		return &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+	for field, tmpl := range map[string]*string{
+		"verify_url":       &app.VerifyURL,
+		"reset_url":        &app.ResetURL,
+		"email_change_url": &app.EmailChangeURL,
+		"invitation_url":   &app.InvitationURL,
+		"magic_link_url":   &app.MagicLinkURL,
+	} {
+		*tmpl = strings.TrimSpace(*tmpl)
+		if err := linktemplate.Validate(*tmpl); err != nil {
+			return &service.FieldError{Field: field, Reason: "URL_INVALID", Message: err.Error()}
+		}
+	}
+	return nil
+}
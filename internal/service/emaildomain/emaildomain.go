@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package emaildomain decides whether an email address may be used to sign
+// up, based on a disposable-provider list, admin rules and optionally MX
+// records.
+package emaildomain
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/mail"
+	"os"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// Reasons reported in service.FieldError.
+const (
+	ReasonInvalid    = "EMAIL_INVALID"
+	ReasonDisposable = "EMAIL_DOMAIN_DISPOSABLE"
+	ReasonDenied     = "EMAIL_DOMAIN_DENIED"
+	ReasonNoMX       = "EMAIL_DOMAIN_NO_MX"
+)
+
+//go:embed disposable_domains.txt
+var defaultDisposable string
+
+// Resolver is the subset of *net.Resolver used for MX checks.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type Checker struct {
+	repo       entity.EmailDomainRepository
+	disposable map[string]bool
+	resolver   Resolver
+}
+
+type Option func(*Checker)
+
+// WithResolver enables MX checks through r.
+func WithResolver(r Resolver) Option {
+	return func(c *Checker) {
+		c.resolver = r
+	}
+}
+
+// WithDisposableDomains adds domains to the built-in disposable list.
+func WithDisposableDomains(domains []string) Option {
+	return func(c *Checker) {
+		for _, d := range domains {
+			c.disposable[strings.ToLower(d)] = true
+		}
+	}
+}
+
+func NewChecker(repo entity.EmailDomainRepository, opts ...Option) *Checker {
+	c := &Checker{repo: repo, disposable: map[string]bool{}}
+	domains, _ := ParseList(strings.NewReader(defaultDisposable))
+	WithDisposableDomains(domains)(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ParseList reads one domain per line, skipping blanks and # comments.
+func ParseList(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	return domains, scanner.Err()
+}
+
+// LoadFile reads an additional disposable-domain list from disk.
+func LoadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseList(f)
+}
+
+// candidates returns the domain and each of its parents, most specific
+// first: a.b.example.com, b.example.com, example.com.
+func candidates(domain string) []string {
+	var out []string
+	for {
+		out = append(out, domain)
+		dot := strings.Index(domain, ".")
+		if dot < 0 || !strings.Contains(domain[dot+1:], ".") {
+			return out
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// Check validates addr for use as an account email. field names the request
+// field reported in the returned *service.FieldError.
+func (c *Checker) Check(ctx context.Context, field, addr string) error {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Address != addr {
+		return &service.FieldError{Field: field, Reason: ReasonInvalid, Message: "not a valid email address"}
+	}
+	domain := strings.ToLower(addr[strings.LastIndex(addr, "@")+1:])
+	names := candidates(domain)
+
+	rules, err := c.repo.FindEmailDomainRules(ctx, names)
+	if err != nil {
+		return err
+	}
+	byDomain := make(map[string]string, len(rules))
+	for _, r := range rules {
+		byDomain[r.Domain] = r.Action
+	}
+
+	allowed := false
+	for _, name := range names {
+		if action, ok := byDomain[name]; ok {
+			if action == model.EmailDomainDeny {
+				return &service.FieldError{Field: field, Reason: ReasonDenied, Message: fmt.Sprintf("email domain %s is not allowed", domain)}
+			}
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		for _, name := range names {
+			if c.disposable[name] {
+				return &service.FieldError{Field: field, Reason: ReasonDisposable, Message: "disposable email addresses are not allowed"}
+			}
+		}
+	}
+
+	if c.resolver != nil {
+		return c.checkMX(ctx, field, domain)
+	}
+	return nil
+}
+
+// checkMX rejects domains that definitely cannot receive mail. Lookup
+// failures other than "not found" are logged and let through.
+func (c *Checker) checkMX(ctx context.Context, field, domain string) error {
+	noMail := &service.FieldError{Field: field, Reason: ReasonNoMX, Message: fmt.Sprintf("email domain %s does not accept mail", domain)}
+
+	records, err := c.resolver.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		log.Printf("mx lookup %s: %v", domain, err)
+		return nil
+	}
+	// A single "." record is a null MX (RFC 7505): the domain accepts no mail.
+	if len(records) == 1 && records[0].Host == "." {
+		return noMail
+	}
+	if len(records) > 0 {
+		return nil
+	}
+
+	// Without MX records the domain's own A/AAAA record is the implicit MX
+	// (RFC 5321 section 5.1).
+	addrs, err := c.resolver.LookupHost(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		log.Printf("host lookup %s: %v", domain, err)
+		return nil
+	}
+	if len(addrs) == 0 {
+		return noMail
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+func (c *Checker) ListRules(ctx context.Context) ([]*model.EmailDomainRule, error) {
+	return c.repo.ListEmailDomainRules(ctx)
+}
+
+func (c *Checker) SetRule(ctx context.Context, domain, action, note, createdBy string) (*model.EmailDomainRule, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" || strings.Contains(domain, "@") {
+		return nil, &service.FieldError{Field: "domain", Reason: ReasonInvalid, Message: "not a valid domain"}
+	}
+	if action != model.EmailDomainAllow && action != model.EmailDomainDeny {
+		return nil, &service.FieldError{Field: "action", Reason: "INVALID_ACTION", Message: `must be "allow" or "deny"`}
+	}
+	return c.repo.UpsertEmailDomainRule(ctx, &model.EmailDomainRule{
+		Domain:    domain,
+		Action:    action,
+		Note:      note,
+		CreatedBy: createdBy,
+	})
+}
+
+func (c *Checker) DeleteRule(ctx context.Context, domain string) error {
+	return c.repo.DeleteEmailDomainRule(ctx, strings.ToLower(strings.TrimSpace(domain)))
+}
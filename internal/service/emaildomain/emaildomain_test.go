@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package emaildomain
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+)
+
+// fakeResolver answers lookups from maps so tests do not depend on DNS.
+// Unknown names behave like NXDOMAIN; err, when set, fails every lookup.
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+	err   error
+}
+
+func (f fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	records, ok := f.mx[strings.ToLower(name)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	addrs, ok := f.hosts[strings.ToLower(host)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+type fakeRules map[string]string
+
+func (f fakeRules) ListEmailDomainRules(context.Context) ([]*model.EmailDomainRule, error) {
+	var out []*model.EmailDomainRule
+	for domain, action := range f {
+		out = append(out, &model.EmailDomainRule{Domain: domain, Action: action})
+	}
+	return out, nil
+}
+
+func (f fakeRules) FindEmailDomainRules(_ context.Context, domains []string) ([]*model.EmailDomainRule, error) {
+	var out []*model.EmailDomainRule
+	for _, d := range domains {
+		if action, ok := f[d]; ok {
+			out = append(out, &model.EmailDomainRule{Domain: d, Action: action})
+		}
+	}
+	return out, nil
+}
+
+func (f fakeRules) UpsertEmailDomainRule(_ context.Context, rule *model.EmailDomainRule) (*model.EmailDomainRule, error) {
+	f[rule.Domain] = rule.Action
+	return rule, nil
+}
+
+func (f fakeRules) DeleteEmailDomainRule(_ context.Context, domain string) error {
+	delete(f, domain)
+	return nil
+}
+
+func reasonOf(err error) string {
+	var fe *service.FieldError
+	if errors.As(err, &fe) {
+		return fe.Reason
+	}
+	return ""
+}
+
+func TestCheckRules(t *testing.T) {
+	rules := fakeRules{
+		"blocked.example":  model.EmailDomainDeny,
+		"mailinator.com":   model.EmailDomainAllow,
+		"corp.example":     model.EmailDomainAllow,
+		"eng.corp.example": model.EmailDomainDeny,
+	}
+	c := NewChecker(rules, WithDisposableDomains([]string{"throwaway.test"}))
+
+	tests := []struct {
+		name   string
+		addr   string
+		reason string
+	}{
+		{"plain domain", "alice@example.com", ""},
+		{"invalid address", "not-an-email", ReasonInvalid},
+		{"display name", "Alice <alice@example.com>", ReasonInvalid},
+		{"built-in disposable", "bob@guerrillamail.com", ReasonDisposable},
+		{"disposable subdomain", "bob@x.10minutemail.com", ReasonDisposable},
+		{"configured disposable", "bob@throwaway.test", ReasonDisposable},
+		{"deny rule", "eve@blocked.example", ReasonDenied},
+		{"deny rule covers subdomains", "eve@mail.blocked.example", ReasonDenied},
+		{"deny rule is case-insensitive", "eve@BLOCKED.example", ReasonDenied},
+		{"allow rule overrides disposable list", "bob@mailinator.com", ""},
+		{"most specific rule wins", "carol@eng.corp.example", ReasonDenied},
+		{"parent allow rule", "dave@sales.corp.example", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Check(context.Background(), "email", tt.addr)
+			if got := reasonOf(err); got != tt.reason {
+				t.Fatalf("Check(%q) = %v, want reason %q", tt.addr, err, tt.reason)
+			}
+			if tt.reason == "" && err != nil {
+				t.Fatalf("Check(%q) = %v", tt.addr, err)
+			}
+		})
+	}
+}
+
+func TestCheckFieldName(t *testing.T) {
+	c := NewChecker(fakeRules{})
+	var fe *service.FieldError
+	if err := c.Check(context.Background(), "new_email", "bob@guerrillamail.com"); !errors.As(err, &fe) || fe.Field != "new_email" {
+		t.Fatalf("got %v, want a field error for new_email", err)
+	}
+}
+
+func TestCheckMX(t *testing.T) {
+	resolver := fakeResolver{
+		mx: map[string][]*net.MX{
+			"mail.example":   {{Host: "mx1.mail.example.", Pref: 10}},
+			"nullmx.example": {{Host: ".", Pref: 0}},
+			"empty.example":  {},
+		},
+		hosts: map[string][]string{
+			"aonly.example":    {"192.0.2.10"},
+			"aaaaonly.example": {"2001:db8::10"},
+			"empty.example":    {},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		resolver fakeResolver
+		addr     string
+		reason   string
+	}{
+		{"mx record", resolver, "a@mail.example", ""},
+		{"null mx", resolver, "a@nullmx.example", ReasonNoMX},
+		{"no mx, A record is the implicit mx", resolver, "a@aonly.example", ""},
+		{"no mx, AAAA record is the implicit mx", resolver, "a@aaaaonly.example", ""},
+		{"no mx and no address", resolver, "a@empty.example", ReasonNoMX},
+		{"nxdomain", resolver, "a@missing.example", ReasonNoMX},
+		{"dns failure lets the address through", fakeResolver{err: &net.DNSError{Err: "timeout", IsTimeout: true}}, "a@mail.example", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(fakeRules{}, WithResolver(tt.resolver))
+			err := c.Check(context.Background(), "email", tt.addr)
+			if got := reasonOf(err); got != tt.reason || (tt.reason == "" && err != nil) {
+				t.Fatalf("Check(%q) = %v, want reason %q", tt.addr, err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestCheckDenyRuleBeforeMX(t *testing.T) {
+	c := NewChecker(fakeRules{"blocked.example": model.EmailDomainDeny}, WithResolver(fakeResolver{}))
+	if got := reasonOf(c.Check(context.Background(), "email", "a@blocked.example")); got != ReasonDenied {
+		t.Fatalf("reason = %q, want %q", got, ReasonDenied)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	domains, err := ParseList(strings.NewReader("# comment\n\nFoo.Example\n  bar.example  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(domains, ",") != "foo.example,bar.example" {
+		t.Fatalf("ParseList = %v", domains)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// Values of audit_events.outcome.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditQuery filters the audit log. Empty fields match everything. Events
+// are returned newest first, starting below BeforeID when it is set.
+type AuditQuery struct {
+	ActorID  string
+	TargetID string
+	OrgID    string
+	Action   string
+	Outcome  string
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// AuditRepository appends to and reads the audit log.
+type AuditRepository interface {
+	AppendAuditEvent(ctx context.Context, ev *model.AuditEvent) (*model.AuditEvent, error)
+	ListAuditEvents(ctx context.Context, q AuditQuery) ([]*model.AuditEvent, error)
+	// PurgeAuditEvents deletes events that occurred before the given time.
+	PurgeAuditEvents(ctx context.Context, before time.Time) (int, error)
+}
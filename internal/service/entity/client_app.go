@@ -0,0 +1,35 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// ClientAppRepository stores registered client apps. GetClientApp resolves
+// a client ID in any organization and returns an error wrapping
+// sql.ErrNoRows for unknown IDs; Update and Delete only touch apps of
+// app.OrgID or orgID and return repo.ErrNotFound otherwise.
+type ClientAppRepository interface {
+	CreateClientApp(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error)
+	UpdateClientApp(ctx context.Context, app *model.ClientApp) (*model.ClientApp, error)
+	GetClientApp(ctx context.Context, clientID string) (*model.ClientApp, error)
+	ListClientApps(ctx context.Context, orgID string) ([]*model.ClientApp, error)
+	DeleteClientApp(ctx context.Context, orgID, clientID string) error
+}
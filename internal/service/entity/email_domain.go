@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type EmailDomainRepository interface {
+	ListEmailDomainRules(ctx context.Context) ([]*model.EmailDomainRule, error)
+	// FindEmailDomainRules returns the rules stored for any of the domains.
+	FindEmailDomainRules(ctx context.Context, domains []string) ([]*model.EmailDomainRule, error)
+	UpsertEmailDomainRule(ctx context.Context, rule *model.EmailDomainRule) (*model.EmailDomainRule, error)
+	DeleteEmailDomainRule(ctx context.Context, domain string) error
+}
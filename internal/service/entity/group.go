@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// GroupRepository stores groups. Every group belongs to one organization
+// and only that organization's members can join it.
+type GroupRepository interface {
+	// CreateGroup inserts the group and its members in one transaction.
+	CreateGroup(ctx context.Context, orgID, displayName, externalID string, roles, memberIDs []string) (*model.Group, error)
+	GetGroup(ctx context.Context, orgID, id string) (*model.Group, error)
+	QueryGroups(ctx context.Context, q ListQuery) ([]*model.Group, int, error)
+	// UpdateGroup replaces the attributes and the full member list.
+	UpdateGroup(ctx context.Context, orgID, id, displayName, externalID string, memberIDs []string) (*model.Group, error)
+	// UpdateGroupDetails changes the name and roles, leaving members alone.
+	UpdateGroupDetails(ctx context.Context, orgID, id, displayName string, roles []string) (*model.Group, error)
+	DeleteGroup(ctx context.Context, orgID, id string) error
+	ListGroupMembers(ctx context.Context, orgID string, groupIDs []string) ([]*model.GroupMember, error)
+	ListUserGroups(ctx context.Context, orgID string, userIDs []string) ([]*model.GroupMember, error)
+
+	AddGroupMembers(ctx context.Context, orgID, groupID string, userIDs []string) error
+	RemoveGroupMember(ctx context.Context, orgID, groupID, userID string) error
+	// AddSubgroup nests memberGroupID in groupID. It fails if groupID is
+	// already reachable from memberGroupID.
+	AddSubgroup(ctx context.Context, orgID, groupID, memberGroupID string) error
+	RemoveSubgroup(ctx context.Context, orgID, groupID, memberGroupID string) error
+	ListSubgroups(ctx context.Context, orgID string, groupIDs []string) ([]*model.Subgroup, error)
+	// ListEffectiveGroups returns every group userID belongs to in orgID,
+	// directly or through nested groups.
+	ListEffectiveGroups(ctx context.Context, orgID, userID string) ([]*model.Group, error)
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, inv *model.Invitation) (*model.Invitation, error)
+	GetInvitation(ctx context.Context, id string) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, orgID string, pendingOnly bool) ([]*model.Invitation, error)
+	// ListUserInvitations returns invitations sent to email or accepted as
+	// userID, in every organization.
+	ListUserInvitations(ctx context.Context, userID, email string) ([]*model.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, id string) error
+	// AcceptInvitation creates the invited user, adds them to the
+	// invitation's organization with its roles and marks the invitation
+	// accepted in a single transaction.
+	AcceptInvitation(ctx context.Context, id, username, password string) (UserEntity, error)
+}
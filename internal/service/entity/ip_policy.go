@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type IPPolicyRepository interface {
+	// ListIPPolicies returns the policies of every organization and the
+	// platform-wide ones, for the in-memory rule set.
+	ListIPPolicies(ctx context.Context) ([]*model.IPPolicy, error)
+	CreateIPPolicy(ctx context.Context, p *model.IPPolicy) (*model.IPPolicy, error)
+	// DeleteIPPolicy deletes a policy of orgID, or a platform-wide one
+	// when orgID is empty.
+	DeleteIPPolicy(ctx context.Context, orgID, id string) error
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// Purposes of one-time tokens. A token is only accepted for the purpose it
+// was issued for.
+const (
+	TokenPurposeEmailVerify   = "email_verify"
+	TokenPurposeEmailChange   = "email_change"
+	TokenPurposePasswordReset = "password_reset"
+)
+
+// OneTimeTokenRepository stores hashed single-use tokens. Lookups that find
+// no live token return an error wrapping sql.ErrNoRows.
+type OneTimeTokenRepository interface {
+	// CreateOneTimeToken stores t and deletes the user's earlier tokens for
+	// the same purpose, so only the newest one can be redeemed.
+	CreateOneTimeToken(ctx context.Context, t *model.OneTimeToken) (*model.OneTimeToken, error)
+	// ConsumeOneTimeToken marks the unexpired, unused token with hash as
+	// used and returns it.
+	ConsumeOneTimeToken(ctx context.Context, purpose, hash string) (*model.OneTimeToken, error)
+	// ConsumeUserOneTimeToken redeems userID's live token for purpose if
+	// hash matches it. A mismatch counts as a failed attempt; once
+	// maxAttempts have failed the token is no longer accepted.
+	ConsumeUserOneTimeToken(ctx context.Context, userID, purpose, hash string, maxAttempts int) (*model.OneTimeToken, error)
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type OrganizationRepository interface {
+	// CreateOrganization inserts the organization and makes ownerID its
+	// first member with the given roles.
+	CreateOrganization(ctx context.Context, org *model.Organization, ownerID string, ownerRoles []string) (*model.Organization, error)
+	// The Get methods return sql.ErrNoRows when nothing matches.
+	GetOrganization(ctx context.Context, id string) (*model.Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (*model.Organization, error)
+	ListOrganizations(ctx context.Context) ([]*model.Organization, error)
+	UpdateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error)
+	GetMembership(ctx context.Context, orgID, userID string) (*model.OrganizationMember, error)
+	ListMembers(ctx context.Context, orgID string) ([]*model.OrganizationMember, error)
+	// ListUserMemberships returns the user's memberships, oldest first.
+	ListUserMemberships(ctx context.Context, userID string) ([]*model.OrganizationMember, error)
+	// SetMember adds the user to the organization or replaces their roles.
+	SetMember(ctx context.Context, orgID, userID string, roles []string) (*model.OrganizationMember, error)
+	RemoveMember(ctx context.Context, orgID, userID string) error
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// Values of outbox.status.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// OutboxRepository stores messages for background delivery. Enqueue joins
+// the transaction on ctx, so the message is only stored if the change it
+// describes commits.
+type OutboxRepository interface {
+	EnqueueOutbox(ctx context.Context, topic string, payload json.RawMessage) (*model.OutboxMessage, error)
+	// ClaimOutbox locks up to limit due messages for lease and counts the
+	// attempt. Messages claimed by another worker are skipped.
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxMessage, error)
+	// MarkOutboxSent records delivery and clears the payload.
+	MarkOutboxSent(ctx context.Context, id string) error
+	// RetryOutbox releases the message for another attempt at next.
+	RetryOutbox(ctx context.Context, id string, next time.Time, lastError string) error
+	// DeadOutbox stops retrying the message.
+	DeadOutbox(ctx context.Context, id string, lastError string) error
+	// CountOutbox returns the number of messages per status.
+	CountOutbox(ctx context.Context) (map[string]int, error)
+	// PurgeOutbox deletes messages delivered before the given time.
+	PurgeOutbox(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type PersonalAccessTokenRepository interface {
+	CreatePersonalAccessToken(ctx context.Context, t *model.PersonalAccessToken) (*model.PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, hash string) (*model.PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID string) ([]*model.PersonalAccessToken, error)
+	// RevokePersonalAccessToken only matches tokens owned by userID.
+	RevokePersonalAccessToken(ctx context.Context, userID, id string) error
+	TouchPersonalAccessToken(ctx context.Context, id string) error
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type ScimClientRepository interface {
+	CreateScimClient(ctx context.Context, c *model.ScimClient) (*model.ScimClient, error)
+	// GetScimClientByHash ignores revoked clients.
+	GetScimClientByHash(ctx context.Context, hash string) (*model.ScimClient, error)
+	ListScimClients(ctx context.Context, orgID string) ([]*model.ScimClient, error)
+	RevokeScimClient(ctx context.Context, orgID, id string) error
+	TouchScimClient(ctx context.Context, id string) error
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+type ServiceAccountRepository interface {
+	CreateServiceAccount(ctx context.Context, sa *model.ServiceAccount) (*model.ServiceAccount, error)
+	// GetServiceAccountByClientID ignores disabled accounts.
+	GetServiceAccountByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, orgID string) ([]*model.ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, orgID, id string) error
+}
@@ -18,19 +18,55 @@ package entity
 
 import (
 	"context"
+	"encoding/json"
 	"time"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+// Values of users.status.
+const (
+	UserStatusActive          = "active"
+	UserStatusPendingApproval = "pending_approval"
+	UserStatusRejected        = "rejected"
+	// UserStatusDisabled is set when a provisioning client deactivates the
+	// user (SCIM active=false).
+	UserStatusDisabled = "disabled"
+)
+
+// Metadata documents of a user, named after their columns.
+const (
+	MetadataUser = "user_metadata"
+	MetadataApp  = "app_metadata"
 )
 
 type UserRepository interface {
-	CreateUser(ctx context.Context, email, username, password string) (UserEntity, error)
+	CreateUser(ctx context.Context, email, username, password, status string) (UserEntity, error)
 	GetUserByEmail(ctx context.Context, email string) (UserEntity, error)
-	SetVerifyToken(ctx context.Context, userID, token string) error
-	GetUserByVerifyToken(ctx context.Context, token string) (UserEntity, error)
+	GetUserByUsername(ctx context.Context, username string) (UserEntity, error)
+	GetUserByID(ctx context.Context, userID string) (UserEntity, error)
+	// GetOrgUserByID is GetUserByID limited to members of orgID.
+	GetOrgUserByID(ctx context.Context, orgID, userID string) (UserEntity, error)
 	SetEmailVerified(ctx context.Context, userID string) error
-	SaveResetToken(ctx context.Context, s string, resetToken string, expire time.Time) error
-	GetUserByResetToken(ctx context.Context, token string) (UserEntity, error)
+	UpdateUsername(ctx context.Context, userID, username string) (UserEntity, error)
+	SetPendingEmail(ctx context.Context, userID, email string) (UserEntity, error)
+	ConfirmEmailChange(ctx context.Context, userID, email string) error
+	SoftDeleteUser(ctx context.Context, userID string, deleteAfter time.Time) error
+	// PurgeDeletedUsers removes soft-deleted users due before before and
+	// returns their IDs, each with the organizations it belonged to.
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (map[string][]string, error)
+	ListUsersByStatus(ctx context.Context, orgID, status string) ([]UserEntity, error)
+	UpdateUserStatus(ctx context.Context, userID, from, to string) (UserEntity, error)
+	// QueryUsers lists members of q.OrgID. GetRoles of the results returns
+	// the roles held in that organization.
+	QueryUsers(ctx context.Context, q ListQuery) ([]UserEntity, int, error)
+	CreateProvisionedUser(ctx context.Context, orgID string, p ProvisionedUser) (UserEntity, error)
+	UpdateProvisionedUser(ctx context.Context, orgID, userID string, p ProvisionedUser) (UserEntity, error)
 	UpdatePassword(ctx context.Context, userID, newPassword string) error
-	ClearResetToken(ctx context.Context, userID string) error
+	// UpdateMetadata replaces the metadata document named by field
+	// (MetadataUser or MetadataApp) with fn's result. The row stays locked
+	// while fn runs so concurrent updates do not lose each other's keys.
+	UpdateMetadata(ctx context.Context, userID, field string, fn func(current json.RawMessage) (json.RawMessage, error)) (UserEntity, error)
 }
 
 type UserEntity interface {
@@ -39,7 +75,38 @@ type UserEntity interface {
 	GetUsername() string
 	GetEmailVerified() bool
 	CheckPassword(password string) bool
-	GetResetTokenExpire() time.Time
+	GetRoles() []string
+	GetPendingEmail() string
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+	GetStatus() string
+	GetExternalID() string
+	GetGivenName() string
+	GetFamilyName() string
+	GetUserMetadata() json.RawMessage
+	GetAppMetadata() json.RawMessage
+}
+
+// ListQuery selects a page of an organization's records matching an
+// optional SCIM filter.
+type ListQuery struct {
+	OrgID  string
+	Filter scim.Filter
+	Offset int
+	Limit  int
+}
+
+// ProvisionedUser holds the attributes an identity provider manages. The
+// email is trusted as verified and the account has no usable password.
+// Roles are granted in the provisioning organization only.
+type ProvisionedUser struct {
+	Email      string
+	Username   string
+	ExternalID string
+	GivenName  string
+	FamilyName string
+	Roles      []string
+	Active     bool
 }
 
 type CacheRepository interface {
@@ -47,4 +114,13 @@ type CacheRepository interface {
 	GetToken(ctx context.Context, userID string) (string, error)
 	SetBlacklist(ctx context.Context, token string, ttl time.Duration) error
 	DeleteRefreshToken(ctx context.Context, userID string) error
+	GetProfile(ctx context.Context, userID string) (string, error)
+	StoreProfile(ctx context.Context, userID, profile string, ttl time.Duration) error
+	DeleteProfile(ctx context.Context, userID string) error
+	RevokeUserTokens(ctx context.Context, userID string, at time.Time, ttl time.Duration) error
+	GetTokensRevokedAt(ctx context.Context, userID string) (time.Time, error)
+	GetEffectiveRoles(ctx context.Context, orgID, userID string) (string, int64, error)
+	StoreEffectiveRoles(ctx context.Context, orgID, userID string, version int64, data string, ttl time.Duration) error
+	InvalidateEffectiveRoles(ctx context.Context, orgID string) error
+	CountVerificationResend(ctx context.Context, addr string, window time.Duration) (int64, error)
 }
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entity
+
+import (
+	"context"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+)
+
+// User lifecycle events published to webhooks.
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserVerified        = "user.verified"
+	EventUserPasswordChanged = "user.password_changed"
+	EventUserEmailChanged    = "user.email_changed"
+	EventUserDisabled        = "user.disabled"
+	EventUserDeleted         = "user.deleted"
+)
+
+// WebhookEvents lists every event a subscription can filter on.
+var WebhookEvents = []string{
+	EventUserRegistered,
+	EventUserVerified,
+	EventUserPasswordChanged,
+	EventUserEmailChanged,
+	EventUserDisabled,
+	EventUserDeleted,
+}
+
+// Values of webhook_deliveries.status.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookRepository stores subscriptions and their delivery log. Get
+// lookups return an error wrapping sql.ErrNoRows for unknown IDs; Update
+// and Delete only touch subscriptions of sub.OrgID or orgID and return
+// repo.ErrNotFound otherwise.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error)
+	UpdateWebhook(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id string) (*model.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context, orgID string) ([]*model.WebhookSubscription, error)
+	// ListWebhooksForEvent returns the enabled subscriptions of orgIDs that
+	// receive event.
+	ListWebhooksForEvent(ctx context.Context, event string, orgIDs []string) ([]*model.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, orgID, id string) error
+
+	CreateWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (*model.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error)
+	// ListWebhookDeliveries returns the newest deliveries of a subscription
+	// first.
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*model.WebhookDelivery, error)
+	// RecordWebhookAttempt counts an attempt and stores its outcome.
+	RecordWebhookAttempt(ctx context.Context, id, status string, responseStatus int, lastError string) error
+}
@@ -16,9 +16,37 @@
 
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrInvalidPassword = errors.New("invalid password")
 var ErrInvalidToken = errors.New("invalid token")
 var ErrEmailNotVerified = errors.New("email not verified")
 var ErrUsernameNotValid = errors.New("username not valid")
+var ErrInvalidIPPolicy = errors.New("invalid ip policy")
+var ErrEmailInUse = errors.New("email already in use")
+var ErrInvitationInvalid = errors.New("invitation invalid")
+var ErrRegistrationClosed = errors.New("registration is closed")
+var ErrInviteOnly = errors.New("registration is by invitation only")
+var ErrEmailDomainNotAllowed = errors.New("email domain not allowed")
+var ErrPendingApproval = errors.New("account pending approval")
+var ErrAccountRejected = errors.New("account registration rejected")
+var ErrImpersonationNotAllowed = errors.New("impersonation not allowed")
+var ErrAccountDisabled = errors.New("account disabled")
+var ErrOrganizationNotFound = errors.New("organization not found")
+var ErrNotOrganizationMember = errors.New("not a member of this organization")
+var ErrNoActiveOrganization = errors.New("no active organization")
+var ErrTooManyRequests = errors.New("too many requests, try again later")
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string
+	Reason  string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
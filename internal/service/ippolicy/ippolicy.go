@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ippolicy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+type rule struct {
+	policy *model.IPPolicy
+	prefix netip.Prefix
+}
+
+func (r rule) matchesMethod(method string) bool {
+	p := r.policy.Method
+	if p == "*" {
+		return true
+	}
+	if strings.HasSuffix(p, "*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(p, "*"))
+	}
+	return p == method
+}
+
+// Service keeps a compiled copy of the CIDR policies in memory so the
+// interceptor never touches the database on the request path.
+type Service struct {
+	repo  entity.IPPolicyRepository
+	mu    sync.RWMutex
+	rules []rule
+}
+
+func NewService(repo entity.IPPolicyRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Reload replaces the in-memory rule set with the rows currently stored.
+func (s *Service) Reload(ctx context.Context) (int, error) {
+	policies, err := s.repo.ListIPPolicies(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rules := make([]rule, 0, len(policies))
+	for _, p := range policies {
+		prefix, err := netip.ParsePrefix(p.CIDR)
+		if err != nil {
+			log.Printf("skip ip policy %s: %v", p.ID, err)
+			continue
+		}
+		rules = append(rules, rule{policy: p, prefix: prefix})
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.mu.Unlock()
+	return len(rules), nil
+}
+
+// Watch reloads the policies every interval so changes made on other
+// replicas are picked up without a restart.
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reload(ctx); err != nil {
+				log.Printf("reload ip policies: %v", err)
+			}
+		}
+	}
+}
+
+// Check decides whether addr may call method under the policies of orgID,
+// or under the platform-wide policies when orgID is empty. Deny rules
+// always win. When at least one allow rule targets the method, the
+// address must match one of them. The returned policy is the rule that
+// caused a denial, or nil when the call is allowed or no rule matched.
+func (s *Service) Check(orgID, method string, addr netip.Addr) (bool, *model.IPPolicy) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addr = addr.Unmap()
+	var allowRules []rule
+	for _, r := range s.rules {
+		if r.policy.OrgID != orgID || !r.matchesMethod(method) {
+			continue
+		}
+		switch r.policy.Action {
+		case model.IPPolicyDeny:
+			if r.prefix.Contains(addr) {
+				return false, r.policy
+			}
+		case model.IPPolicyAllow:
+			allowRules = append(allowRules, r)
+		}
+	}
+
+	if len(allowRules) == 0 {
+		return true, nil
+	}
+	for _, r := range allowRules {
+		if r.prefix.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, allowRules[0].policy
+}
+
+// List returns the policies of orgID, followed by the platform-wide ones
+// when platform is set.
+func (s *Service) List(ctx context.Context, orgID string, platform bool) ([]*model.IPPolicy, error) {
+	policies, err := s.repo.ListIPPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var own, global []*model.IPPolicy
+	for _, p := range policies {
+		switch {
+		case p.OrgID == "" && platform:
+			global = append(global, p)
+		case p.OrgID != "" && p.OrgID == orgID:
+			own = append(own, p)
+		}
+	}
+	return append(own, global...), nil
+}
+
+// Create adds a policy to orgID, or a platform-wide one when orgID is
+// empty.
+func (s *Service) Create(ctx context.Context, orgID, method, action, cidr, description, createdBy string) (*model.IPPolicy, error) {
+	if action != model.IPPolicyAllow && action != model.IPPolicyDeny {
+		return nil, fmt.Errorf("%w: action must be %q or %q", service.ErrInvalidIPPolicy, model.IPPolicyAllow, model.IPPolicyDeny)
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		addr, addrErr := netip.ParseAddr(cidr)
+		if addrErr != nil {
+			return nil, fmt.Errorf("%w: %v", service.ErrInvalidIPPolicy, err)
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if method == "" {
+		method = "*"
+	}
+
+	created, err := s.repo.CreateIPPolicy(ctx, &model.IPPolicy{
+		OrgID:       orgID,
+		Method:      method,
+		Action:      action,
+		CIDR:        prefix.Masked().String(),
+		Description: description,
+		CreatedBy:   createdBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Delete removes a policy of orgID, or a platform-wide one when orgID is
+// empty.
+func (s *Service) Delete(ctx context.Context, orgID, id string) error {
+	if err := s.repo.DeleteIPPolicy(ctx, orgID, id); err != nil {
+		return err
+	}
+	_, err := s.Reload(ctx)
+	return err
+}
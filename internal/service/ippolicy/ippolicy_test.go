@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ippolicy
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+type fakeRepo struct {
+	entity.IPPolicyRepository
+	policies []*model.IPPolicy
+}
+
+func (f *fakeRepo) ListIPPolicies(context.Context) ([]*model.IPPolicy, error) {
+	return f.policies, nil
+}
+
+func newTestService(t *testing.T, policies ...*model.IPPolicy) *Service {
+	t.Helper()
+	s := NewService(&fakeRepo{policies: policies})
+	if _, err := s.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func policy(id, orgID, method, action, cidr string) *model.IPPolicy {
+	return &model.IPPolicy{ID: id, OrgID: orgID, Method: method, Action: action, CIDR: cidr}
+}
+
+const loginMethod = "/auth.v1.AuthService/Login"
+
+func TestCheck(t *testing.T) {
+	s := newTestService(t,
+		policy("office", "org-1", "*", model.IPPolicyAllow, "10.0.0.0/8"),
+		policy("bad-host", "org-1", "*", model.IPPolicyDeny, "10.1.2.3/32"),
+		policy("admin-vpn", "org-1", "/auth.v1.AuthService/Admin*", model.IPPolicyAllow, "192.168.1.0/24"),
+		policy("scanner", "", loginMethod, model.IPPolicyDeny, "203.0.113.0/24"),
+		policy("broken", "org-1", "*", model.IPPolicyDeny, "not-a-cidr"),
+	)
+
+	tests := []struct {
+		name        string
+		orgID       string
+		method      string
+		addr        string
+		wantAllowed bool
+		wantPolicy  string
+	}{
+		{"allow rule matches", "org-1", loginMethod, "10.9.9.9", true, ""},
+		{"outside every allow rule", "org-1", loginMethod, "198.51.100.7", false, "office"},
+		{"deny wins over a matching allow", "org-1", loginMethod, "10.1.2.3", false, "bad-host"},
+		{"ipv4-mapped ipv6 address", "org-1", loginMethod, "::ffff:10.1.2.3", false, "bad-host"},
+		{"method prefix rule allows", "org-1", "/auth.v1.AuthService/AdminListUsers", "192.168.1.20", true, ""},
+		{"method prefix rule only covers its methods", "org-1", loginMethod, "192.168.1.20", false, "office"},
+		{"any matching allow rule suffices", "org-1", "/auth.v1.AuthService/AdminListUsers", "10.9.9.9", true, ""},
+		{"no allow rule matches", "org-1", "/auth.v1.AuthService/AdminListUsers", "172.16.0.1", false, "office"},
+		{"other organization has no rules", "org-2", loginMethod, "198.51.100.7", true, ""},
+		{"platform deny only applies platform-wide", "org-1", loginMethod, "203.0.113.5", false, "office"},
+		{"platform deny", "", loginMethod, "203.0.113.5", false, "scanner"},
+		{"platform rule for another method", "", "/auth.v1.AuthService/Register", "203.0.113.5", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, p := s.Check(tt.orgID, tt.method, netip.MustParseAddr(tt.addr))
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+			gotPolicy := ""
+			if p != nil {
+				gotPolicy = p.ID
+			}
+			if gotPolicy != tt.wantPolicy {
+				t.Errorf("policy = %q, want %q", gotPolicy, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestReloadSkipsInvalidCIDR(t *testing.T) {
+	s := NewService(&fakeRepo{policies: []*model.IPPolicy{
+		policy("ok", "", "*", model.IPPolicyDeny, "10.0.0.0/8"),
+		policy("broken", "", "*", model.IPPolicyDeny, "10.0.0.0/33"),
+	}})
+	n, err := s.Reload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Reload loaded %d rules, want 1", n)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package onetimetoken issues and redeems the single-use tokens mailed to
+// users, such as email verification and password reset links. Only
+// SHA-256 hashes are stored, so reading the table does not yield usable
+// tokens.
+package onetimetoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+const (
+	// codeDigits is the length of codes from IssueCode.
+	codeDigits = 6
+	// maxCodeAttempts is how many wrong guesses void a code. Codes have far
+	// less entropy than link tokens, so guessing must be bounded.
+	maxCodeAttempts = 5
+)
+
+type Service struct {
+	repo entity.OneTimeTokenRepository
+}
+
+func NewService(repo entity.OneTimeTokenRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Issue creates a link token for userID valid for ttl. Earlier tokens of
+// the user for the same purpose stop working.
+func (s *Service) Issue(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	raw := token.GenerateOneTimeToken()
+	if err := s.store(ctx, userID, purpose, raw, ttl); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Consume redeems a token issued for purpose and returns its user. Unknown,
+// expired and already used tokens yield service.ErrInvalidToken.
+func (s *Service) Consume(ctx context.Context, purpose, raw string) (string, error) {
+	if raw == "" {
+		return "", service.ErrInvalidToken
+	}
+	t, err := s.repo.ConsumeOneTimeToken(ctx, purpose, token.HashOneTimeToken(raw))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", service.ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.UserID, nil
+}
+
+// IssueCode creates a short numeric code for userID, for flows where the
+// user types the value instead of following a link.
+func (s *Service) IssueCode(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	code := token.GenerateOneTimeCode(codeDigits)
+	if err := s.store(ctx, userID, purpose, code, ttl); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ConsumeCode redeems userID's code for purpose. Wrong codes count against
+// the code's attempt limit and yield service.ErrInvalidToken, as do codes
+// that expired, were used or ran out of attempts.
+func (s *Service) ConsumeCode(ctx context.Context, userID, purpose, code string) error {
+	if code == "" {
+		return service.ErrInvalidToken
+	}
+	_, err := s.repo.ConsumeUserOneTimeToken(ctx, userID, purpose, token.HashOneTimeToken(code), maxCodeAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ErrInvalidToken
+	}
+	return err
+}
+
+func (s *Service) store(ctx context.Context, userID, purpose, raw string, ttl time.Duration) error {
+	_, err := s.repo.CreateOneTimeToken(ctx, &model.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: token.HashOneTimeToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return err
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package onetimetoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+// memoryStore keeps tokens in memory with the semantics the SQL
+// repository implements: consuming checks and marks in one step.
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens []*model.OneTimeToken
+}
+
+func (m *memoryStore) CreateOneTimeToken(_ context.Context, t *model.OneTimeToken) (*model.OneTimeToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.tokens[:0]
+	for _, old := range m.tokens {
+		if old.UserID != t.UserID || old.Purpose != t.Purpose {
+			kept = append(kept, old)
+		}
+	}
+	m.tokens = append(kept, t)
+	return t, nil
+}
+
+func (m *memoryStore) live(t *model.OneTimeToken) bool {
+	return t.ConsumedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+func (m *memoryStore) ConsumeOneTimeToken(_ context.Context, purpose, hash string) (*model.OneTimeToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.Purpose == purpose && t.TokenHash == hash && m.live(t) {
+			now := time.Now()
+			t.ConsumedAt = &now
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("consume one-time token: %w", sql.ErrNoRows)
+}
+
+func (m *memoryStore) ConsumeUserOneTimeToken(_ context.Context, userID, purpose, hash string, maxAttempts int) (*model.OneTimeToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.UserID != userID || t.Purpose != purpose || !m.live(t) || t.Attempts >= maxAttempts {
+			continue
+		}
+		if t.TokenHash != hash {
+			t.Attempts++
+			return nil, fmt.Errorf("one-time token mismatch: %w", sql.ErrNoRows)
+		}
+		now := time.Now()
+		t.ConsumedAt = &now
+		return t, nil
+	}
+	return nil, fmt.Errorf("consume one-time token: %w", sql.ErrNoRows)
+}
+
+var _ entity.OneTimeTokenRepository = (*memoryStore)(nil)
+
+func TestConsume(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(&memoryStore{})
+
+	issue := func(userID, purpose string, ttl time.Duration) string {
+		raw, err := svc.Issue(ctx, userID, purpose, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	verify := issue("user-1", entity.TokenPurposeEmailVerify, time.Hour)
+	reset := issue("user-1", entity.TokenPurposePasswordReset, time.Hour)
+	expired := issue("user-2", entity.TokenPurposeEmailVerify, -time.Second)
+	replaced := issue("user-3", entity.TokenPurposeEmailChange, time.Hour)
+	current := issue("user-3", entity.TokenPurposeEmailChange, time.Hour)
+
+	// Steps run in order; the second redemption of a token must fail.
+	tests := []struct {
+		name     string
+		purpose  string
+		raw      string
+		wantUser string
+		wantErr  error
+	}{
+		{"valid token", entity.TokenPurposeEmailVerify, verify, "user-1", nil},
+		{"used twice", entity.TokenPurposeEmailVerify, verify, "", service.ErrInvalidToken},
+		{"other purpose", entity.TokenPurposeEmailVerify, reset, "", service.ErrInvalidToken},
+		{"own purpose", entity.TokenPurposePasswordReset, reset, "user-1", nil},
+		{"expired", entity.TokenPurposeEmailVerify, expired, "", service.ErrInvalidToken},
+		{"replaced by a newer token", entity.TokenPurposeEmailChange, replaced, "", service.ErrInvalidToken},
+		{"newest token", entity.TokenPurposeEmailChange, current, "user-3", nil},
+		{"empty", entity.TokenPurposeEmailVerify, "", "", service.ErrInvalidToken},
+		{"unknown", entity.TokenPurposeEmailVerify, token.GenerateOneTimeToken(), "", service.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		userID, err := svc.Consume(ctx, tt.purpose, tt.raw)
+		if !errors.Is(err, tt.wantErr) || userID != tt.wantUser {
+			t.Errorf("%s: Consume = (%q, %v), want (%q, %v)", tt.name, userID, err, tt.wantUser, tt.wantErr)
+		}
+	}
+}
+
+func TestConsumeConcurrently(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(&memoryStore{})
+	raw, err := svc.Issue(ctx, "user-1", entity.TokenPurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Consume(ctx, entity.TokenPurposePasswordReset, raw); err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if successes != 1 {
+		t.Errorf("%d concurrent consumes succeeded, want 1", successes)
+	}
+}
+
+func TestConsumeCodeAttempts(t *testing.T) {
+	tests := []struct {
+		name         string
+		wrongGuesses int
+		wantErr      error
+	}{
+		{"right code at once", 0, nil},
+		{"right code on the last attempt", maxCodeAttempts - 1, nil},
+		{"code void after too many wrong guesses", maxCodeAttempts, service.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := &memoryStore{}
+			svc := NewService(store)
+			code, err := svc.IssueCode(ctx, "user-1", entity.TokenPurposeEmailVerify, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(code) != codeDigits {
+				t.Fatalf("code %q has %d digits, want %d", code, len(code), codeDigits)
+			}
+			wrong := "x" + code[1:]
+			for range tt.wrongGuesses {
+				if err := svc.ConsumeCode(ctx, "user-1", entity.TokenPurposeEmailVerify, wrong); !errors.Is(err, service.ErrInvalidToken) {
+					t.Fatalf("wrong code: %v, want %v", err, service.ErrInvalidToken)
+				}
+			}
+			if got := store.tokens[0].Attempts; got != tt.wrongGuesses {
+				t.Errorf("attempts = %d, want %d", got, tt.wrongGuesses)
+			}
+			if err := svc.ConsumeCode(ctx, "user-1", entity.TokenPurposeEmailVerify, code); !errors.Is(err, tt.wantErr) {
+				t.Errorf("right code: %v, want %v", err, tt.wantErr)
+			}
+			if err := svc.ConsumeCode(ctx, "user-1", entity.TokenPurposeEmailVerify, code); !errors.Is(err, service.ErrInvalidToken) {
+				t.Errorf("code reused: %v, want %v", err, service.ErrInvalidToken)
+			}
+		})
+	}
+}
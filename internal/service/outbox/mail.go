@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+)
+
+// TopicMail carries email.Message payloads.
+const TopicMail = "mail"
+
+// EnqueueMail stores msg for background delivery.
+func (s *Service) EnqueueMail(ctx context.Context, msg *email.Message) error {
+	return s.Enqueue(ctx, TopicMail, msg)
+}
+
+// MailHandler delivers TopicMail messages through m. The outbox id becomes
+// the Message-ID or idempotency key. Permanent SMTP rejections (5xx) and
+// HTTP API errors other than 408, 429 and 5xx are not retried.
+func MailHandler(m email.Mailer) Handler {
+	return func(ctx context.Context, id string, payload json.RawMessage) error {
+		var msg email.Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return Permanent(fmt.Errorf("decode mail: %w", err))
+		}
+		msg.ID = id
+		err := m.Send(ctx, &msg)
+		var smtpErr *textproto.Error
+		if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+			return Permanent(err)
+		}
+		var httpErr *email.HTTPError
+		if errors.As(err, &httpErr) && !httpErr.Temporary() {
+			return Permanent(err)
+		}
+		return err
+	}
+}
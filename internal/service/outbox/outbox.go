@@ -0,0 +1,249 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package outbox delivers messages that were stored in the same database
+// transaction as the change they describe. A worker claims due messages,
+// hands them to the handler registered for their topic and retries
+// failures with exponential backoff until they are dead-lettered.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// Handler delivers one message. id is stable across retries and can be
+// used as an idempotency key downstream.
+type Handler func(ctx context.Context, id string, payload json.RawMessage) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying; the message is
+// dead-lettered at once.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// 指标通过 expvar 暴露在 /debug/vars
+var (
+	metrics      = expvar.NewMap("outbox")
+	pendingGauge = new(expvar.Int)
+	deadGauge    = new(expvar.Int)
+	delivered    = new(expvar.Int)
+	retried      = new(expvar.Int)
+	deadLettered = new(expvar.Int)
+)
+
+// handlerTimeout bounds a single delivery attempt.
+const handlerTimeout = 30 * time.Second
+
+func init() {
+	metrics.Set("pending", pendingGauge)
+	metrics.Set("dead", deadGauge)
+	metrics.Set("delivered_total", delivered)
+	metrics.Set("retried_total", retried)
+	metrics.Set("dead_lettered_total", deadLettered)
+}
+
+type Service struct {
+	repo     entity.OutboxRepository
+	handlers map[string]Handler
+
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	retention   time.Duration
+}
+
+type Option func(*Service)
+
+// WithMaxAttempts sets how many deliveries are tried before a message is
+// dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(s *Service) {
+		s.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay after the first failure, which doubles with
+// every further failure up to maxDelay.
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(s *Service) {
+		s.baseDelay = base
+		s.maxDelay = maxDelay
+	}
+}
+
+func NewService(repo entity.OutboxRepository, opts ...Option) *Service {
+	s := &Service{
+		repo:        repo,
+		handlers:    make(map[string]Handler),
+		batchSize:   50,
+		lease:       2 * time.Minute,
+		maxAttempts: 8,
+		baseDelay:   30 * time.Second,
+		maxDelay:    time.Hour,
+		retention:   7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handle registers the handler for topic. It must be called before Run.
+func (s *Service) Handle(topic string, h Handler) {
+	s.handlers[topic] = h
+}
+
+// Enqueue stores payload for delivery under topic. Inside a repository
+// transaction the message is only stored if the transaction commits.
+func (s *Service) Enqueue(ctx context.Context, topic string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s payload: %w", topic, err)
+	}
+	_, err = s.repo.EnqueueOutbox(ctx, topic, raw)
+	return err
+}
+
+// Run delivers due messages every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 一批处理满了就接着处理，不等下一个 tick
+			for {
+				n, err := s.Deliver(ctx)
+				if err != nil {
+					log.Printf("[outbox] deliver: %v", err)
+				}
+				if err != nil || n < s.batchSize {
+					break
+				}
+			}
+			s.refreshGauges(ctx)
+
+			if time.Since(lastPurge) > time.Hour {
+				lastPurge = time.Now()
+				if n, err := s.repo.PurgeOutbox(ctx, time.Now().Add(-s.retention)); err != nil {
+					log.Printf("[outbox] purge: %v", err)
+				} else if n > 0 {
+					log.Printf("[outbox] purged %d delivered messages", n)
+				}
+			}
+		}
+	}
+}
+
+// Deliver claims one batch of due messages and processes it. It returns
+// the number of messages claimed.
+func (s *Service) Deliver(ctx context.Context) (int, error) {
+	msgs, err := s.repo.ClaimOutbox(ctx, s.batchSize, s.lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range msgs {
+		s.process(ctx, m)
+	}
+	return len(msgs), nil
+}
+
+func (s *Service) process(ctx context.Context, m *model.OutboxMessage) {
+	h, ok := s.handlers[m.Topic]
+	if !ok {
+		s.deadLetter(ctx, m, fmt.Errorf("no handler for topic %q", m.Topic))
+		return
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	err := h(hctx, m.ID, json.RawMessage(m.Payload))
+	cancel()
+
+	var perm *permanentError
+	switch {
+	case err == nil:
+		if err := s.repo.MarkOutboxSent(ctx, m.ID); err != nil {
+			// 已经发出去了，租约过期后会重发同一个 id
+			log.Printf("[outbox] %s %s delivered but not marked: %v", m.Topic, m.ID, err)
+			return
+		}
+		delivered.Add(1)
+	case errors.As(err, &perm) || m.Attempts >= s.maxAttempts:
+		s.deadLetter(ctx, m, err)
+	default:
+		next := time.Now().Add(s.backoff(m.Attempts))
+		if err := s.repo.RetryOutbox(ctx, m.ID, next, err.Error()); err != nil {
+			log.Printf("[outbox] reschedule %s %s: %v", m.Topic, m.ID, err)
+			return
+		}
+		retried.Add(1)
+		log.Printf("[outbox] %s %s attempt %d failed, retry at %s: %v", m.Topic, m.ID, m.Attempts, next.Format(time.RFC3339), err)
+	}
+}
+
+func (s *Service) deadLetter(ctx context.Context, m *model.OutboxMessage, cause error) {
+	if err := s.repo.DeadOutbox(ctx, m.ID, cause.Error()); err != nil {
+		log.Printf("[outbox] dead-letter %s %s: %v", m.Topic, m.ID, err)
+		return
+	}
+	deadLettered.Add(1)
+	log.Printf("[outbox] %s %s dead-lettered after %d attempts: %v", m.Topic, m.ID, m.Attempts, cause)
+}
+
+// backoff is the delay after the given number of failed attempts, with up
+// to 20% jitter so a burst of failures does not retry in lockstep.
+func (s *Service) backoff(attempts int) time.Duration {
+	d := s.baseDelay
+	for i := 1; i < attempts && d < s.maxDelay; i++ {
+		d *= 2
+	}
+	if d > s.maxDelay {
+		d = s.maxDelay
+	}
+	return d + time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+func (s *Service) refreshGauges(ctx context.Context) {
+	counts, err := s.repo.CountOutbox(ctx)
+	if err != nil {
+		log.Printf("[outbox] count: %v", err)
+		return
+	}
+	pendingGauge.Set(int64(counts[entity.OutboxPending]))
+	deadGauge.Set(int64(counts[entity.OutboxDead]))
+}
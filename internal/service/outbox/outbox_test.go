@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+)
+
+// fakeOutbox hands out the queued messages once and records what the
+// worker decided for each of them.
+type fakeOutbox struct {
+	entity.OutboxRepository
+	queued []*model.OutboxMessage
+	sent   []string
+	retry  map[string]time.Time
+	dead   map[string]string
+}
+
+func newFakeOutbox(msgs ...*model.OutboxMessage) *fakeOutbox {
+	return &fakeOutbox{queued: msgs, retry: map[string]time.Time{}, dead: map[string]string{}}
+}
+
+func (f *fakeOutbox) ClaimOutbox(_ context.Context, limit int, _ time.Duration) ([]*model.OutboxMessage, error) {
+	n := min(limit, len(f.queued))
+	claimed := f.queued[:n]
+	f.queued = f.queued[n:]
+	return claimed, nil
+}
+
+func (f *fakeOutbox) MarkOutboxSent(_ context.Context, id string) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeOutbox) RetryOutbox(_ context.Context, id string, next time.Time, _ string) error {
+	f.retry[id] = next
+	return nil
+}
+
+func (f *fakeOutbox) DeadOutbox(_ context.Context, id string, lastError string) error {
+	f.dead[id] = lastError
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	s := NewService(nil, WithBackoff(30*time.Second, time.Hour))
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			got := s.backoff(tt.attempts)
+			// up to 20% jitter on top of the base delay
+			if got < tt.want || got > tt.want+tt.want/5 {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempts, got, tt.want, tt.want+tt.want/5)
+				break
+			}
+		}
+	}
+}
+
+func TestDeliverOutcome(t *testing.T) {
+	errTemporary := errors.New("connection refused")
+	tests := []struct {
+		name      string
+		topic     string
+		attempts  int
+		handleErr error
+		want      string
+	}{
+		{"delivered", "mail", 1, nil, "sent"},
+		{"first failure is retried", "mail", 1, errTemporary, "retry"},
+		{"failure before the limit is retried", "mail", 2, errTemporary, "retry"},
+		{"failure on the last attempt", "mail", 3, errTemporary, "dead"},
+		{"permanent failure", "mail", 1, Permanent(errTemporary), "dead"},
+		{"delivered on the last attempt", "mail", 3, nil, "sent"},
+		{"unknown topic", "sms", 1, nil, "dead"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOutbox(&model.OutboxMessage{ID: "msg-1", Topic: tt.topic, Payload: []byte(`{}`), Attempts: tt.attempts})
+			s := NewService(repo, WithMaxAttempts(3), WithBackoff(time.Minute, time.Hour))
+			s.Handle("mail", func(context.Context, string, json.RawMessage) error { return tt.handleErr })
+
+			before := time.Now()
+			if n, err := s.Deliver(context.Background()); err != nil || n != 1 {
+				t.Fatalf("Deliver = (%d, %v), want (1, nil)", n, err)
+			}
+
+			got := ""
+			switch {
+			case len(repo.sent) == 1:
+				got = "sent"
+			case len(repo.dead) == 1:
+				got = "dead"
+			case len(repo.retry) == 1:
+				got = "retry"
+			}
+			if got != tt.want {
+				t.Fatalf("outcome = %q, want %q (sent %v, retry %v, dead %v)", got, tt.want, repo.sent, repo.retry, repo.dead)
+			}
+			// the base delay of one minute doubles with every failed attempt
+			if next, ok := repo.retry["msg-1"]; ok {
+				if delay := time.Minute << (tt.attempts - 1); next.Before(before.Add(delay)) {
+					t.Errorf("retry at %v, want at least %v later", next, delay)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+// directory is an in-memory stand-in for the repositories the service
+// uses. Each fake embeds its interface, so calling a method the tests do
+// not expect panics instead of silently succeeding.
+type directory struct {
+	seq     int
+	users   map[string]*model.User
+	order   []string
+	members map[string]map[string]bool // org -> user IDs
+	groups  map[string]*fakeGroup
+	clients map[string]*model.ScimClient // token hash -> client
+
+	revoked      []string
+	disabled     []string
+	invalidated  int
+	softDeleted  []string
+	touchedCount int
+}
+
+type fakeGroup struct {
+	model.Group
+	members []string
+}
+
+func newDirectory() *directory {
+	return &directory{
+		users:   map[string]*model.User{},
+		members: map[string]map[string]bool{},
+		groups:  map[string]*fakeGroup{},
+		clients: map[string]*model.ScimClient{},
+	}
+}
+
+func (d *directory) nextID() string {
+	d.seq++
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", d.seq, d.seq)
+}
+
+func (d *directory) service() *Service {
+	return NewService(fakeUsers{d: d}, fakeGroups{d: d}, fakeClients{d: d}, fakeOrgs{d: d}, fakeRevoker{d: d}, fakeRoles{d: d}, "https://auth.example.com/scim/v2/")
+}
+
+type fakeUsers struct {
+	entity.UserRepository
+	d *directory
+}
+
+func (f fakeUsers) QueryUsers(_ context.Context, q entity.ListQuery) ([]entity.UserEntity, int, error) {
+	var matched []entity.UserEntity
+	for _, id := range f.d.order {
+		u, ok := f.d.users[id]
+		if ok && f.d.members[q.OrgID][id] && matchUser(u, q.Filter) {
+			matched = append(matched, u)
+		}
+	}
+	total := len(matched)
+	start := min(q.Offset, total)
+	end := min(start+q.Limit, total)
+	return matched[start:end], total, nil
+}
+
+// matchUser understands the eq comparisons the tests and loadUser use.
+func matchUser(u *model.User, f scim.Filter) bool {
+	switch f := f.(type) {
+	case nil:
+		return true
+	case *scim.Logical:
+		if f.Op == "and" {
+			return matchUser(u, f.Left) && matchUser(u, f.Right)
+		}
+		return matchUser(u, f.Left) || matchUser(u, f.Right)
+	case *scim.Compare:
+		want := strings.ToLower(fmt.Sprint(f.Value))
+		switch f.Attr {
+		case "id":
+			return u.ID == want
+		case "username":
+			return strings.ToLower(u.Username) == want
+		case "externalid":
+			return strings.ToLower(u.ExternalID) == want
+		}
+	}
+	return false
+}
+
+func (f fakeUsers) CreateProvisionedUser(_ context.Context, orgID string, p entity.ProvisionedUser) (entity.UserEntity, error) {
+	for _, u := range f.d.users {
+		if strings.EqualFold(u.Username, p.Username) {
+			return nil, repo.ErrUsernameTaken
+		}
+	}
+	now := time.Now()
+	u := &model.User{ID: f.d.nextID(), CreatedAt: now, UpdatedAt: now}
+	setProvisioned(u, p)
+	f.d.users[u.ID] = u
+	f.d.order = append(f.d.order, u.ID)
+	if f.d.members[orgID] == nil {
+		f.d.members[orgID] = map[string]bool{}
+	}
+	f.d.members[orgID][u.ID] = true
+	return u, nil
+}
+
+func (f fakeUsers) UpdateProvisionedUser(_ context.Context, orgID, userID string, p entity.ProvisionedUser) (entity.UserEntity, error) {
+	u, ok := f.d.users[userID]
+	if !ok || !f.d.members[orgID][userID] {
+		return nil, repo.ErrNotFound
+	}
+	updated := *u
+	setProvisioned(&updated, p)
+	updated.UpdatedAt = time.Now()
+	f.d.users[userID] = &updated
+	return &updated, nil
+}
+
+func (f fakeUsers) SoftDeleteUser(_ context.Context, userID string, _ time.Time) error {
+	delete(f.d.users, userID)
+	f.d.softDeleted = append(f.d.softDeleted, userID)
+	return nil
+}
+
+func setProvisioned(u *model.User, p entity.ProvisionedUser) {
+	u.Email, u.Username, u.ExternalID = p.Email, p.Username, p.ExternalID
+	u.GivenName, u.FamilyName = p.GivenName, p.FamilyName
+	u.Roles = p.Roles
+	u.EmailVerified = true
+	u.Status = entity.UserStatusActive
+	if !p.Active {
+		u.Status = entity.UserStatusDisabled
+	}
+}
+
+type fakeGroups struct {
+	entity.GroupRepository
+	d *directory
+}
+
+func (f fakeGroups) CreateGroup(_ context.Context, orgID, displayName, externalID string, roles, memberIDs []string) (*model.Group, error) {
+	for _, g := range f.d.groups {
+		if g.OrgID == orgID && strings.EqualFold(g.DisplayName, displayName) {
+			return nil, repo.ErrGroupNameTaken
+		}
+	}
+	now := time.Now()
+	g := &fakeGroup{
+		Group:   model.Group{ID: f.d.nextID(), OrgID: orgID, DisplayName: displayName, ExternalID: externalID, Roles: roles, CreatedAt: now, UpdatedAt: now},
+		members: memberIDs,
+	}
+	f.d.groups[g.ID] = g
+	return &g.Group, nil
+}
+
+func (f fakeGroups) GetGroup(_ context.Context, orgID, id string) (*model.Group, error) {
+	g, ok := f.d.groups[id]
+	if !ok || g.OrgID != orgID {
+		return nil, sql.ErrNoRows
+	}
+	out := g.Group
+	return &out, nil
+}
+
+func (f fakeGroups) QueryGroups(_ context.Context, q entity.ListQuery) ([]*model.Group, int, error) {
+	var matched []*model.Group
+	for _, g := range f.d.groups {
+		if g.OrgID == q.OrgID {
+			out := g.Group
+			matched = append(matched, &out)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *model.Group) int { return strings.Compare(a.ID, b.ID) })
+	total := len(matched)
+	start := min(q.Offset, total)
+	return matched[start:min(start+q.Limit, total)], total, nil
+}
+
+func (f fakeGroups) UpdateGroup(_ context.Context, orgID, id, displayName, externalID string, memberIDs []string) (*model.Group, error) {
+	g, ok := f.d.groups[id]
+	if !ok || g.OrgID != orgID {
+		return nil, repo.ErrNotFound
+	}
+	g.DisplayName, g.ExternalID, g.members = displayName, externalID, memberIDs
+	g.UpdatedAt = time.Now()
+	out := g.Group
+	return &out, nil
+}
+
+func (f fakeGroups) DeleteGroup(_ context.Context, orgID, id string) error {
+	g, ok := f.d.groups[id]
+	if !ok || g.OrgID != orgID {
+		return repo.ErrNotFound
+	}
+	delete(f.d.groups, id)
+	return nil
+}
+
+func (f fakeGroups) ListGroupMembers(_ context.Context, orgID string, groupIDs []string) ([]*model.GroupMember, error) {
+	var out []*model.GroupMember
+	for _, id := range groupIDs {
+		g, ok := f.d.groups[id]
+		if !ok || g.OrgID != orgID {
+			continue
+		}
+		for _, userID := range g.members {
+			if u, ok := f.d.users[userID]; ok {
+				out = append(out, &model.GroupMember{GroupID: g.ID, DisplayName: g.DisplayName, UserID: u.ID, Username: u.Username})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f fakeGroups) ListUserGroups(_ context.Context, orgID string, userIDs []string) ([]*model.GroupMember, error) {
+	var out []*model.GroupMember
+	for _, g := range f.d.groups {
+		if g.OrgID != orgID {
+			continue
+		}
+		for _, userID := range g.members {
+			if slices.Contains(userIDs, userID) {
+				out = append(out, &model.GroupMember{GroupID: g.ID, DisplayName: g.DisplayName, UserID: userID})
+			}
+		}
+	}
+	return out, nil
+}
+
+type fakeOrgs struct {
+	entity.OrganizationRepository
+	d *directory
+}
+
+func (f fakeOrgs) RemoveMember(_ context.Context, orgID, userID string) error {
+	if !f.d.members[orgID][userID] {
+		return repo.ErrNotFound
+	}
+	delete(f.d.members[orgID], userID)
+	for _, g := range f.d.groups {
+		if g.OrgID == orgID {
+			g.members = slices.DeleteFunc(g.members, func(id string) bool { return id == userID })
+		}
+	}
+	return nil
+}
+
+func (f fakeOrgs) ListUserMemberships(_ context.Context, userID string) ([]*model.OrganizationMember, error) {
+	var out []*model.OrganizationMember
+	for orgID, users := range f.d.members {
+		if users[userID] {
+			out = append(out, &model.OrganizationMember{OrgID: orgID, UserID: userID})
+		}
+	}
+	return out, nil
+}
+
+type fakeClients struct {
+	entity.ScimClientRepository
+	d *directory
+}
+
+func (f fakeClients) CreateScimClient(_ context.Context, c *model.ScimClient) (*model.ScimClient, error) {
+	c.ID = f.d.nextID()
+	c.CreatedAt = time.Now()
+	f.d.clients[c.TokenHash] = c
+	return c, nil
+}
+
+func (f fakeClients) GetScimClientByHash(_ context.Context, hash string) (*model.ScimClient, error) {
+	c, ok := f.d.clients[hash]
+	if !ok || c.RevokedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f fakeClients) RevokeScimClient(_ context.Context, orgID, id string) error {
+	for _, c := range f.d.clients {
+		if c.ID == id && c.OrgID == orgID && c.RevokedAt == nil {
+			now := time.Now()
+			c.RevokedAt = &now
+			return nil
+		}
+	}
+	return repo.ErrNotFound
+}
+
+func (f fakeClients) TouchScimClient(_ context.Context, id string) error {
+	f.d.touchedCount++
+	for _, c := range f.d.clients {
+		if c.ID == id {
+			now := time.Now()
+			c.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+type fakeRevoker struct{ d *directory }
+
+func (f fakeRevoker) RevokeAllTokens(_ context.Context, userID string) error {
+	f.d.revoked = append(f.d.revoked, userID)
+	return nil
+}
+
+func (f fakeRevoker) UserDisabled(_ context.Context, userID string) error {
+	f.d.disabled = append(f.d.disabled, userID)
+	return nil
+}
+
+type fakeRoles struct{ d *directory }
+
+func (f fakeRoles) InvalidateGroupRoles(context.Context, string) {
+	f.d.invalidated++
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+// applyPatch applies a PatchOp request to the JSON form of current and
+// decodes the result into out. Working on the JSON document keeps paths
+// such as emails[type eq "work"].value generic across resource types.
+func applyPatch(current interface{}, req *PatchRequest, out interface{}) error {
+	if len(req.Operations) == 0 {
+		return badRequest("invalidSyntax", "no operations")
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	for _, op := range req.Operations {
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return badRequest("invalidSyntax", "unsupported op "+op.Op)
+		}
+		if op.Path == "" {
+			if kind == "remove" {
+				return badRequest("noTarget", "remove requires a path")
+			}
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return badRequest("invalidValue", "value must be an object when path is omitted")
+			}
+			for key, value := range values {
+				if err := applyPath(doc, kind, key, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := applyPath(doc, kind, op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+
+	normalizeBool(doc, "active")
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return badRequest("invalidValue", err.Error())
+	}
+	return nil
+}
+
+func applyPath(doc map[string]interface{}, op, rawPath string, value interface{}) error {
+	path, err := scim.ParsePath(rawPath)
+	if err != nil {
+		return badRequest("invalidPath", err.Error())
+	}
+	key := findKey(doc, path.Attr)
+
+	if path.Filter == nil {
+		if path.Sub != "" {
+			parent, ok := doc[key].(map[string]interface{})
+			if !ok {
+				if op == "remove" {
+					return nil
+				}
+				parent = map[string]interface{}{}
+				doc[key] = parent
+			}
+			subKey := findKey(parent, path.Sub)
+			if op == "remove" {
+				delete(parent, subKey)
+			} else {
+				parent[subKey] = value
+			}
+			return nil
+		}
+
+		existing, isList := doc[key].([]interface{})
+		switch op {
+		case "remove":
+			// Azure AD removes members by sending their values.
+			if isList && value != nil {
+				doc[key] = removeValues(existing, asList(value))
+			} else {
+				delete(doc, key)
+			}
+		case "add":
+			if isList {
+				doc[key] = appendValues(existing, asList(value))
+			} else {
+				doc[key] = value
+			}
+		default:
+			doc[key] = value
+		}
+		return nil
+	}
+
+	list, _ := doc[key].([]interface{})
+	matched := false
+	var kept []interface{}
+	for _, item := range list {
+		elem, ok := item.(map[string]interface{})
+		if !ok || !matches(elem, path.Filter) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && path.Sub == "":
+			continue
+		case op == "remove":
+			delete(elem, findKey(elem, path.Sub))
+		case path.Sub != "":
+			elem[findKey(elem, path.Sub)] = value
+		default:
+			if v, ok := value.(map[string]interface{}); ok {
+				for k, val := range v {
+					elem[findKey(elem, k)] = val
+				}
+			}
+		}
+		kept = append(kept, elem)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return nil
+		}
+		// add/replace on a missing entry creates it from the filter, e.g.
+		// emails[type eq "work"].value
+		cmp, ok := path.Filter.(*scim.Compare)
+		if !ok || cmp.Op != "eq" || path.Sub == "" {
+			return badRequest("noTarget", "no value matches "+rawPath)
+		}
+		kept = append(kept, map[string]interface{}{cmp.Attr: cmp.Value, path.Sub: value})
+	}
+	doc[key] = kept
+	return nil
+}
+
+// findKey returns the existing key matching name case-insensitively, or
+// name itself.
+func findKey(m map[string]interface{}, name string) string {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+func asList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+func itemValue(item interface{}) string {
+	if m, ok := item.(map[string]interface{}); ok {
+		return fmt.Sprint(m[findKey(m, "value")])
+	}
+	return fmt.Sprint(item)
+}
+
+func appendValues(list, values []interface{}) []interface{} {
+	for _, v := range values {
+		dup := false
+		for _, existing := range list {
+			if itemValue(existing) == itemValue(v) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func removeValues(list, values []interface{}) []interface{} {
+	var kept []interface{}
+	for _, item := range list {
+		drop := false
+		for _, v := range values {
+			if itemValue(item) == itemValue(v) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// matches evaluates a value filter against one element of a multi-valued
+// attribute. String comparisons are case-insensitive.
+func matches(elem map[string]interface{}, f scim.Filter) bool {
+	switch f := f.(type) {
+	case *scim.Logical:
+		if f.Op == "and" {
+			return matches(elem, f.Left) && matches(elem, f.Right)
+		}
+		return matches(elem, f.Left) || matches(elem, f.Right)
+	case *scim.Not:
+		return !matches(elem, f.Filter)
+	case *scim.Present:
+		v, ok := elem[findKey(elem, f.Attr)]
+		return ok && v != nil && v != ""
+	case *scim.Compare:
+		v, ok := elem[findKey(elem, f.Attr)]
+		if !ok {
+			// a missing attribute only satisfies ne
+			return f.Op == "ne"
+		}
+		a, b := strings.ToLower(fmt.Sprint(v)), strings.ToLower(fmt.Sprint(f.Value))
+		switch f.Op {
+		case "eq":
+			return a == b
+		case "ne":
+			return a != b
+		case "co":
+			return strings.Contains(a, b)
+		case "sw":
+			return strings.HasPrefix(a, b)
+		case "ew":
+			return strings.HasSuffix(a, b)
+		}
+	}
+	return false
+}
+
+// normalizeBool accepts "True"/"False" strings, which some identity
+// providers send for boolean attributes.
+func normalizeBool(doc map[string]interface{}, name string) {
+	key := findKey(doc, name)
+	if s, ok := doc[key].(string); ok {
+		if b, err := strconv.ParseBool(s); err == nil {
+			doc[key] = b
+		}
+	}
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func testUser() *User {
+	return &User{
+		Schemas:  []string{SchemaUser},
+		ID:       "2819c223-7f76-453a-919d-413861904646",
+		UserName: "bjensen",
+		Name:     &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+		Active:   boolPtr(true),
+		Emails: []MultiValue{
+			{Value: "bjensen@example.com", Type: "work", Primary: true},
+			{Value: "babs@home.example", Type: "home"},
+		},
+		Roles: []MultiValue{{Value: "editor"}, {Value: "viewer"}},
+	}
+}
+
+// ops decodes a PatchOp request body the way the HTTP handler does.
+func ops(t *testing.T, body string) *PatchRequest {
+	t.Helper()
+	req := &PatchRequest{}
+	if err := json.Unmarshal([]byte(body), req); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return req
+}
+
+func TestApplyPatchUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, u *User)
+	}{
+		{
+			name: "replace simple attribute",
+			body: `{"Operations":[{"op":"replace","path":"userName","value":"barbara"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.UserName != "barbara" {
+					t.Errorf("userName = %q", u.UserName)
+				}
+			},
+		},
+		{
+			name: "op names are case-insensitive",
+			body: `{"Operations":[{"op":"Replace","path":"externalId","value":"ext-1"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.ExternalID != "ext-1" {
+					t.Errorf("externalId = %q", u.ExternalID)
+				}
+			},
+		},
+		{
+			name: "replace without path takes an object",
+			body: `{"Operations":[{"op":"replace","value":{"active":false,"externalId":"ext-2"}}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Active == nil || *u.Active || u.ExternalID != "ext-2" {
+					t.Errorf("active = %v, externalId = %q", u.Active, u.ExternalID)
+				}
+			},
+		},
+		{
+			name: "boolean sent as string",
+			body: `{"Operations":[{"op":"replace","path":"active","value":"False"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Active == nil || *u.Active {
+					t.Errorf("active = %v, want false", u.Active)
+				}
+			},
+		},
+		{
+			name: "replace sub-attribute",
+			body: `{"Operations":[{"op":"replace","path":"name.givenName","value":"Babs"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Name.GivenName != "Babs" || u.Name.FamilyName != "Jensen" {
+					t.Errorf("name = %+v", u.Name)
+				}
+			},
+		},
+		{
+			name: "schema URN prefix",
+			body: `{"Operations":[{"op":"replace","path":"urn:ietf:params:scim:schemas:core:2.0:User:name.familyName","value":"Smith"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Name.FamilyName != "Smith" {
+					t.Errorf("familyName = %q", u.Name.FamilyName)
+				}
+			},
+		},
+		{
+			name: "remove sub-attribute",
+			body: `{"Operations":[{"op":"remove","path":"name.familyName"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Name.FamilyName != "" || u.Name.GivenName != "Barbara" {
+					t.Errorf("name = %+v", u.Name)
+				}
+			},
+		},
+		{
+			name: "add to a multi-valued attribute appends and skips duplicates",
+			body: `{"Operations":[{"op":"add","path":"roles","value":[{"value":"admin"},{"value":"editor"}]}]}`,
+			check: func(t *testing.T, u *User) {
+				if got := values(u.Roles); !reflect.DeepEqual(got, []string{"editor", "viewer", "admin"}) {
+					t.Errorf("roles = %v", got)
+				}
+			},
+		},
+		{
+			name: "remove by value (Azure AD style)",
+			body: `{"Operations":[{"op":"remove","path":"roles","value":[{"value":"viewer"}]}]}`,
+			check: func(t *testing.T, u *User) {
+				if got := values(u.Roles); !reflect.DeepEqual(got, []string{"editor"}) {
+					t.Errorf("roles = %v", got)
+				}
+			},
+		},
+		{
+			name: "remove whole attribute",
+			body: `{"Operations":[{"op":"remove","path":"roles"}]}`,
+			check: func(t *testing.T, u *User) {
+				if len(u.Roles) != 0 {
+					t.Errorf("roles = %v", u.Roles)
+				}
+			},
+		},
+		{
+			name: "replace through a valuePath filter",
+			body: `{"Operations":[{"op":"replace","path":"emails[type eq \"work\"].value","value":"barbara@example.com"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Emails[0].Value != "barbara@example.com" || u.Emails[1].Value != "babs@home.example" {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "valuePath filters compare case-insensitively",
+			body: `{"Operations":[{"op":"replace","path":"emails[type eq \"WORK\"].value","value":"barbara@example.com"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Emails[0].Value != "barbara@example.com" {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "add through a valuePath filter creates the entry",
+			body: `{"Operations":[{"op":"add","path":"emails[type eq \"other\"].value","value":"b@other.example"}]}`,
+			check: func(t *testing.T, u *User) {
+				if len(u.Emails) != 3 || u.Emails[2].Type != "other" || u.Emails[2].Value != "b@other.example" {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "replace a whole element through a valuePath filter",
+			body: `{"Operations":[{"op":"replace","path":"emails[type eq \"home\"]","value":{"value":"new@home.example","primary":true}}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Emails[1].Value != "new@home.example" || !u.Emails[1].Primary {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "remove elements matching a valuePath filter",
+			body: `{"Operations":[{"op":"remove","path":"emails[type eq \"home\" or value ew \"@nowhere\"]"}]}`,
+			check: func(t *testing.T, u *User) {
+				if len(u.Emails) != 1 || u.Emails[0].Type != "work" {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "remove a sub-attribute of matching elements",
+			body: `{"Operations":[{"op":"remove","path":"emails[primary eq true].type"}]}`,
+			check: func(t *testing.T, u *User) {
+				if u.Emails[0].Type != "" || u.Emails[1].Type != "home" {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "remove with an unmatched valuePath is a no-op",
+			body: `{"Operations":[{"op":"remove","path":"emails[type eq \"fax\"]"}]}`,
+			check: func(t *testing.T, u *User) {
+				if len(u.Emails) != 2 {
+					t.Errorf("emails = %+v", u.Emails)
+				}
+			},
+		},
+		{
+			name: "operations apply in order",
+			body: `{"Operations":[
+				{"op":"add","path":"roles","value":[{"value":"admin"}]},
+				{"op":"remove","path":"roles[value eq \"editor\"]"},
+				{"op":"replace","path":"displayName","value":"Babs"}
+			]}`,
+			check: func(t *testing.T, u *User) {
+				if got := values(u.Roles); !reflect.DeepEqual(got, []string{"viewer", "admin"}) || u.DisplayName != "Babs" {
+					t.Errorf("roles = %v, displayName = %q", got, u.DisplayName)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &User{}
+			if err := applyPatch(testUser(), ops(t, tt.body), out); err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			tt.check(t, out)
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		scimType string
+	}{
+		{"no operations", `{"Operations":[]}`, "invalidSyntax"},
+		{"unknown op", `{"Operations":[{"op":"move","path":"userName","value":"x"}]}`, "invalidSyntax"},
+		{"remove without path", `{"Operations":[{"op":"remove"}]}`, "noTarget"},
+		{"pathless value must be an object", `{"Operations":[{"op":"replace","value":"x"}]}`, "invalidValue"},
+		{"malformed path", `{"Operations":[{"op":"replace","path":"emails[type eq","value":"x"}]}`, "invalidPath"},
+		{"replace with no match and no eq filter", `{"Operations":[{"op":"replace","path":"emails[type co \"x\"].value","value":"x"}]}`, "noTarget"},
+		{"value of the wrong type", `{"Operations":[{"op":"replace","path":"emails","value":"not-a-list"}]}`, "invalidValue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyPatch(testUser(), ops(t, tt.body), &User{})
+			var scimErr *Error
+			if !errors.As(err, &scimErr) || scimErr.ScimType != tt.scimType || scimErr.Status != 400 {
+				t.Fatalf("applyPatch error = %#v, want 400 %s", err, tt.scimType)
+			}
+		})
+	}
+}
+
+func TestApplyPatchGroupMembers(t *testing.T) {
+	group := &Group{
+		Schemas:     []string{SchemaGroup},
+		DisplayName: "Engineering",
+		Members:     []MultiValue{{Value: "u1"}, {Value: "u2"}},
+	}
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"add members", `{"Operations":[{"op":"add","path":"members","value":[{"value":"u3"},{"value":"u1"}]}]}`, []string{"u1", "u2", "u3"}},
+		{"remove member by valuePath", `{"Operations":[{"op":"remove","path":"members[value eq \"u1\"]"}]}`, []string{"u2"}},
+		{"remove members by value", `{"Operations":[{"op":"remove","path":"members","value":[{"value":"u2"}]}]}`, []string{"u1"}},
+		{"replace members", `{"Operations":[{"op":"replace","path":"members","value":[{"value":"u9"}]}]}`, []string{"u9"}},
+		{"remove all members", `{"Operations":[{"op":"remove","path":"members"}]}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &Group{}
+			if err := applyPatch(group, ops(t, tt.body), out); err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if got := values(out.Members); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("members = %v, want %v", got, tt.want)
+			}
+			if out.DisplayName != "Engineering" {
+				t.Fatalf("displayName = %q", out.DisplayName)
+			}
+		})
+	}
+}
+
+func values(mv []MultiValue) []string {
+	var out []string
+	for _, v := range mv {
+		out = append(out, v.Value)
+	}
+	return out
+}
@@ -0,0 +1,590 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package provisioning implements SCIM 2.0 user and group provisioning on
+// top of the user and group repositories.
+package provisioning
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+const (
+	// TokenPrefix marks SCIM client tokens.
+	TokenPrefix = "sdscim_"
+
+	DefaultCount = 100
+	MaxCount     = 200
+
+	clientTouchInterval = time.Minute
+)
+
+// TokenRevoker ends the sessions of a deprovisioned user. UserDisabled
+// also announces the deactivation.
+type TokenRevoker interface {
+	RevokeAllTokens(ctx context.Context, userID string) error
+	UserDisabled(ctx context.Context, userID string) error
+}
+
+// RoleInvalidator drops cached group roles once group membership changed.
+type RoleInvalidator interface {
+	InvalidateGroupRoles(ctx context.Context, orgID string)
+}
+
+type Service struct {
+	users   entity.UserRepository
+	groups  entity.GroupRepository
+	clients entity.ScimClientRepository
+	orgs    entity.OrganizationRepository
+	revoker TokenRevoker
+	roles   RoleInvalidator
+	baseURL string
+}
+
+// NewService creates the service. baseURL is the public URL of the SCIM
+// root, e.g. https://auth.example.com/scim/v2.
+func NewService(users entity.UserRepository, groups entity.GroupRepository, clients entity.ScimClientRepository, orgs entity.OrganizationRepository, revoker TokenRevoker, roles RoleInvalidator, baseURL string) *Service {
+	return &Service{
+		users:   users,
+		groups:  groups,
+		clients: clients,
+		orgs:    orgs,
+		revoker: revoker,
+		roles:   roles,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// CreateClient registers a provisioning client and returns its bearer
+// token, which is shown once.
+func (s *Service) CreateClient(ctx context.Context, orgID, name, createdBy string) (*model.ScimClient, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	raw := TokenPrefix + hex.EncodeToString(b)
+
+	c, err := s.clients.CreateScimClient(ctx, &model.ScimClient{
+		OrgID:     orgID,
+		Name:      name,
+		Prefix:    raw[:len(TokenPrefix)+8],
+		TokenHash: hashToken(raw),
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	service.AuditTarget(ctx, c.ID)
+	service.AuditDetail(ctx, "name", c.Name)
+	return c, raw, nil
+}
+
+func (s *Service) ListClients(ctx context.Context, orgID string) ([]*model.ScimClient, error) {
+	return s.clients.ListScimClients(ctx, orgID)
+}
+
+func (s *Service) RevokeClient(ctx context.Context, orgID, id string) error {
+	if err := s.clients.RevokeScimClient(ctx, orgID, id); err != nil {
+		return err
+	}
+	service.AuditTarget(ctx, id)
+	return nil
+}
+
+// Authenticate resolves a bearer token to its provisioning client.
+func (s *Service) Authenticate(ctx context.Context, raw string) (*model.ScimClient, error) {
+	if !strings.HasPrefix(raw, TokenPrefix) {
+		return nil, service.ErrInvalidToken
+	}
+	c, err := s.clients.GetScimClientByHash(ctx, hashToken(raw))
+	if err != nil {
+		return nil, service.ErrInvalidToken
+	}
+	if c.LastUsedAt == nil || time.Since(*c.LastUsedAt) > clientTouchInterval {
+		if err := s.clients.TouchScimClient(ctx, c.ID); err != nil {
+			log.Printf("touch scim client %s: %v", c.ID, err)
+		}
+	}
+	return c, nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// listQuery converts SCIM paging parameters (1-based startIndex) into a
+// repository query.
+func listQuery(filter string, startIndex, count int) (entity.ListQuery, error) {
+	q := entity.ListQuery{Offset: max(startIndex, 1) - 1, Limit: min(max(count, 0), MaxCount)}
+	if count < 0 {
+		q.Limit = DefaultCount
+	}
+	if filter = strings.TrimSpace(filter); filter != "" {
+		f, err := scim.ParseFilter(filter)
+		if err != nil {
+			return q, err
+		}
+		q.Filter = f
+	}
+	return q, nil
+}
+
+func newListResponse(total, startIndex int, resources []interface{}) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   max(startIndex, 1),
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// ---- Users ----
+
+// ListUsers returns a page of users. A negative count means the default.
+func (s *Service) ListUsers(ctx context.Context, orgID, filter string, startIndex, count int) (*ListResponse, error) {
+	q, err := listQuery(filter, startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+	q.OrgID = orgID
+	users, total, err := s.users.QueryUsers(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := s.userResources(ctx, orgID, users)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, len(resources))
+	for i, r := range resources {
+		out[i] = r
+	}
+	return newListResponse(total, startIndex, out), nil
+}
+
+func (s *Service) GetUser(ctx context.Context, orgID, id string) (*User, error) {
+	u, err := s.loadUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := s.userResources(ctx, orgID, []entity.UserEntity{u})
+	if err != nil {
+		return nil, err
+	}
+	return resources[0], nil
+}
+
+func (s *Service) CreateUser(ctx context.Context, orgID string, in *User) (*User, error) {
+	p, err := provisionedUser(in)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.CreateProvisionedUser(ctx, orgID, p)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, u.GetID())
+	service.AuditDetail(ctx, "username", u.GetUsername())
+	return s.GetUser(ctx, orgID, u.GetID())
+}
+
+// ReplaceUser implements PUT: every writable attribute is taken from in.
+func (s *Service) ReplaceUser(ctx context.Context, orgID, id string, in *User) (*User, error) {
+	current, err := s.loadUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	p, err := provisionedUser(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, orgID, current, p)
+}
+
+func (s *Service) PatchUser(ctx context.Context, orgID, id string, req *PatchRequest) (*User, error) {
+	current, err := s.GetUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	patched := &User{}
+	if err := applyPatch(current, req, patched); err != nil {
+		return nil, err
+	}
+	p, err := provisionedUser(patched)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.loadUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, orgID, u, p)
+}
+
+func (s *Service) saveUser(ctx context.Context, orgID string, current entity.UserEntity, p entity.ProvisionedUser) (*User, error) {
+	service.AuditTarget(ctx, current.GetID())
+	u, err := s.users.UpdateProvisionedUser(ctx, orgID, current.GetID(), p)
+	if err != nil {
+		return nil, err
+	}
+	if current.GetStatus() != entity.UserStatusDisabled && u.GetStatus() == entity.UserStatusDisabled {
+		service.AuditDetail(ctx, "status", entity.UserStatusDisabled)
+		if err := s.revoker.UserDisabled(ctx, u.GetID()); err != nil {
+			log.Printf("revoke tokens of deactivated user %s: %v", u.GetID(), err)
+		}
+	}
+	if added := newRoles(current.GetRoles(), u.GetRoles()); len(added) > 0 {
+		service.AuditDetail(ctx, "roles_added", strings.Join(added, ","))
+	}
+	return s.GetUser(ctx, orgID, u.GetID())
+}
+
+// DeleteUser removes the user from the organization and ends their
+// sessions. A user left without any organization is soft-deleted for
+// immediate purge.
+func (s *Service) DeleteUser(ctx context.Context, orgID, id string) error {
+	service.AuditTarget(ctx, id)
+	if _, err := s.loadUser(ctx, orgID, id); err != nil {
+		return err
+	}
+	if err := s.orgs.RemoveMember(ctx, orgID, id); err != nil {
+		return err
+	}
+	remaining, err := s.orgs.ListUserMemberships(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		if err := s.users.SoftDeleteUser(ctx, id, time.Now()); err != nil {
+			return err
+		}
+	}
+	s.roles.InvalidateGroupRoles(ctx, orgID)
+	if err := s.revoker.RevokeAllTokens(ctx, id); err != nil {
+		log.Printf("revoke tokens of deleted user %s: %v", id, err)
+	}
+	return nil
+}
+
+func (s *Service) loadUser(ctx context.Context, orgID, id string) (entity.UserEntity, error) {
+	if !isUUID(id) {
+		return nil, notFound("user " + id + " not found")
+	}
+	// id 已通过 UUID 校验，拼进过滤表达式是安全的
+	f, _ := scim.ParseFilter(`id eq "` + id + `"`)
+	users, _, err := s.users.QueryUsers(ctx, entity.ListQuery{OrgID: orgID, Filter: f, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, notFound("user " + id + " not found")
+	}
+	return users[0], nil
+}
+
+func (s *Service) userResources(ctx context.Context, orgID string, users []entity.UserEntity) ([]*User, error) {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.GetID()
+	}
+	groupsByUser := map[string][]MultiValue{}
+	if len(ids) > 0 {
+		memberships, err := s.groups.ListUserGroups(ctx, orgID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range memberships {
+			groupsByUser[m.UserID] = append(groupsByUser[m.UserID], MultiValue{
+				Value:   m.GroupID,
+				Display: m.DisplayName,
+				Type:    "direct",
+				Ref:     s.baseURL + "/Groups/" + m.GroupID,
+			})
+		}
+	}
+
+	out := make([]*User, len(users))
+	for i, u := range users {
+		out[i] = s.userResource(u, groupsByUser[u.GetID()])
+	}
+	return out, nil
+}
+
+func (s *Service) userResource(u entity.UserEntity, groups []MultiValue) *User {
+	active := u.GetStatus() == entity.UserStatusActive
+	r := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          u.GetID(),
+		ExternalID:  u.GetExternalID(),
+		UserName:    u.GetUsername(),
+		DisplayName: u.GetUsername(),
+		Active:      &active,
+		Emails:      []MultiValue{{Value: u.GetEmail(), Type: "work", Primary: true}},
+		Groups:      groups,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      u.GetCreatedAt(),
+			LastModified: u.GetUpdatedAt(),
+			Location:     s.baseURL + "/Users/" + u.GetID(),
+		},
+	}
+	if given, family := u.GetGivenName(), u.GetFamilyName(); given != "" || family != "" {
+		r.Name = &Name{
+			Formatted:  strings.TrimSpace(given + " " + family),
+			GivenName:  given,
+			FamilyName: family,
+		}
+		r.DisplayName = r.Name.Formatted
+	}
+	for _, role := range u.GetRoles() {
+		r.Roles = append(r.Roles, MultiValue{Value: role})
+	}
+	return r
+}
+
+// provisionedUser validates a User resource and extracts the attributes
+// that are stored.
+func provisionedUser(in *User) (entity.ProvisionedUser, error) {
+	p := entity.ProvisionedUser{
+		Username:   strings.ToLower(strings.TrimSpace(in.UserName)),
+		ExternalID: strings.TrimSpace(in.ExternalID),
+		Active:     in.Active == nil || *in.Active,
+	}
+	if p.Username == "" {
+		return p, badRequest("invalidValue", "userName is required")
+	}
+	if in.Name != nil {
+		p.GivenName = strings.TrimSpace(in.Name.GivenName)
+		p.FamilyName = strings.TrimSpace(in.Name.FamilyName)
+	}
+
+	for _, e := range in.Emails {
+		if e.Primary || p.Email == "" {
+			p.Email = strings.TrimSpace(e.Value)
+		}
+	}
+	if p.Email == "" && strings.Contains(p.Username, "@") {
+		p.Email = p.Username
+	}
+	if p.Email == "" {
+		return p, badRequest("invalidValue", "an email address is required")
+	}
+
+	for _, r := range in.Roles {
+		if v := strings.TrimSpace(r.Value); v != "" && !slices.Contains(p.Roles, v) {
+			p.Roles = append(p.Roles, v)
+		}
+	}
+	return p, nil
+}
+
+func newRoles(before, after []string) []string {
+	var added []string
+	for _, r := range after {
+		if !slices.Contains(before, r) {
+			added = append(added, r)
+		}
+	}
+	return added
+}
+
+// ---- Groups ----
+
+func (s *Service) ListGroups(ctx context.Context, orgID, filter string, startIndex, count int) (*ListResponse, error) {
+	q, err := listQuery(filter, startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+	q.OrgID = orgID
+	groups, total, err := s.groups.QueryGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := s.groupResources(ctx, orgID, groups)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, len(resources))
+	for i, r := range resources {
+		out[i] = r
+	}
+	return newListResponse(total, startIndex, out), nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, orgID, id string) (*Group, error) {
+	g, err := s.loadGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := s.groupResources(ctx, orgID, []*model.Group{g})
+	if err != nil {
+		return nil, err
+	}
+	return resources[0], nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, orgID string, in *Group) (*Group, error) {
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, badRequest("invalidValue", "displayName is required")
+	}
+	g, err := s.groups.CreateGroup(ctx, orgID, name, strings.TrimSpace(in.ExternalID), nil, memberIDs(in.Members))
+	if err != nil {
+		return nil, err
+	}
+	s.roles.InvalidateGroupRoles(ctx, orgID)
+	service.AuditTarget(ctx, g.ID)
+	service.AuditDetail(ctx, "display_name", g.DisplayName)
+	return s.GetGroup(ctx, orgID, g.ID)
+}
+
+func (s *Service) ReplaceGroup(ctx context.Context, orgID, id string, in *Group) (*Group, error) {
+	if _, err := s.loadGroup(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, orgID, id, in)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, orgID, id string, req *PatchRequest) (*Group, error) {
+	current, err := s.GetGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	patched := &Group{}
+	if err := applyPatch(current, req, patched); err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, orgID, id, patched)
+}
+
+func (s *Service) saveGroup(ctx context.Context, orgID, id string, in *Group) (*Group, error) {
+	service.AuditTarget(ctx, id)
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, badRequest("invalidValue", "displayName is required")
+	}
+	if _, err := s.groups.UpdateGroup(ctx, orgID, id, name, strings.TrimSpace(in.ExternalID), memberIDs(in.Members)); err != nil {
+		return nil, err
+	}
+	s.roles.InvalidateGroupRoles(ctx, orgID)
+	return s.GetGroup(ctx, orgID, id)
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, orgID, id string) error {
+	service.AuditTarget(ctx, id)
+	if !isUUID(id) {
+		return notFound("group " + id + " not found")
+	}
+	if err := s.groups.DeleteGroup(ctx, orgID, id); err != nil {
+		return err
+	}
+	s.roles.InvalidateGroupRoles(ctx, orgID)
+	return nil
+}
+
+func (s *Service) loadGroup(ctx context.Context, orgID, id string) (*model.Group, error) {
+	if !isUUID(id) {
+		return nil, notFound("group " + id + " not found")
+	}
+	return s.groups.GetGroup(ctx, orgID, id)
+}
+
+func (s *Service) groupResources(ctx context.Context, orgID string, groups []*model.Group) ([]*Group, error) {
+	ids := make([]string, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+	membersByGroup := map[string][]MultiValue{}
+	if len(ids) > 0 {
+		members, err := s.groups.ListGroupMembers(ctx, orgID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			membersByGroup[m.GroupID] = append(membersByGroup[m.GroupID], MultiValue{
+				Value:   m.UserID,
+				Display: m.Username,
+				Type:    "User",
+				Ref:     s.baseURL + "/Users/" + m.UserID,
+			})
+		}
+	}
+
+	out := make([]*Group, len(groups))
+	for i, g := range groups {
+		out[i] = &Group{
+			Schemas:     []string{SchemaGroup},
+			ID:          g.ID,
+			ExternalID:  g.ExternalID,
+			DisplayName: g.DisplayName,
+			Members:     membersByGroup[g.ID],
+			Meta: &Meta{
+				ResourceType: "Group",
+				Created:      g.CreatedAt,
+				LastModified: g.UpdatedAt,
+				Location:     s.baseURL + "/Groups/" + g.ID,
+			},
+		}
+	}
+	return out, nil
+}
+
+func memberIDs(members []MultiValue) []string {
+	var ids []string
+	for _, m := range members {
+		if isUUID(m.Value) && !slices.Contains(ids, m.Value) {
+			ids = append(ids, m.Value)
+		}
+	}
+	return ids
+}
+
+// isUUID keeps malformed IDs away from PostgreSQL, which would reject the
+// cast with an error instead of returning no rows.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
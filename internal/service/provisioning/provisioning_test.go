@@ -0,0 +1,395 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provisioning
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+const testOrg = "org-1"
+
+func TestListQuery(t *testing.T) {
+	tests := []struct {
+		name                  string
+		startIndex, count     int
+		wantOffset, wantLimit int
+	}{
+		{"defaults", 0, -1, 0, DefaultCount},
+		{"first page", 1, 10, 0, 10},
+		{"startIndex is 1-based", 5, 2, 4, 2},
+		{"negative startIndex", -3, 10, 0, 10},
+		{"count is capped", 1, 500, 0, MaxCount},
+		{"count zero asks for totals only", 1, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := listQuery("", tt.startIndex, tt.count)
+			if err != nil {
+				t.Fatalf("listQuery: %v", err)
+			}
+			if q.Offset != tt.wantOffset || q.Limit != tt.wantLimit || q.Filter != nil {
+				t.Fatalf("listQuery = %+v, want offset %d limit %d", q, tt.wantOffset, tt.wantLimit)
+			}
+		})
+	}
+
+	q, err := listQuery(` userName eq "bjensen" `, 1, 10)
+	if err != nil {
+		t.Fatalf("listQuery with filter: %v", err)
+	}
+	want := &scim.Compare{Attr: "username", Op: "eq", Value: "bjensen"}
+	if !reflect.DeepEqual(q.Filter, want) {
+		t.Fatalf("filter = %#v, want %#v", q.Filter, want)
+	}
+
+	if _, err := listQuery(`userName xx "bjensen"`, 1, 10); !errors.Is(err, scim.ErrInvalidFilter) {
+		t.Fatalf("bad filter error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func newUser(name string) *User {
+	return &User{
+		Schemas:  []string{SchemaUser},
+		UserName: name,
+		Emails:   []MultiValue{{Value: name + "@example.com", Primary: true}},
+	}
+}
+
+func TestListUsersPagination(t *testing.T) {
+	d := newDirectory()
+	s := d.service()
+	ctx := context.Background()
+	var ids []string
+	for i := range 5 {
+		u, err := s.CreateUser(ctx, testOrg, newUser(fmt.Sprintf("user%d", i)))
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		ids = append(ids, u.ID)
+	}
+	// a user of another organization must not show up
+	if _, err := s.CreateUser(ctx, "org-2", newUser("outsider")); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		filter            string
+		startIndex, count int
+		wantIDs           []string
+		wantTotal         int
+		wantStartIndex    int
+	}{
+		{"all", "", 0, -1, ids, 5, 1},
+		{"first page", "", 1, 2, ids[:2], 5, 1},
+		{"second page", "", 3, 2, ids[2:4], 5, 3},
+		{"past the end", "", 10, 2, nil, 5, 10},
+		{"count zero", "", 1, 0, nil, 5, 1},
+		{"filter", `userName eq "USER3"`, 1, 10, ids[3:4], 1, 1},
+		{"filter with or", `userName eq "user0" or userName eq "user4"`, 1, 10, []string{ids[0], ids[4]}, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.ListUsers(ctx, testOrg, tt.filter, tt.startIndex, tt.count)
+			if err != nil {
+				t.Fatalf("ListUsers: %v", err)
+			}
+			var got []string
+			for _, r := range resp.Resources {
+				got = append(got, r.(*User).ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", got, tt.wantIDs)
+			}
+			if resp.TotalResults != tt.wantTotal || resp.StartIndex != tt.wantStartIndex || resp.ItemsPerPage != len(tt.wantIDs) {
+				t.Errorf("totalResults %d startIndex %d itemsPerPage %d, want %d %d %d",
+					resp.TotalResults, resp.StartIndex, resp.ItemsPerPage, tt.wantTotal, tt.wantStartIndex, len(tt.wantIDs))
+			}
+			if !slices.Equal(resp.Schemas, []string{SchemaListResponse}) {
+				t.Errorf("schemas = %v", resp.Schemas)
+			}
+		})
+	}
+
+	if _, err := s.ListUsers(ctx, testOrg, `userName eq`, 1, 10); !errors.Is(err, scim.ErrInvalidFilter) {
+		t.Fatalf("bad filter error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	d := newDirectory()
+	s := d.service()
+	ctx := context.Background()
+
+	c, raw, err := s.CreateClient(ctx, testOrg, " okta ", "admin-1")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	if !strings.HasPrefix(raw, TokenPrefix) || c.Name != "okta" || !strings.HasPrefix(raw, c.Prefix) {
+		t.Fatalf("client %+v, token %q", c, raw)
+	}
+	if c.TokenHash == raw || strings.Contains(c.TokenHash, raw[len(TokenPrefix):]) {
+		t.Fatal("raw token stored")
+	}
+	if _, _, err := s.CreateClient(ctx, testOrg, "  ", "admin-1"); err == nil {
+		t.Fatal("CreateClient accepted an empty name")
+	}
+
+	got, err := s.Authenticate(ctx, raw)
+	if err != nil || got.ID != c.ID || got.OrgID != testOrg {
+		t.Fatalf("Authenticate = %+v, %v", got, err)
+	}
+	// LastUsedAt is only written once per clientTouchInterval
+	if _, err := s.Authenticate(ctx, raw); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if d.touchedCount != 1 {
+		t.Fatalf("touched %d times, want 1", d.touchedCount)
+	}
+
+	for name, token := range map[string]string{
+		"empty":        "",
+		"wrong prefix": "sdpat_" + raw[len(TokenPrefix):],
+		"unknown":      raw + "0",
+	} {
+		if _, err := s.Authenticate(ctx, token); !errors.Is(err, service.ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	if err := s.RevokeClient(ctx, testOrg, c.ID); err != nil {
+		t.Fatalf("RevokeClient: %v", err)
+	}
+	if _, err := s.Authenticate(ctx, raw); !errors.Is(err, service.ErrInvalidToken) {
+		t.Fatalf("revoked: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestUserRoundTrip(t *testing.T) {
+	d := newDirectory()
+	s := d.service()
+	ctx := context.Background()
+
+	created, err := s.CreateUser(ctx, testOrg, &User{
+		Schemas:    []string{SchemaUser},
+		UserName:   "BJensen",
+		ExternalID: "okta-1",
+		Name:       &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+		Emails: []MultiValue{
+			{Value: "babs@home.example", Type: "home"},
+			{Value: "bjensen@example.com", Type: "work", Primary: true},
+		},
+		Roles: []MultiValue{{Value: "editor"}, {Value: "editor"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.UserName != "bjensen" || created.Emails[0].Value != "bjensen@example.com" ||
+		created.Active == nil || !*created.Active || created.DisplayName != "Barbara Jensen" ||
+		len(created.Roles) != 1 || created.Meta.Location != "https://auth.example.com/scim/v2/Users/"+created.ID {
+		t.Fatalf("created = %+v", created)
+	}
+	if _, err := s.CreateUser(ctx, testOrg, newUser("bjensen")); !errors.Is(err, repo.ErrUsernameTaken) {
+		t.Fatalf("duplicate CreateUser error = %v", err)
+	}
+
+	got, err := s.GetUser(ctx, testOrg, created.ID)
+	if err != nil || !reflect.DeepEqual(got, created) {
+		t.Fatalf("GetUser = %+v, %v", got, err)
+	}
+	if _, err := s.GetUser(ctx, "org-2", created.ID); !isNotFound(err) {
+		t.Fatalf("GetUser from another org error = %v, want 404", err)
+	}
+
+	replaced, err := s.ReplaceUser(ctx, testOrg, created.ID, &User{
+		Schemas:  []string{SchemaUser},
+		UserName: "bjensen",
+		Active:   new(bool),
+		Emails:   []MultiValue{{Value: "barbara@example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceUser: %v", err)
+	}
+	if *replaced.Active || replaced.Name != nil || replaced.ExternalID != "" || replaced.Emails[0].Value != "barbara@example.com" {
+		t.Fatalf("replaced = %+v", replaced)
+	}
+	if !slices.Equal(d.disabled, []string{created.ID}) {
+		t.Fatalf("disabled = %v, want the deactivated user", d.disabled)
+	}
+	if _, err := s.ReplaceUser(ctx, testOrg, created.ID, &User{UserName: "bjensen"}); err == nil {
+		t.Fatal("ReplaceUser accepted a user without an email")
+	}
+
+	patched, err := s.PatchUser(ctx, testOrg, created.ID, &PatchRequest{Operations: []PatchOp{
+		{Op: "replace", Path: "active", Value: "True"},
+		{Op: "add", Path: "name.givenName", Value: "Babs"},
+		{Op: "add", Path: "roles", Value: []interface{}{map[string]interface{}{"value": "admin"}}},
+	}})
+	if err != nil {
+		t.Fatalf("PatchUser: %v", err)
+	}
+	if !*patched.Active || patched.Name.GivenName != "Babs" || len(patched.Roles) != 1 || patched.Roles[0].Value != "admin" {
+		t.Fatalf("patched = %+v", patched)
+	}
+	if len(d.disabled) != 1 {
+		t.Fatalf("reactivation announced as deactivation: %v", d.disabled)
+	}
+
+	if err := s.DeleteUser(ctx, testOrg, created.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if !slices.Equal(d.softDeleted, []string{created.ID}) || !slices.Equal(d.revoked, []string{created.ID}) {
+		t.Fatalf("softDeleted = %v, revoked = %v", d.softDeleted, d.revoked)
+	}
+	if _, err := s.GetUser(ctx, testOrg, created.ID); !isNotFound(err) {
+		t.Fatalf("GetUser after delete error = %v, want 404", err)
+	}
+	if err := s.DeleteUser(ctx, testOrg, created.ID); !isNotFound(err) {
+		t.Fatalf("second DeleteUser error = %v, want 404", err)
+	}
+}
+
+func TestDeleteUserKeepsOtherMemberships(t *testing.T) {
+	d := newDirectory()
+	s := d.service()
+	ctx := context.Background()
+
+	u, err := s.CreateUser(ctx, testOrg, newUser("shared"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	d.members["org-2"] = map[string]bool{u.ID: true}
+
+	if err := s.DeleteUser(ctx, testOrg, u.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if len(d.softDeleted) != 0 {
+		t.Fatalf("softDeleted = %v, want none while org-2 still has the user", d.softDeleted)
+	}
+	if _, err := s.GetUser(ctx, "org-2", u.ID); err != nil {
+		t.Fatalf("GetUser in org-2: %v", err)
+	}
+}
+
+func TestLoadRejectsMalformedIDs(t *testing.T) {
+	s := newDirectory().service()
+	ctx := context.Background()
+	for _, id := range []string{"", "42", `" or id pr or id eq "`, "2819c223-7f76-453a-919d-41386190464g"} {
+		if _, err := s.GetUser(ctx, testOrg, id); !isNotFound(err) {
+			t.Errorf("GetUser(%q) error = %v, want 404", id, err)
+		}
+		if _, err := s.GetGroup(ctx, testOrg, id); !isNotFound(err) {
+			t.Errorf("GetGroup(%q) error = %v, want 404", id, err)
+		}
+	}
+}
+
+func TestGroupRoundTrip(t *testing.T) {
+	d := newDirectory()
+	s := d.service()
+	ctx := context.Background()
+
+	alice, _ := s.CreateUser(ctx, testOrg, newUser("alice"))
+	bob, _ := s.CreateUser(ctx, testOrg, newUser("bob"))
+
+	created, err := s.CreateGroup(ctx, testOrg, &Group{
+		Schemas:     []string{SchemaGroup},
+		DisplayName: " Engineering ",
+		Members:     []MultiValue{{Value: alice.ID}, {Value: "not-a-uuid"}, {Value: alice.ID}},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if created.DisplayName != "Engineering" || !reflect.DeepEqual(memberValues(created), []string{alice.ID}) {
+		t.Fatalf("created = %+v", created)
+	}
+	if d.invalidated != 1 {
+		t.Fatalf("group roles invalidated %d times, want 1", d.invalidated)
+	}
+
+	u, err := s.GetUser(ctx, testOrg, alice.ID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if len(u.Groups) != 1 || u.Groups[0].Value != created.ID || u.Groups[0].Display != "Engineering" {
+		t.Fatalf("user groups = %+v", u.Groups)
+	}
+
+	replaced, err := s.ReplaceGroup(ctx, testOrg, created.ID, &Group{
+		DisplayName: "Platform",
+		Members:     []MultiValue{{Value: bob.ID}},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceGroup: %v", err)
+	}
+	if replaced.DisplayName != "Platform" || !reflect.DeepEqual(memberValues(replaced), []string{bob.ID}) {
+		t.Fatalf("replaced = %+v", replaced)
+	}
+	if _, err := s.ReplaceGroup(ctx, testOrg, created.ID, &Group{}); err == nil {
+		t.Fatal("ReplaceGroup accepted an empty displayName")
+	}
+
+	patched, err := s.PatchGroup(ctx, testOrg, created.ID, &PatchRequest{Operations: []PatchOp{
+		{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": alice.ID}}},
+		{Op: "remove", Path: fmt.Sprintf("members[value eq %q]", bob.ID)},
+	}})
+	if err != nil {
+		t.Fatalf("PatchGroup: %v", err)
+	}
+	if !reflect.DeepEqual(memberValues(patched), []string{alice.ID}) {
+		t.Fatalf("patched members = %v", memberValues(patched))
+	}
+
+	list, err := s.ListGroups(ctx, testOrg, "", 1, 10)
+	if err != nil || list.TotalResults != 1 {
+		t.Fatalf("ListGroups = %+v, %v", list, err)
+	}
+
+	if err := s.DeleteGroup(ctx, testOrg, created.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if _, err := s.GetGroup(ctx, testOrg, created.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetGroup after delete error = %v, want sql.ErrNoRows", err)
+	}
+	if err := s.DeleteGroup(ctx, testOrg, "not-a-uuid"); !isNotFound(err) {
+		t.Fatalf("DeleteGroup with a malformed id error = %v, want 404", err)
+	}
+}
+
+func memberValues(g *Group) []string {
+	var out []string
+	for _, m := range g.Members {
+		out = append(out, m.Value)
+	}
+	return out
+}
+
+func isNotFound(err error) bool {
+	var scimErr *Error
+	return errors.As(err, &scimErr) && scimErr.Status == 404
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package serviceaccount
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+// ClientAssertionType is the only client_assertion_type accepted for
+// private_key_jwt authentication (RFC 7523).
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// maxAssertionLifetime bounds how far in the future a client assertion may
+// expire, which also bounds how long its jti is remembered.
+const maxAssertionLifetime = 10 * time.Minute
+
+// ReplayGuard remembers client assertion IDs until they expire.
+type ReplayGuard interface {
+	MarkAssertionUsed(ctx context.Context, jti string, ttl time.Duration) (bool, error)
+}
+
+// Error is an OAuth 2.0 error response (RFC 6749 section 5.2).
+type Error struct {
+	Code        string
+	Description string
+	Status      int
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func invalidClient(desc string) *Error {
+	return &Error{Code: "invalid_client", Description: desc, Status: http.StatusUnauthorized}
+}
+
+func invalidRequest(desc string) *Error {
+	return &Error{Code: "invalid_request", Description: desc, Status: http.StatusBadRequest}
+}
+
+// TokenRequest holds the parameters of a client_credentials request after
+// the transport has decoded the form and the Authorization header.
+type TokenRequest struct {
+	GrantType           string
+	ClientID            string
+	ClientSecret        string
+	ClientAssertionType string
+	ClientAssertion     string
+	Scopes              []string
+	Audience            []string
+}
+
+type TokenResponse struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scopes      []string
+}
+
+type Service struct {
+	repo     entity.ServiceAccountRepository
+	replay   ReplayGuard
+	tokenURL string
+}
+
+// NewService creates the service. tokenURL is the public URL of the token
+// endpoint; client assertions must name it as their audience.
+func NewService(repo entity.ServiceAccountRepository, replay ReplayGuard, tokenURL string) *Service {
+	return &Service{repo: repo, replay: replay, tokenURL: tokenURL}
+}
+
+// Create registers a service account owned by orgID. When publicKeyPEM is
+// empty a client secret is generated and returned once; otherwise the
+// account can only use private_key_jwt.
+func (s *Service) Create(ctx context.Context, orgID, name string, scopes, audiences []string, publicKeyPEM, createdBy string) (*model.ServiceAccount, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &service.FieldError{Field: "name", Reason: "REQUIRED", Message: "name is required"}
+	}
+	if publicKeyPEM != "" {
+		if _, err := parsePublicKey(publicKeyPEM); err != nil {
+			return nil, "", &service.FieldError{Field: "public_key", Reason: "PUBLIC_KEY_INVALID", Message: err.Error()}
+		}
+	}
+
+	sa := &model.ServiceAccount{
+		OrgID:     orgID,
+		ClientID:  "sa_" + randomHex(16),
+		Name:      name,
+		PublicKey: publicKeyPEM,
+		Scopes:    compact(scopes),
+		Audiences: compact(audiences),
+		CreatedBy: createdBy,
+	}
+	var secret string
+	if publicKeyPEM == "" {
+		secret = randomHex(32)
+		sa.SecretHash = hashSecret(secret)
+	}
+
+	created, err := s.repo.CreateServiceAccount(ctx, sa)
+	if err != nil {
+		return nil, "", err
+	}
+	service.AuditTarget(ctx, created.ClientID)
+	service.AuditDetail(ctx, "name", created.Name)
+	return created, secret, nil
+}
+
+func (s *Service) List(ctx context.Context, orgID string) ([]*model.ServiceAccount, error) {
+	return s.repo.ListServiceAccounts(ctx, orgID)
+}
+
+// Disable stops the account from obtaining new tokens. Tokens already issued
+// stay valid until they expire, which is why they are short-lived.
+func (s *Service) Disable(ctx context.Context, orgID, id string) error {
+	if err := s.repo.DisableServiceAccount(ctx, orgID, id); err != nil {
+		return err
+	}
+	service.AuditTarget(ctx, id)
+	return nil
+}
+
+// ClientCredentials authenticates the client and issues an access token
+// limited to the requested scopes and audiences. Errors are always *Error.
+func (s *Service) ClientCredentials(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	if req.GrantType != "client_credentials" {
+		return nil, &Error{Code: "unsupported_grant_type", Description: "only client_credentials is supported", Status: http.StatusBadRequest}
+	}
+
+	sa, err := s.authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string(sa.Scopes)
+	if len(req.Scopes) > 0 {
+		for _, scope := range req.Scopes {
+			if !slices.Contains(sa.Scopes, scope) {
+				return nil, &Error{Code: "invalid_scope", Description: "scope " + scope + " is not granted to this client", Status: http.StatusBadRequest}
+			}
+		}
+		scopes = compact(req.Scopes)
+	}
+
+	audience := []string(sa.Audiences)
+	if len(req.Audience) > 0 {
+		for _, aud := range req.Audience {
+			if !slices.Contains(sa.Audiences, aud) {
+				return nil, &Error{Code: "invalid_target", Description: "audience " + aud + " is not allowed for this client", Status: http.StatusBadRequest}
+			}
+		}
+		audience = compact(req.Audience)
+	}
+
+	accessToken, ttl, err := token.GenerateClientToken(sa.ClientID, sa.OrgID, scopes, audience)
+	if err != nil {
+		log.Printf("issue client token for %s: %v", sa.ClientID, err)
+		return nil, &Error{Code: "server_error", Description: "failed to issue token", Status: http.StatusInternalServerError}
+	}
+	service.AuditActor(ctx, sa.ClientID, "service_account", sa.OrgID)
+	service.AuditDetail(ctx, "scopes", strings.Join(scopes, " "))
+	return &TokenResponse{AccessToken: accessToken, ExpiresIn: ttl, Scopes: scopes}, nil
+}
+
+func (s *Service) authenticate(ctx context.Context, req TokenRequest) (*model.ServiceAccount, error) {
+	if req.ClientAssertion != "" || req.ClientAssertionType != "" {
+		if req.ClientSecret != "" {
+			return nil, invalidRequest("use either client_secret or client_assertion")
+		}
+		if req.ClientAssertionType != ClientAssertionType {
+			return nil, invalidRequest("unsupported client_assertion_type")
+		}
+		return s.authenticateAssertion(ctx, req.ClientID, req.ClientAssertion)
+	}
+
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return nil, invalidClient("client authentication required")
+	}
+	sa, err := s.repo.GetServiceAccountByClientID(ctx, req.ClientID)
+	if err != nil || sa.SecretHash == "" {
+		return nil, invalidClient("invalid client credentials")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(req.ClientSecret)), []byte(sa.SecretHash)) != 1 {
+		return nil, invalidClient("invalid client credentials")
+	}
+	return sa, nil
+}
+
+// authenticateAssertion verifies a private_key_jwt client assertion: the
+// client signs a JWT with iss = sub = client_id and aud = token endpoint.
+func (s *Service) authenticateAssertion(ctx context.Context, clientID, assertion string) (*model.ServiceAccount, error) {
+	// The client_id parameter is optional with assertions, so read the
+	// issuer first to find the key, then verify.
+	unverified := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, unverified); err != nil {
+		return nil, invalidClient("malformed client assertion")
+	}
+	if clientID == "" {
+		clientID = unverified.Issuer
+	}
+
+	sa, err := s.repo.GetServiceAccountByClientID(ctx, clientID)
+	if err != nil || sa.PublicKey == "" {
+		return nil, invalidClient("invalid client assertion")
+	}
+	key, err := parsePublicKey(sa.PublicKey)
+	if err != nil {
+		log.Printf("service account %s has an unusable public key: %v", sa.ClientID, err)
+		return nil, invalidClient("invalid client assertion")
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(assertion, claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods(methodsFor(key)),
+		jwt.WithIssuer(sa.ClientID),
+		jwt.WithSubject(sa.ClientID),
+		jwt.WithAudience(s.tokenURL),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, invalidClient("invalid client assertion")
+	}
+	if claims.ID == "" {
+		return nil, invalidClient("client assertion must carry a jti")
+	}
+	lifetime := time.Until(claims.ExpiresAt.Time)
+	if lifetime > maxAssertionLifetime {
+		return nil, invalidClient("client assertion expires too far in the future")
+	}
+
+	fresh, err := s.replay.MarkAssertionUsed(ctx, sa.ClientID+":"+claims.ID, lifetime)
+	if err != nil {
+		log.Printf("record client assertion for %s: %v", sa.ClientID, err)
+		return nil, &Error{Code: "server_error", Description: "failed to verify client assertion", Status: http.StatusInternalServerError}
+	}
+	if !fresh {
+		return nil, invalidClient("client assertion already used")
+	}
+	return sa, nil
+}
+
+func parsePublicKey(pemData string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("public key must be PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func methodsFor(key interface{}) []string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		return []string{"ES256", "ES384", "ES512"}
+	default:
+		return []string{"EdDSA"}
+	}
+}
+
+// hashSecret is enough for generated secrets, which carry 256 bits of
+// entropy.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// compact trims values and drops blanks and duplicates.
+func compact(values []string) []string {
+	out := []string{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
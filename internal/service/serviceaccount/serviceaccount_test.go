@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package serviceaccount
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+)
+
+const testTokenURL = "https://auth.example.com/oauth2/token"
+
+type fakeAccounts struct {
+	entity.ServiceAccountRepository
+	accounts map[string]*model.ServiceAccount
+}
+
+func (f *fakeAccounts) GetServiceAccountByClientID(_ context.Context, clientID string) (*model.ServiceAccount, error) {
+	sa, ok := f.accounts[clientID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return sa, nil
+}
+
+// memoryReplay is a ReplayGuard that never forgets.
+type memoryReplay map[string]bool
+
+func (m memoryReplay) MarkAssertionUsed(_ context.Context, jti string, _ time.Duration) (bool, error) {
+	if m[jti] {
+		return false, nil
+	}
+	m[jti] = true
+	return true, nil
+}
+
+func newKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func assertion(t *testing.T, key ed25519.PrivateKey, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func assertionClaims(clientID, jti string, ttl time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{testTokenURL},
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+	}
+}
+
+func TestClientAssertion(t *testing.T) {
+	key, publicPEM := newKey(t)
+	otherKey, _ := newKey(t)
+	accounts := &fakeAccounts{accounts: map[string]*model.ServiceAccount{
+		"billing": {ClientID: "billing", OrgID: "org-1", PublicKey: publicPEM,
+			Scopes: []string{"orders:read"}, Audiences: []string{"orders"}},
+	}}
+	svc := NewService(accounts, memoryReplay{}, testTokenURL)
+
+	used := assertion(t, key, assertionClaims("billing", "jti-used", time.Minute))
+	if _, err := svc.ClientCredentials(context.Background(), TokenRequest{
+		GrantType: "client_credentials", ClientAssertionType: ClientAssertionType, ClientAssertion: used,
+	}); err != nil {
+		t.Fatalf("first use of an assertion: %v", err)
+	}
+
+	wrongAudience := assertionClaims("billing", "jti-aud", time.Minute)
+	wrongAudience.Audience = jwt.ClaimStrings{"https://elsewhere.example.com/token"}
+
+	tests := []struct {
+		name      string
+		assertion string
+		wantCode  string
+	}{
+		{"fresh assertion", assertion(t, key, assertionClaims("billing", "jti-fresh", time.Minute)), ""},
+		{"replayed jti", used, "invalid_client"},
+		{"same jti in a new assertion", assertion(t, key, assertionClaims("billing", "jti-used", 2*time.Minute)), "invalid_client"},
+		{"missing jti", assertion(t, key, assertionClaims("billing", "", time.Minute)), "invalid_client"},
+		{"lifetime too long", assertion(t, key, assertionClaims("billing", "jti-long", time.Hour)), "invalid_client"},
+		{"expired", assertion(t, key, assertionClaims("billing", "jti-expired", -time.Minute)), "invalid_client"},
+		{"wrong audience", assertion(t, key, wrongAudience), "invalid_client"},
+		{"signed by another key", assertion(t, otherKey, assertionClaims("billing", "jti-other", time.Minute)), "invalid_client"},
+		{"unknown client", assertion(t, key, assertionClaims("unknown", "jti-unknown", time.Minute)), "invalid_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.ClientCredentials(context.Background(), TokenRequest{
+				GrantType: "client_credentials", ClientAssertionType: ClientAssertionType, ClientAssertion: tt.assertion,
+			})
+			var oauthErr *Error
+			if errors.As(err, &oauthErr) {
+				if oauthErr.Code != tt.wantCode {
+					t.Fatalf("error = %v, want %q", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || tt.wantCode != "" {
+				t.Fatalf("error = %v, want %q", err, tt.wantCode)
+			}
+			claims, err := token.ParseAndValidate(resp.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != "billing" || claims.Issuer == "" || claims.Email != "" {
+				t.Errorf("claims sub=%q iss=%q email=%q, want sub billing, an issuer and no email", claims.Subject, claims.Issuer, claims.Email)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned when an endpoint resolves to an address
+// deliveries must not reach.
+var errBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedIP reports whether ip is loopback, link-local, private or
+// unspecified. Endpoints are entered by org admins, so without this check
+// a webhook could be pointed at the service's own network.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// checkHost resolves host and fails if any of its addresses is blocked.
+func checkHost(ctx context.Context, resolver *net.Resolver, host string) error {
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr.IP, errBlockedAddress)
+		}
+	}
+	return nil
+}
+
+// dialControl runs after the name is resolved and before the connection is
+// made, so an endpoint whose DNS answer changes after validation (DNS
+// rebinding) is still refused.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blockedIP(ip) {
+		return fmt.Errorf("dial %s: %w", address, errBlockedAddress)
+	}
+	return nil
+}
+
+// newDeliveryClient returns the client deliveries are posted with. It never
+// uses a proxy, so the dialer sees the endpoint's own address.
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialControl}
+	return &http.Client{
+		Timeout: defaultTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 2,
+		},
+		// 重定向按失败处理，避免被引导到内网地址
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
@@ -0,0 +1,429 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package webhook notifies subscribed endpoints of user lifecycle events.
+// Publish records one delivery per matching subscription and queues it in
+// the caller's transaction; Deliver, run by the outbox worker, signs and
+// posts it and keeps the outcome in the delivery log.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/signature"
+)
+
+// TopicWebhook is the outbox topic of queued deliveries.
+const TopicWebhook = "webhook"
+
+// Headers sent with every delivery. The signature covers the timestamp
+// and body, see package signature.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// SecretPrefix starts every signing secret.
+const SecretPrefix = "whsec_"
+
+const (
+	defaultMaxAttempts  = 8
+	defaultTimeout      = 10 * time.Second
+	defaultListLimit    = 50
+	maxListLimit        = 200
+	maxDescriptionBytes = 255
+)
+
+// Queue stores deliveries for the background worker. Enqueueing inside a
+// transaction commits or rolls back with it.
+type Queue interface {
+	Enqueue(ctx context.Context, topic string, payload interface{}) error
+}
+
+// Transactor runs fn in a database transaction that repository calls made
+// with fn's context join.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Event is the JSON body of every delivery. ID stays the same across
+// retries, replays and subscriptions, so receivers can drop duplicates.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// deliveryRef is the outbox payload of a queued delivery.
+type deliveryRef struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+type Service struct {
+	repo        entity.WebhookRepository
+	queue       Queue
+	tx          Transactor
+	client      *http.Client
+	resolver    *net.Resolver
+	maxAttempts int
+}
+
+type Option func(*Service)
+
+// WithMaxAttempts sets after how many failed attempts a delivery is given
+// up. It should match the outbox's limit.
+func WithMaxAttempts(n int) Option {
+	return func(s *Service) {
+		if n > 0 {
+			s.maxAttempts = n
+		}
+	}
+}
+
+// WithHTTPClient replaces the client deliveries are posted with. The
+// default client refuses to connect to non-public addresses; a replacement
+// has to bring its own guard.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Service) {
+		s.client = c
+	}
+}
+
+// WithTransactor makes Replay record and queue a delivery atomically.
+func WithTransactor(t Transactor) Option {
+	return func(s *Service) {
+		s.tx = t
+	}
+}
+
+func NewService(repo entity.WebhookRepository, queue Queue, opts ...Option) *Service {
+	s := &Service{
+		repo:        repo,
+		queue:       queue,
+		maxAttempts: defaultMaxAttempts,
+		resolver:    net.DefaultResolver,
+		client:      newDeliveryClient(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Publish queues event with data for every enabled subscription of orgIDs
+// that receives it; orgIDs are the organizations the event's user belongs
+// to. Call it inside the transaction of the change it reports.
+func (s *Service) Publish(ctx context.Context, event string, orgIDs []string, data interface{}) error {
+	if len(orgIDs) == 0 {
+		return nil
+	}
+	subs, err := s.repo.ListWebhooksForEvent(ctx, event, orgIDs)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", event, err)
+	}
+	ev := Event{ID: newEventID(), Type: event, CreatedAt: time.Now().UTC(), Data: raw}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", event, err)
+	}
+
+	for _, sub := range subs {
+		if err := s.queueDelivery(ctx, &model.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        ev.ID,
+			Event:          event,
+			Payload:        body,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) queueDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	created, err := s.repo.CreateWebhookDelivery(ctx, d)
+	if err != nil {
+		return err
+	}
+	*d = *created
+	return s.queue.Enqueue(ctx, TopicWebhook, deliveryRef{DeliveryID: created.ID})
+}
+
+// Deliver posts a queued delivery. It has the shape of an outbox handler;
+// a returned error makes the outbox retry with backoff. After maxAttempts
+// failures the delivery is marked failed and no longer retried.
+func (s *Service) Deliver(ctx context.Context, _ string, payload json.RawMessage) error {
+	var ref deliveryRef
+	if err := json.Unmarshal(payload, &ref); err != nil || ref.DeliveryID == "" {
+		log.Printf("webhook: dropping malformed outbox payload %s", payload)
+		return nil
+	}
+
+	d, err := s.repo.GetWebhookDelivery(ctx, ref.DeliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 订阅已删除，投递记录随之删除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if d.Status != entity.WebhookDeliveryPending {
+		return nil
+	}
+
+	sub, err := s.repo.GetWebhook(ctx, d.SubscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sub.Disabled {
+		return s.repo.RecordWebhookAttempt(ctx, d.ID, entity.WebhookDeliveryFailed, 0, "subscription disabled")
+	}
+
+	code, postErr := s.post(ctx, sub, d)
+	if postErr == nil {
+		return s.repo.RecordWebhookAttempt(ctx, d.ID, entity.WebhookDeliverySucceeded, code, "")
+	}
+
+	status := entity.WebhookDeliveryPending
+	if d.Attempts+1 >= s.maxAttempts {
+		status = entity.WebhookDeliveryFailed
+	}
+	if err := s.repo.RecordWebhookAttempt(ctx, d.ID, status, code, postErr.Error()); err != nil {
+		return err
+	}
+	if status == entity.WebhookDeliveryFailed {
+		log.Printf("webhook: delivery %s of %s to %s failed for good: %v", d.ID, d.Event, sub.URL, postErr)
+		return nil
+	}
+	return postErr
+}
+
+// post sends d to sub and returns the response status.
+func (s *Service) post(ctx context.Context, sub *model.WebhookSubscription, d *model.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, signature.Sign(sub.Secret, ts, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if msg := strings.TrimSpace(string(respBody)); msg != "" {
+			return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, msg)
+		}
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Create registers a subscription with a fresh signing secret. The
+// returned subscription is the only place the secret is shown.
+func (s *Service) Create(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	if err := s.validate(ctx, sub); err != nil {
+		return nil, err
+	}
+	sub.Secret = newSecret()
+	created, err := s.repo.CreateWebhook(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, created.ID)
+	service.AuditDetail(ctx, "url", created.URL)
+	service.AuditDetail(ctx, "events", strings.Join(created.Events, ","))
+	return created, nil
+}
+
+// Update replaces the URL, events, description and disabled flag of
+// sub.ID. The secret is kept.
+func (s *Service) Update(ctx context.Context, sub *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	if err := s.validate(ctx, sub); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateWebhook(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, updated.ID)
+	service.AuditDetail(ctx, "url", updated.URL)
+	service.AuditDetail(ctx, "events", strings.Join(updated.Events, ","))
+	return updated, nil
+}
+
+// List returns the subscriptions of orgID.
+func (s *Service) List(ctx context.Context, orgID string) ([]*model.WebhookSubscription, error) {
+	return s.repo.ListWebhooks(ctx, orgID)
+}
+
+// Delete removes a subscription of orgID and its delivery log. Queued
+// deliveries are dropped.
+func (s *Service) Delete(ctx context.Context, orgID, id string) error {
+	if err := s.repo.DeleteWebhook(ctx, orgID, id); err != nil {
+		return err
+	}
+	service.AuditTarget(ctx, id)
+	return nil
+}
+
+// Deliveries returns the newest deliveries of a subscription of orgID.
+// Subscriptions of other organizations return an error wrapping
+// sql.ErrNoRows.
+func (s *Service) Deliveries(ctx context.Context, orgID, subscriptionID string, limit int) ([]*model.WebhookDelivery, error) {
+	if _, err := s.subscription(ctx, orgID, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	return s.repo.ListWebhookDeliveries(ctx, subscriptionID, min(limit, maxListLimit))
+}
+
+// Replay queues the event of a logged delivery again, as a new delivery
+// with the same event ID and body. Unknown IDs, and deliveries of another
+// organization's subscriptions, return an error wrapping sql.ErrNoRows.
+func (s *Service) Replay(ctx context.Context, orgID, deliveryID string) (*model.WebhookDelivery, error) {
+	orig, err := s.repo.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.subscription(ctx, orgID, orig.SubscriptionID); err != nil {
+		return nil, err
+	}
+	d := &model.WebhookDelivery{
+		SubscriptionID: orig.SubscriptionID,
+		EventID:        orig.EventID,
+		Event:          orig.Event,
+		Payload:        orig.Payload,
+		ReplayOf:       orig.ID,
+	}
+	queue := func(ctx context.Context) error { return s.queueDelivery(ctx, d) }
+	if s.tx != nil {
+		err = s.tx.InTx(ctx, queue)
+	} else {
+		err = queue(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	service.AuditTarget(ctx, orig.ID)
+	service.AuditDetail(ctx, "replayed_as", d.ID)
+	return d, nil
+}
+
+// subscription returns subscription id if it belongs to orgID.
+func (s *Service) subscription(ctx context.Context, orgID, id string) (*model.WebhookSubscription, error) {
+	sub, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.OrgID != orgID {
+		return nil, fmt.Errorf("get webhook: %w", sql.ErrNoRows)
+	}
+	return sub, nil
+}
+
+func (s *Service) validate(ctx context.Context, sub *model.WebhookSubscription) error {
+	sub.URL = strings.TrimSpace(sub.URL)
+	sub.Description = strings.TrimSpace(sub.Description)
+
+	u, err := url.Parse(sub.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return &service.FieldError{Field: "url", Reason: "URL_INVALID", Message: "url must be an absolute https URL"}
+	}
+	if u.User != nil {
+		return &service.FieldError{Field: "url", Reason: "URL_INVALID", Message: "url must not contain credentials"}
+	}
+	if err := checkHost(ctx, s.resolver, u.Hostname()); err != nil {
+		return &service.FieldError{Field: "url", Reason: "URL_NOT_ALLOWED", Message: err.Error()}
+	}
+	if len(sub.Description) > maxDescriptionBytes {
+		return &service.FieldError{Field: "description", Reason: "TOO_LONG", Message: "description is limited to 255 bytes"}
+	}
+
+	var events []string
+	for _, e := range sub.Events {
+		e = strings.TrimSpace(e)
+		if e != "*" && !slices.Contains(entity.WebhookEvents, e) {
+			return &service.FieldError{Field: "events", Reason: "UNKNOWN_EVENT",
+				Message: fmt.Sprintf("unknown event %q, expected * or one of %s", e, strings.Join(entity.WebhookEvents, ", "))}
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return &service.FieldError{Field: "events", Reason: "REQUIRED", Message: "at least one event is required"}
+	}
+	sub.Events = events
+	return nil
+}
+
+func newSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return SecretPrefix + hex.EncodeToString(b)
+}
+
+// newEventID returns a random (version 4) UUID.
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *AuthServer) DeleteAccount(ctx context.Context, req *authpb.DeleteAccountRequest) (*authpb.DeleteAccountResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	deleteAfter, err := s.AuthService.DeleteAccount(ctx, claims.UserID, req.Password)
+	if errors.Is(err, service.ErrInvalidPassword) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &authpb.DeleteAccountResponse{
+		Message:     "account scheduled for deletion",
+		DeleteAfter: timestamppb.New(deleteAfter),
+	}, nil
+}
+
+func (s *AuthServer) ExportMyData(ctx context.Context, req *authpb.ExportMyDataRequest) (*authpb.ExportMyDataResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.AuthService.ExportMyData(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authpb.ExportMyDataResponse{
+		Data:        string(data),
+		ContentType: "application/json",
+		GeneratedAt: timestamppb.New(time.Now()),
+	}, nil
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const roleAdmin = "admin"
+
+func claimsFromContext(ctx context.Context) (*token.Claims, error) {
+	claims, ok := ctx.Value("claims").(*token.Claims)
+	if !ok || claims == nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return claims, nil
+}
+
+func requireAdmin(ctx context.Context) (*token.Claims, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasRole(roleAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "admin role required")
+	}
+	return claims, nil
+}
+
+// requireAdminInOrg is requireAdmin for calls that act on users, which
+// stay limited to the members of the active organization.
+func requireAdminInOrg(ctx context.Context) (*token.Claims, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.OrgID == "" {
+		return nil, status.Error(codes.FailedPrecondition, service.ErrNoActiveOrganization.Error())
+	}
+	return claims, nil
+}
+
+// requireOrgAdmin admits platform admins and admins of the caller's active
+// organization. The returned claims carry the organization in OrgID.
+func requireOrgAdmin(ctx context.Context) (*token.Claims, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.OrgID == "" {
+		return nil, status.Error(codes.FailedPrecondition, service.ErrNoActiveOrganization.Error())
+	}
+	if !claims.HasRole(roleAdmin) && !claims.HasOrgRole(roleAdmin) {
+		return nil, status.Error(codes.PermissionDenied, "organization admin role required")
+	}
+	return claims, nil
+}
+
+func toIPPolicyPB(p *model.IPPolicy) *authpb.IPPolicy {
+	return &authpb.IPPolicy{
+		Id:          p.ID,
+		OrgId:       p.OrgID,
+		Method:      p.Method,
+		Action:      p.Action,
+		Cidr:        p.CIDR,
+		Description: p.Description,
+		CreatedBy:   p.CreatedBy,
+		CreatedAt:   timestamppb.New(p.CreatedAt),
+	}
+}
+
+// ListIPPolicies returns the policies of the caller's active organization.
+// Platform admins also get the platform-wide policies.
+func (s *AuthServer) ListIPPolicies(ctx context.Context, req *authpb.ListIPPoliciesRequest) (*authpb.ListIPPoliciesResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := s.IPPolicies.List(ctx, claims.OrgID, claims.HasRole(roleAdmin))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListIPPoliciesResponse{}
+	for _, p := range policies {
+		resp.Policies = append(resp.Policies, toIPPolicyPB(p))
+	}
+	return resp, nil
+}
+
+// CreateIPPolicy adds a policy to the caller's active organization, or a
+// platform-wide one when platform_wide is set, which needs a platform admin.
+func (s *AuthServer) CreateIPPolicy(ctx context.Context, req *authpb.CreateIPPolicyRequest) (*authpb.CreateIPPolicyResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := claims.OrgID
+	if req.PlatformWide {
+		if !claims.HasRole(roleAdmin) {
+			return nil, status.Error(codes.PermissionDenied, "admin role required")
+		}
+		orgID = ""
+	}
+
+	policy, err := s.IPPolicies.Create(ctx, orgID, req.Method, req.Action, req.Cidr, req.Description, claims.UserID)
+	if errors.Is(err, service.ErrInvalidIPPolicy) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateIPPolicyResponse{Policy: toIPPolicyPB(policy)}, nil
+}
+
+// DeleteIPPolicy deletes a policy of the caller's active organization.
+// Platform admins may also delete platform-wide policies.
+func (s *AuthServer) DeleteIPPolicy(ctx context.Context, req *authpb.DeleteIPPolicyRequest) (*authpb.DeleteIPPolicyResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "policy id is required")
+	}
+
+	err = s.IPPolicies.Delete(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, repo.ErrNotFound) && claims.HasRole(roleAdmin) {
+		err = s.IPPolicies.Delete(ctx, "", req.Id)
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "policy not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.DeleteIPPolicyResponse{Message: "policy deleted"}, nil
+}
+
+func (s *AuthServer) ReloadIPPolicies(ctx context.Context, req *authpb.ReloadIPPoliciesRequest) (*authpb.ReloadIPPoliciesResponse, error) {
+	if _, err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	count, err := s.IPPolicies.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.ReloadIPPoliciesResponse{Count: int32(count)}, nil
+}
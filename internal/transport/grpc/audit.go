@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/audit"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// audited reports whether calls to method go into the audit log. Reads
+// and token introspection are left out; everything that logs in, changes
+// data or revokes access is recorded.
+func audited(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	switch name {
+	case "HealthCheck", "ValidateToken", "Me":
+		return false
+	}
+	return !strings.HasPrefix(name, "List") && !strings.HasPrefix(name, "Get")
+}
+
+// auditAction turns "/auth.v1.AuthService/ForgotPassword" into
+// "forgot_password".
+func auditAction(method string) string {
+	name := method[strings.LastIndex(method, "/")+1:]
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// requestTarget picks the object a request is about from the usual ID
+// fields of the generated messages.
+func requestTarget(req interface{}) string {
+	if r, ok := req.(interface{ GetUserId() string }); ok && r.GetUserId() != "" {
+		return r.GetUserId()
+	}
+	if r, ok := req.(interface{ GetId() string }); ok && r.GetId() != "" {
+		return r.GetId()
+	}
+	if r, ok := req.(interface{ GetClientId() string }); ok && r.GetClientId() != "" {
+		return r.GetClientId()
+	}
+	if r, ok := req.(interface{ GetIdentifier() string }); ok && r.GetIdentifier() != "" {
+		return r.GetIdentifier()
+	}
+	if r, ok := req.(interface{ GetEmail() string }); ok {
+		return r.GetEmail()
+	}
+	return ""
+}
+
+// requestID returns the ID the caller sent in x-request-id
+// (Grpc-Metadata-X-Request-Id through the gateway) or a new random one.
+func requestID(given string) string {
+	if given != "" && len(given) <= 128 {
+		return given
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func userAgent(md metadata.MD) string {
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// AuditInterceptor writes a row to the audit log for every audited call,
+// including calls rejected by later interceptors, and for any other call
+// that asks for it with service.AuditAlways. The request ID is sent back
+// in the x-request-id header.
+func AuditInterceptor(recorder *audit.Service, trusted []netip.Prefix) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if recorder == nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		var given string
+		if v := md.Get("x-request-id"); len(v) > 0 {
+			given = v[0]
+		}
+		ev := &model.AuditEvent{
+			Action:    auditAction(info.FullMethod),
+			Outcome:   entity.AuditSuccess,
+			TargetID:  requestTarget(req),
+			UserAgent: userAgent(md),
+			RequestID: requestID(given),
+		}
+		if addr, ok := clientIP(ctx, trusted); ok {
+			ev.IP = addr.String()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", ev.RequestID))
+
+		ctx, note := service.WithAuditNote(ctx)
+		resp, err := handler(ctx, req)
+		if !audited(info.FullMethod) && !note.Always {
+			return resp, err
+		}
+		if err != nil {
+			st, _ := status.FromError(err)
+			ev.Outcome = entity.AuditFailure
+			ev.Reason = st.Code().String() + ": " + st.Message()
+		}
+		record(ctx, recorder, ev, note)
+		return resp, err
+	}
+}
+
+// auditHTTP records requests to the plain HTTP endpoints (OAuth2, SCIM)
+// like AuditInterceptor does for gRPC calls. action names a request and
+// reports whether it is audited; handlers can still force a record with
+// service.AuditAlways. Responses of 400 and above are failures.
+func auditHTTP(recorder *audit.Service, trusted []netip.Prefix, action func(*http.Request) (string, bool), next http.Handler) http.Handler {
+	if recorder == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, audited := action(r)
+		ev := &model.AuditEvent{
+			Action:    name,
+			Outcome:   entity.AuditSuccess,
+			UserAgent: r.UserAgent(),
+			RequestID: requestID(r.Header.Get("X-Request-Id")),
+		}
+		if addr, ok := httpClientIP(r, trusted); ok {
+			ev.IP = addr.String()
+		}
+		w.Header().Set("X-Request-Id", ev.RequestID)
+
+		ctx, note := service.WithAuditNote(r.Context())
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		if !audited && !note.Always {
+			return
+		}
+		if sw.status >= http.StatusBadRequest {
+			ev.Outcome = entity.AuditFailure
+			ev.Reason = strconv.Itoa(sw.status) + " " + http.StatusText(sw.status)
+		}
+		record(ctx, recorder, ev, note)
+	})
+}
+
+// record writes ev even when the client has already gone away.
+func record(ctx context.Context, recorder *audit.Service, ev *model.AuditEvent, note *service.AuditNote) {
+	// 客户端断开也要写入审计日志
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := recorder.Record(recordCtx, ev, note); err != nil {
+		log.Printf("record audit event %s: %v", ev.Action, err)
+	}
+}
+
+// statusWriter remembers the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// noteActor tells the audit log who is calling.
+func noteActor(ctx context.Context, claims *token.Claims) {
+	switch {
+	case claims.Act != nil:
+		service.AuditActor(ctx, claims.Act.Subject, "impersonator", claims.OrgID)
+		service.AuditDetail(ctx, "on_behalf_of", claims.UserID)
+	case claims.TokenType == token.TokenTypeClient:
+		service.AuditActor(ctx, claims.Subject, "service_account", claims.OrgID)
+	case claims.TokenType == token.TokenTypePAT:
+		service.AuditActor(ctx, claims.UserID, "personal_access_token", claims.OrgID)
+	default:
+		service.AuditActor(ctx, claims.UserID, "user", claims.OrgID)
+	}
+}
+
+func toAuditEventPB(ev *model.AuditEvent) *authpb.AuditEvent {
+	return &authpb.AuditEvent{
+		Id:         ev.ID,
+		OccurredAt: timestamppb.New(ev.OccurredAt),
+		Action:     ev.Action,
+		Outcome:    ev.Outcome,
+		Reason:     ev.Reason,
+		ActorId:    ev.ActorID,
+		ActorType:  ev.ActorType,
+		TargetId:   ev.TargetID,
+		OrgId:      ev.OrgID,
+		Ip:         ev.IP,
+		UserAgent:  ev.UserAgent,
+		RequestId:  ev.RequestID,
+		Details:    ev.Details.String(),
+	}
+}
+
+func (s *AuthServer) ListAuditEvents(ctx context.Context, req *authpb.ListAuditEventsRequest) (*authpb.ListAuditEventsResponse, error) {
+	if _, err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	q := entity.AuditQuery{
+		ActorID:  req.ActorId,
+		TargetID: req.TargetId,
+		OrgID:    req.OrgId,
+		Action:   req.Action,
+		Outcome:  req.Outcome,
+		IP:       req.Ip,
+		Limit:    int(req.PageSize),
+	}
+	if req.Since != nil {
+		q.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		q.Until = req.Until.AsTime()
+	}
+
+	events, next, err := s.Audit.List(ctx, q, req.PageToken)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListAuditEventsResponse{NextPageToken: next}
+	for _, ev := range events {
+		resp.Events = append(resp.Events, toAuditEventPB(ev))
+	}
+	return resp, nil
+}
@@ -18,22 +18,62 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/auth"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
 	"github.com/shinoda4/sd-svc-auth/pkg/token"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// resolveIdentifier picks the login identifier from a request. The legacy
+// email and username fields are still accepted when identifier is empty.
+func resolveIdentifier(identifier string, typ authpb.IdentifierType, email, username string) (string, auth.IdentifierKind, error) {
+	var kind auth.IdentifierKind
+	switch typ {
+	case authpb.IdentifierType_IDENTIFIER_TYPE_EMAIL:
+		kind = auth.IdentifierEmail
+	case authpb.IdentifierType_IDENTIFIER_TYPE_USERNAME:
+		kind = auth.IdentifierUsername
+	default:
+		kind = auth.IdentifierAuto
+	}
+
+	switch {
+	case identifier != "":
+		return identifier, kind, nil
+	case email != "":
+		return email, auth.IdentifierEmail, nil
+	case username != "":
+		return username, auth.IdentifierUsername, nil
+	}
+	return "", kind, status.Error(codes.InvalidArgument, "identifier is required")
+}
+
 func (s *AuthServer) Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.LoginResponse, error) {
-	accessToken, refreshToken, accessTTL, refreshTTL, err := s.AuthService.Login(ctx, req.Email, req.Password)
+	identifier, kind, err := resolveIdentifier(req.Identifier, req.IdentifierType, req.Email, "")
 	if err != nil {
 		return nil, err
 	}
+
+	accessToken, refreshToken, accessTTL, refreshTTL, err := s.AuthService.Login(ctx, identifier, kind, req.Password, req.Organization)
+	switch {
+	case errors.Is(err, service.ErrPendingApproval):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrAccountRejected), errors.Is(err, service.ErrAccountDisabled),
+		errors.Is(err, service.ErrNotOrganizationMember):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, err
+	}
 	return &authpb.LoginResponse{
 		AccessToken:      accessToken,
 		RefreshToken:     refreshToken,
@@ -43,19 +83,31 @@ func (s *AuthServer) Login(ctx context.Context, req *authpb.LoginRequest) (*auth
 }
 
 func (s *AuthServer) Register(ctx context.Context, req *authpb.RegisterRequest) (*authpb.RegisterResponse, error) {
-	baseURL := os.Getenv("SERVER_HOST")
-	port := os.Getenv("SERVER_PORT")
-	verifyLink := fmt.Sprintf("%s/api/v1/verify", baseURL+":"+port)
-
-	user, verifyToken, err := s.AuthService.Register(ctx, req.Email, req.Username, req.Password, true, verifyLink)
+	links, err := s.clientLinks(ctx, req.ClientId)
 	if err != nil {
 		return nil, err
 	}
 
+	user, err := s.AuthService.Register(ctx, req.Email, req.Username, req.Password, req.Organization, true, links.Verify)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	switch {
+	case errors.Is(err, service.ErrRegistrationClosed), errors.Is(err, service.ErrInviteOnly), errors.Is(err, service.ErrEmailDomainNotAllowed):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	message := "registered"
+	if user.GetStatus() == entity.UserStatusPendingApproval {
+		message = "registered, pending approval"
+	}
 	return &authpb.RegisterResponse{
-		UserId:      user.GetID(),
-		Message:     "registered",
-		VerifyToken: verifyToken,
+		UserId:  user.GetID(),
+		Message: message,
 	}, nil
 }
 
@@ -80,6 +132,32 @@ func (s *AuthServer) VerifyEmail(ctx context.Context, req *authpb.VerifyEmailReq
 	}, nil
 }
 
+// ResendVerification mails a fresh verification link. The response is the
+// same whether or not the address belongs to an unverified account.
+func (s *AuthServer) ResendVerification(ctx context.Context, req *authpb.ResendVerificationRequest) (*authpb.ResendVerificationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	links, err := s.clientLinks(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.AuthService.ResendVerification(ctx, req.Email, links.Verify)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, service.ErrTooManyRequests) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.ResendVerificationResponse{
+		Message: "if the address belongs to an unverified account, a verification email was sent",
+	}, nil
+}
+
 func (s *AuthServer) Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error) {
 	rawToken, ok := ctx.Value("raw_token").(string)
 	if !ok || rawToken == "" {
@@ -101,16 +179,47 @@ func (s *AuthServer) Me(ctx context.Context, req *authpb.MeRequest) (*authpb.MeR
 		return nil, fmt.Errorf("unauthorized")
 	}
 
+	profile, err := s.AuthService.Me(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &authpb.MeResponse{
-		UserId:    claims.UserID,
-		Email:     claims.Email,
-		ExpiresIn: timestamppb.New(time.Unix(claims.ExpiresAt.Time.Unix(), 0)),
-		IssuedAt:  timestamppb.New(time.Unix(claims.IssuedAt.Time.Unix(), 0)),
+		UserId:           profile.UserID,
+		Email:            profile.Email,
+		ExpiresIn:        timestamppb.New(time.Unix(claims.ExpiresAt.Time.Unix(), 0)),
+		IssuedAt:         timestamppb.New(time.Unix(claims.IssuedAt.Time.Unix(), 0)),
+		Username:         profile.Username,
+		EmailVerified:    profile.EmailVerified,
+		PendingEmail:     profile.PendingEmail,
+		Roles:            profile.Roles,
+		CreatedAt:        timestamppb.New(profile.CreatedAt),
+		UpdatedAt:        timestamppb.New(profile.UpdatedAt),
+		Status:           profile.Status,
+		LinkedIdentities: toLinkedIdentitiesPB(profile.LinkedIdentities),
 	}, nil
 }
 
+func toLinkedIdentitiesPB(identities []auth.LinkedIdentity) []*authpb.LinkedIdentity {
+	out := make([]*authpb.LinkedIdentity, 0, len(identities))
+	for _, id := range identities {
+		out = append(out, &authpb.LinkedIdentity{Provider: id.Provider, Subject: id.Subject})
+	}
+	return out
+}
+
 func (s *AuthServer) ForgotPassword(ctx context.Context, req *authpb.ForgotPasswordRequest) (*authpb.ForgotPasswordResponse, error) {
-	err := s.AuthService.PasswordReset(ctx, req.Email, req.Username)
+	identifier, kind, err := resolveIdentifier(req.Identifier, req.IdentifierType, req.Email, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.clientLinks(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.AuthService.PasswordReset(ctx, identifier, kind, links.Reset)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +242,9 @@ func (s *AuthServer) ResetPassword(ctx context.Context, req *authpb.ResetPasswor
 	}
 
 	err := s.AuthService.PasswordResetConfirm(ctx, t, req.NewPasswordConfirm)
+	if errors.Is(err, service.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, "reset token is invalid or expired")
+	}
 	if err != nil {
 		return nil, err
 	}
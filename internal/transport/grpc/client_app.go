@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service/clientapp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// clientLinks returns the link templates of the client app a request
+// names, either in its client_id field or in x-client-id metadata (the
+// gateway forwards the Grpc-Metadata-X-Client-Id header). Requests that
+// name no app get the configured defaults.
+func (s *AuthServer) clientLinks(ctx context.Context, clientID string) (clientapp.Links, error) {
+	if clientID == "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get("x-client-id"); len(v) > 0 {
+			clientID = v[0]
+		}
+	}
+	links, err := s.ClientApps.Links(ctx, clientID)
+	if st, ok := fieldErrorStatus(err); ok {
+		return links, st
+	}
+	return links, err
+}
+
+func toClientAppPB(app *model.ClientApp) *authpb.ClientApp {
+	return &authpb.ClientApp{
+		ClientId:       app.ClientID,
+		Name:           app.Name,
+		VerifyUrl:      app.VerifyURL,
+		ResetUrl:       app.ResetURL,
+		EmailChangeUrl: app.EmailChangeURL,
+		InvitationUrl:  app.InvitationURL,
+		MagicLinkUrl:   app.MagicLinkURL,
+		CreatedBy:      app.CreatedBy,
+		CreatedAt:      timestamppb.New(app.CreatedAt),
+		UpdatedAt:      timestamppb.New(app.UpdatedAt),
+	}
+}
+
+func fromClientAppPB(pb *authpb.ClientApp) *model.ClientApp {
+	if pb == nil {
+		return &model.ClientApp{}
+	}
+	return &model.ClientApp{
+		ClientID:       pb.ClientId,
+		Name:           pb.Name,
+		VerifyURL:      pb.VerifyUrl,
+		ResetURL:       pb.ResetUrl,
+		EmailChangeURL: pb.EmailChangeUrl,
+		InvitationURL:  pb.InvitationUrl,
+		MagicLinkURL:   pb.MagicLinkUrl,
+	}
+}
+
+func (s *AuthServer) ListClientApps(ctx context.Context, req *authpb.ListClientAppsRequest) (*authpb.ListClientAppsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := s.ClientApps.List(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListClientAppsResponse{}
+	for _, app := range apps {
+		resp.Apps = append(resp.Apps, toClientAppPB(app))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) CreateClientApp(ctx context.Context, req *authpb.CreateClientAppRequest) (*authpb.CreateClientAppResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := fromClientAppPB(req.App)
+	in.OrgID = claims.OrgID
+	in.CreatedBy = claims.UserID
+	app, err := s.ClientApps.Create(ctx, in)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, repo.ErrClientIDTaken) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateClientAppResponse{App: toClientAppPB(app)}, nil
+}
+
+func (s *AuthServer) UpdateClientApp(ctx context.Context, req *authpb.UpdateClientAppRequest) (*authpb.UpdateClientAppResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := fromClientAppPB(req.App)
+	in.OrgID = claims.OrgID
+	app, err := s.ClientApps.Update(ctx, in)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "client app not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.UpdateClientAppResponse{App: toClientAppPB(app)}, nil
+}
+
+func (s *AuthServer) DeleteClientApp(ctx context.Context, req *authpb.DeleteClientAppRequest) (*authpb.DeleteClientAppResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ClientApps.Delete(ctx, claims.OrgID, req.ClientId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "client app not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.DeleteClientAppResponse{Message: "client app deleted"}, nil
+}
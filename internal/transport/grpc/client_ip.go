@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/ippolicy"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP resolves the address of the real caller. X-Forwarded-For is
+// only honoured when the direct peer is a trusted proxy, and the chain is
+// walked right to left so a client cannot spoof its way past the proxy.
+func clientIP(ctx context.Context, trusted []netip.Prefix) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return forwardedIP(p.Addr.String(), md.Get("x-forwarded-for"), trusted)
+}
+
+// httpClientIP is clientIP for the plain HTTP endpoints.
+func httpClientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	return forwardedIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), trusted)
+}
+
+func forwardedIP(peerAddr string, forwardedFor []string, trusted []netip.Prefix) (netip.Addr, bool) {
+	addrPort, err := netip.ParseAddrPort(peerAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr := addrPort.Addr().Unmap()
+	if !isTrusted(addr, trusted) {
+		return addr, true
+	}
+
+	var hops []string
+	for _, v := range forwardedFor {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// IPPolicyInterceptor rejects calls whose client address is not permitted
+// for the target method by the platform-wide policies, or cannot be
+// determined, and records the client IP on the context. Every denial is
+// written to the audit log.
+func IPPolicyInterceptor(policies *ippolicy.Service, trusted []netip.Prefix) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		addr, ok := clientIP(ctx, trusted)
+		if !ok {
+			// 无法确定来源地址时拒绝，而不是绕过策略
+			service.AuditAlways(ctx)
+			service.AuditDetail(ctx, "ip_policy", "client address unknown")
+			return nil, status.Error(codes.PermissionDenied, "client address unknown")
+		}
+		ctx = context.WithValue(ctx, "client_ip", addr.String())
+
+		allowed, policy := policies.Check("", info.FullMethod, addr)
+		if !allowed {
+			service.AuditAlways(ctx)
+			service.AuditDetail(ctx, "ip_policy", policy.ID)
+			return nil, status.Error(codes.PermissionDenied, "client address not allowed")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// OrgIPPolicyInterceptor applies the policies of the organization a
+// token is bound to. It runs after AuthInterceptor; calls without claims
+// or without an active organization only face the platform-wide policies.
+func OrgIPPolicyInterceptor(policies *ippolicy.Service) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		claims, _ := ctx.Value("claims").(*token.Claims)
+		if claims == nil || claims.OrgID == "" {
+			return handler(ctx, req)
+		}
+		// IPPolicyInterceptor 已拒绝无法确定地址的调用
+		ip, _ := ctx.Value("client_ip").(string)
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, "client address unknown")
+		}
+		allowed, policy := policies.Check(claims.OrgID, info.FullMethod, addr)
+		if !allowed {
+			service.AuditAlways(ctx)
+			service.AuditDetail(ctx, "ip_policy", policy.ID)
+			return nil, status.Error(codes.PermissionDenied, "client address not allowed")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toEmailDomainRulePB(r *model.EmailDomainRule) *authpb.EmailDomainRule {
+	return &authpb.EmailDomainRule{
+		Domain:    r.Domain,
+		Action:    r.Action,
+		Note:      r.Note,
+		CreatedBy: r.CreatedBy,
+		CreatedAt: timestamppb.New(r.CreatedAt),
+	}
+}
+
+func (s *AuthServer) ListEmailDomainRules(ctx context.Context, req *authpb.ListEmailDomainRulesRequest) (*authpb.ListEmailDomainRulesResponse, error) {
+	if _, err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.EmailDomains.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListEmailDomainRulesResponse{}
+	for _, r := range rules {
+		resp.Rules = append(resp.Rules, toEmailDomainRulePB(r))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) SetEmailDomainRule(ctx context.Context, req *authpb.SetEmailDomainRuleRequest) (*authpb.SetEmailDomainRuleResponse, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := s.EmailDomains.SetRule(ctx, req.Domain, req.Action, req.Note, claims.UserID)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.SetEmailDomainRuleResponse{Rule: toEmailDomainRulePB(rule)}, nil
+}
+
+func (s *AuthServer) DeleteEmailDomainRule(ctx context.Context, req *authpb.DeleteEmailDomainRuleRequest) (*authpb.DeleteEmailDomainRuleResponse, error) {
+	if _, err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	err := s.EmailDomains.DeleteRule(ctx, req.Domain)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "rule not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.DeleteEmailDomainRuleResponse{Message: "rule deleted"}, nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"errors"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fieldErrorStatus converts a *service.FieldError into InvalidArgument with
+// a BadRequest detail, so clients can point at the offending field. The
+// second result is false when err is not a field error.
+func fieldErrorStatus(err error) (error, bool) {
+	var fe *service.FieldError
+	if !errors.As(err, &fe) {
+		return nil, false
+	}
+
+	st := status.New(codes.InvalidArgument, fe.Error())
+	detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       fe.Field,
+			Description: fe.Message,
+			Reason:      fe.Reason,
+		}},
+	})
+	if detailErr != nil {
+		return st.Err(), true
+	}
+	return detailed.Err(), true
+}
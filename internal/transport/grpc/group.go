@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toGroupPB(g *model.Group) *authpb.Group {
+	return &authpb.Group{
+		Id:          g.ID,
+		OrgId:       g.OrgID,
+		DisplayName: g.DisplayName,
+		ExternalId:  g.ExternalID,
+		Roles:       g.Roles,
+		CreatedAt:   timestamppb.New(g.CreatedAt),
+		UpdatedAt:   timestamppb.New(g.UpdatedAt),
+	}
+}
+
+// groupStatus maps the errors shared by the group RPCs.
+func groupStatus(err error) error {
+	if st, ok := fieldErrorStatus(err); ok {
+		return st
+	}
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		return status.Error(codes.NotFound, "group or member not found")
+	case errors.Is(err, repo.ErrGroupNameTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, repo.ErrGroupCycle):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
+
+func (s *AuthServer) CreateGroup(ctx context.Context, req *authpb.CreateGroupRequest) (*authpb.CreateGroupResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := s.AuthService.CreateGroup(ctx, claims.OrgID, req.DisplayName, req.Roles)
+	if err != nil {
+		return nil, groupStatus(err)
+	}
+	return &authpb.CreateGroupResponse{Group: toGroupPB(g)}, nil
+}
+
+func (s *AuthServer) ListGroups(ctx context.Context, req *authpb.ListGroupsRequest) (*authpb.ListGroupsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, total, err := s.AuthService.ListGroups(ctx, claims.OrgID, int(req.Offset), int(req.PageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListGroupsResponse{Total: int32(total)}
+	for _, g := range groups {
+		resp.Groups = append(resp.Groups, toGroupPB(g))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) GetGroup(ctx context.Context, req *authpb.GetGroupRequest) (*authpb.GetGroupResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := s.AuthService.GetGroup(ctx, claims.OrgID, req.Id)
+	if err != nil {
+		return nil, groupStatus(err)
+	}
+
+	resp := &authpb.GetGroupResponse{Group: toGroupPB(details.Group)}
+	for _, m := range details.Members {
+		resp.Members = append(resp.Members, &authpb.GroupUser{UserId: m.UserID, Username: m.Username})
+	}
+	for _, sg := range details.Subgroups {
+		resp.Subgroups = append(resp.Subgroups, &authpb.GroupRef{Id: sg.MemberGroupID, DisplayName: sg.DisplayName})
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) UpdateGroup(ctx context.Context, req *authpb.UpdateGroupRequest) (*authpb.UpdateGroupResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := s.AuthService.UpdateGroup(ctx, claims.OrgID, req.Id, req.DisplayName, req.Roles)
+	if err != nil {
+		return nil, groupStatus(err)
+	}
+	return &authpb.UpdateGroupResponse{Group: toGroupPB(g)}, nil
+}
+
+func (s *AuthServer) DeleteGroup(ctx context.Context, req *authpb.DeleteGroupRequest) (*authpb.DeleteGroupResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.AuthService.DeleteGroup(ctx, claims.OrgID, req.Id); err != nil {
+		return nil, groupStatus(err)
+	}
+	return &authpb.DeleteGroupResponse{Message: "group deleted"}, nil
+}
+
+func (s *AuthServer) AddGroupMembers(ctx context.Context, req *authpb.AddGroupMembersRequest) (*authpb.AddGroupMembersResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.UserIds) == 0 && len(req.MemberGroupIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids or member_group_ids is required")
+	}
+
+	err = s.AuthService.AddGroupMembers(ctx, claims.OrgID, req.GroupId, req.UserIds, req.MemberGroupIds)
+	if err != nil {
+		return nil, groupStatus(err)
+	}
+	return &authpb.AddGroupMembersResponse{Message: "members added"}, nil
+}
+
+// RemoveGroupMember removes either a user or a nested group.
+func (s *AuthServer) RemoveGroupMember(ctx context.Context, req *authpb.RemoveGroupMemberRequest) (*authpb.RemoveGroupMemberResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.UserId != "" && req.MemberGroupId == "":
+		err = s.AuthService.RemoveGroupMember(ctx, claims.OrgID, req.GroupId, req.UserId)
+	case req.MemberGroupId != "" && req.UserId == "":
+		err = s.AuthService.RemoveSubgroup(ctx, claims.OrgID, req.GroupId, req.MemberGroupId)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "exactly one of user_id and member_group_id is required")
+	}
+	if err != nil {
+		return nil, groupStatus(err)
+	}
+	return &authpb.RemoveGroupMemberResponse{Message: "member removed"}, nil
+}
+
+// GetEffectiveRoles reports the roles a member holds in the active
+// organization. Anyone may ask about themselves; other users need an org
+// admin.
+func (s *AuthServer) GetEffectiveRoles(ctx context.Context, req *authpb.GetEffectiveRolesRequest) (*authpb.GetEffectiveRolesResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.UserId
+	if userID == "" || userID == claims.UserID {
+		userID = claims.UserID
+		if claims.OrgID == "" {
+			return nil, status.Error(codes.FailedPrecondition, service.ErrNoActiveOrganization.Error())
+		}
+	} else if claims, err = requireOrgAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	roles, groups, err := s.AuthService.GetEffectiveRoles(ctx, claims.OrgID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "user is not a member of the organization")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.GetEffectiveRolesResponse{Roles: roles, Groups: groups}, nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultImpersonationTTL = 15 * time.Minute
+	maxImpersonationTTL     = time.Hour
+)
+
+// impersonationBlockedMethods cannot be called with an impersonation token:
+// they write to the impersonated account (identity, profile, metadata,
+// credentials), mint further tokens, or would let the admin act with their
+// own privileges.
+var impersonationBlockedMethods = map[string]bool{
+	"/auth.v1.AuthService/RefreshToken":              true,
+	"/auth.v1.AuthService/UpdateProfile":             true,
+	"/auth.v1.AuthService/UpdateUserMetadata":        true,
+	"/auth.v1.AuthService/ChangeEmail":               true,
+	"/auth.v1.AuthService/DeleteAccount":             true,
+	"/auth.v1.AuthService/ExportMyData":              true,
+	"/auth.v1.AuthService/CreatePersonalAccessToken": true,
+	"/auth.v1.AuthService/RevokePersonalAccessToken": true,
+	"/auth.v1.AuthService/Impersonate":               true,
+	"/auth.v1.AuthService/SwitchOrganization":        true,
+}
+
+func (s *AuthServer) Impersonate(ctx context.Context, req *authpb.ImpersonateRequest) (*authpb.ImpersonateResponse, error) {
+	claims, err := requireAdminInOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	ttl := defaultImpersonationTTL
+	if req.ExpiresInMinutes > 0 {
+		ttl = min(time.Duration(req.ExpiresInMinutes)*time.Minute, maxImpersonationTTL)
+	}
+
+	accessToken, accessTTL, err := s.AuthService.Impersonate(ctx, claims, req.UserId, req.Reason, ttl, req.NotifyUser)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, service.ErrImpersonationNotAllowed), errors.Is(err, service.ErrNoActiveOrganization):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return &authpb.ImpersonateResponse{
+		AccessToken: accessToken,
+		ExpiresIn:   timestamppb.New(time.Now().Add(accessTTL)),
+	}, nil
+}
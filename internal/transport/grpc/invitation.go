@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultInvitationTTL = 72 * time.Hour
+	maxInvitationTTL     = 30 * 24 * time.Hour
+)
+
+func toInvitationPB(inv *model.Invitation) *authpb.Invitation {
+	pb := &authpb.Invitation{
+		Id:        inv.ID,
+		OrgId:     inv.OrgID,
+		Email:     inv.Email,
+		Roles:     inv.Roles,
+		InvitedBy: inv.InvitedBy,
+		UserId:    inv.UserID,
+		Status:    inv.Status(time.Now()),
+		ExpiresAt: timestamppb.New(inv.ExpiresAt),
+		CreatedAt: timestamppb.New(inv.CreatedAt),
+	}
+	if inv.AcceptedAt != nil {
+		pb.AcceptedAt = timestamppb.New(*inv.AcceptedAt)
+	}
+	if inv.RevokedAt != nil {
+		pb.RevokedAt = timestamppb.New(*inv.RevokedAt)
+	}
+	return pb
+}
+
+func (s *AuthServer) CreateInvitation(ctx context.Context, req *authpb.CreateInvitationRequest) (*authpb.CreateInvitationResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	ttl := defaultInvitationTTL
+	if req.ExpiresInHours > 0 {
+		ttl = min(time.Duration(req.ExpiresInHours)*time.Hour, maxInvitationTTL)
+	}
+
+	links, err := s.clientLinks(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.AuthService.CreateInvitation(ctx, claims.OrgID, claims.UserID, req.Email, req.Roles, ttl, links.Invitation)
+	if errors.Is(err, service.ErrEmailInUse) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateInvitationResponse{Invitation: toInvitationPB(inv)}, nil
+}
+
+func (s *AuthServer) ListInvitations(ctx context.Context, req *authpb.ListInvitationsRequest) (*authpb.ListInvitationsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.AuthService.ListInvitations(ctx, claims.OrgID, req.PendingOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListInvitationsResponse{}
+	for _, inv := range invitations {
+		resp.Invitations = append(resp.Invitations, toInvitationPB(inv))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) RevokeInvitation(ctx context.Context, req *authpb.RevokeInvitationRequest) (*authpb.RevokeInvitationResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "invitation id is required")
+	}
+
+	err = s.AuthService.RevokeInvitation(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, repo.ErrInvitationNotPending) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.RevokeInvitationResponse{Message: "invitation revoked"}, nil
+}
+
+func (s *AuthServer) AcceptInvitation(ctx context.Context, req *authpb.AcceptInvitationRequest) (*authpb.AcceptInvitationResponse, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	user, err := s.AuthService.AcceptInvitation(ctx, req.Token, req.Username, req.Password)
+	var exists *repo.ErrUserExists
+	switch {
+	case errors.Is(err, service.ErrInvitationInvalid), errors.Is(err, repo.ErrInvitationNotPending):
+		return nil, status.Error(codes.FailedPrecondition, "invitation is invalid, expired or already used")
+	case errors.Is(err, service.ErrUsernameNotValid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repo.ErrUsernameTaken), errors.As(err, &exists):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return &authpb.AcceptInvitationResponse{
+		UserId:  user.GetID(),
+		Message: "registered",
+	}, nil
+}
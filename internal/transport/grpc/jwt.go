@@ -18,9 +18,12 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -49,12 +52,28 @@ func (s *AuthServer) ValidateToken(ctx context.Context, req *authpb.ValidateToke
 		return nil, status.Error(codes.Unauthenticated, "missing token")
 	}
 	claims, err := s.AuthService.ValidateToken(ctx, rawToken)
+	if errors.Is(err, service.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, "token revoked")
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &authpb.ValidateTokenResponse{
-		Valid:  true,
-		UserId: claims.UserID,
-		Email:  claims.Email,
-	}, nil
+	resp := &authpb.ValidateTokenResponse{
+		Valid:    true,
+		UserId:   claims.UserID,
+		Email:    claims.Email,
+		OrgId:    claims.OrgID,
+		OrgRoles: claims.OrgRoles,
+		Groups:   claims.Groups,
+		Metadata: metadataClaim(claims.Metadata),
+		Scopes:   claims.Scopes,
+		Audience: claims.Audience,
+	}
+	if claims.TokenType == token.TokenTypeClient {
+		resp.ClientId = claims.Subject
+	}
+	if claims.Act != nil {
+		resp.ActorId = claims.Act.Subject
+	}
+	return resp, nil
 }
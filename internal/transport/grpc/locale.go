@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// LocaleInterceptor records the caller's Accept-Language on the context,
+// where it picks the language of emails the call sends. The gateway
+// forwards the HTTP header as grpcgateway-accept-language.
+func LocaleInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, key := range []string{"accept-language", "grpcgateway-accept-language"} {
+			if v := md.Get(key); len(v) > 0 {
+				ctx = context.WithValue(ctx, "accept_language", v[0])
+				break
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GetMetadata returns both metadata documents of a user. Anyone may read
+// their own; other users need the admin role and must belong to the
+// admin's active organization.
+func (s *AuthServer) GetMetadata(ctx context.Context, req *authpb.GetMetadataRequest) (*authpb.GetMetadataResponse, error) {
+	orgID, userID, err := metadataSubject(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthService.GetMetadata(ctx, orgID, userID)
+	if err != nil {
+		return nil, metadataError(err)
+	}
+	userMetadata, err := toStruct(user.GetUserMetadata())
+	if err != nil {
+		return nil, err
+	}
+	appMetadata, err := toStruct(user.GetAppMetadata())
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.GetMetadataResponse{
+		UserId:       user.GetID(),
+		UserMetadata: userMetadata,
+		AppMetadata:  appMetadata,
+	}, nil
+}
+
+// UpdateUserMetadata merges req.Patch into the user-editable metadata.
+// Users may patch their own; admins those of their active organization.
+func (s *AuthServer) UpdateUserMetadata(ctx context.Context, req *authpb.UpdateUserMetadataRequest) (*authpb.UpdateUserMetadataResponse, error) {
+	orgID, userID, err := metadataSubject(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := patchJSON(req.Patch)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthService.UpdateUserMetadata(ctx, orgID, userID, patch)
+	if err != nil {
+		return nil, metadataError(err)
+	}
+	doc, err := toStruct(user.GetUserMetadata())
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.UpdateUserMetadataResponse{UserId: user.GetID(), UserMetadata: doc}, nil
+}
+
+// UpdateAppMetadata merges req.Patch into the admin-managed metadata of a
+// member of the admin's active organization.
+func (s *AuthServer) UpdateAppMetadata(ctx context.Context, req *authpb.UpdateAppMetadataRequest) (*authpb.UpdateAppMetadataResponse, error) {
+	claims, err := requireAdminInOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	patch, err := patchJSON(req.Patch)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthService.UpdateAppMetadata(ctx, claims.OrgID, req.UserId, patch)
+	if err != nil {
+		return nil, metadataError(err)
+	}
+	doc, err := toStruct(user.GetAppMetadata())
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.UpdateAppMetadataResponse{UserId: user.GetID(), AppMetadata: doc}, nil
+}
+
+// metadataSubject resolves whose metadata a request targets: the caller
+// when userID is empty or their own, otherwise userID if the caller is an
+// admin. orgID is the organization the target must belong to, empty for
+// the caller's own metadata.
+func metadataSubject(ctx context.Context, userID string) (orgID, subject string, err error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if userID == "" || userID == claims.UserID {
+		return "", claims.UserID, nil
+	}
+	if claims, err = requireAdminInOrg(ctx); err != nil {
+		return "", "", err
+	}
+	return claims.OrgID, userID, nil
+}
+
+func patchJSON(patch *structpb.Struct) (json.RawMessage, error) {
+	if patch == nil {
+		return nil, status.Error(codes.InvalidArgument, "patch is required")
+	}
+	data, err := protojson.Marshal(patch)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid patch: %v", err)
+	}
+	return data, nil
+}
+
+func toStruct(doc json.RawMessage) (*structpb.Struct, error) {
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(doc, s); err != nil {
+		return nil, status.Errorf(codes.Internal, "decode metadata: %v", err)
+	}
+	return s, nil
+}
+
+func metadataError(err error) error {
+	if st, ok := fieldErrorStatus(err); ok {
+		return st
+	}
+	if errors.Is(err, repo.ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return status.Error(codes.NotFound, "user not found")
+	}
+	return err
+}
+
+// metadataClaim converts the token's metadata claim for ValidateToken.
+func metadataClaim(values map[string]interface{}) *structpb.Struct {
+	if len(values) == 0 {
+		return nil
+	}
+	s, err := structpb.NewStruct(values)
+	if err != nil {
+		log.Printf("convert metadata claim: %v", err)
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/serviceaccount"
+)
+
+// oauth2TokenHandler serves POST /oauth2/token for the client_credentials
+// grant. Clients authenticate with HTTP Basic, client_secret_post or a
+// private_key_jwt assertion.
+func oauth2TokenHandler(serviceAccounts *serviceaccount.Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeOAuthError(w, &serviceaccount.Error{Code: "invalid_request", Description: "method must be POST", Status: http.StatusMethodNotAllowed})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+		if err := r.ParseForm(); err != nil {
+			writeOAuthError(w, &serviceaccount.Error{Code: "invalid_request", Description: "malformed form body", Status: http.StatusBadRequest})
+			return
+		}
+
+		req := serviceaccount.TokenRequest{
+			GrantType:           r.PostForm.Get("grant_type"),
+			ClientID:            r.PostForm.Get("client_id"),
+			ClientSecret:        r.PostForm.Get("client_secret"),
+			ClientAssertionType: r.PostForm.Get("client_assertion_type"),
+			ClientAssertion:     r.PostForm.Get("client_assertion"),
+			Scopes:              strings.Fields(r.PostForm.Get("scope")),
+			Audience:            append(r.PostForm["audience"], r.PostForm["resource"]...),
+		}
+		if id, secret, ok := r.BasicAuth(); ok {
+			// RFC 6749 2.3.1: credentials are form-encoded before Basic encoding
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+			if req.ClientID != "" && req.ClientID != id {
+				writeOAuthError(w, &serviceaccount.Error{Code: "invalid_request", Description: "client_id does not match the Authorization header", Status: http.StatusBadRequest})
+				return
+			}
+			req.ClientID, req.ClientSecret = id, secret
+		}
+		service.AuditTarget(r.Context(), req.ClientID)
+
+		resp, err := serviceAccounts.ClientCredentials(r.Context(), req)
+		if err != nil {
+			var oauthErr *serviceaccount.Error
+			if !errors.As(err, &oauthErr) {
+				oauthErr = &serviceaccount.Error{Code: "server_error", Status: http.StatusInternalServerError}
+			}
+			service.AuditDetail(r.Context(), "error", oauthErr.Code)
+			writeOAuthError(w, oauthErr)
+			return
+		}
+
+		writeOAuthJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": resp.AccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   int(resp.ExpiresIn.Seconds()),
+			"scope":        strings.Join(resp.Scopes, " "),
+		})
+	})
+}
+
+// oauth2AuditAction audits every token request.
+func oauth2AuditAction(*http.Request) (string, bool) {
+	return "oauth2_token", true
+}
+
+func writeOAuthError(w http.ResponseWriter, e *serviceaccount.Error) {
+	if e.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	body := map[string]interface{}{"error": e.Code}
+	if e.Description != "" {
+		body["error_description"] = e.Description
+	}
+	writeOAuthJSON(w, e.Status, body)
+}
+
+func writeOAuthJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("write oauth2 response: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toOrganizationPB(o *model.Organization) *authpb.Organization {
+	return &authpb.Organization{
+		Id:                          o.ID,
+		Slug:                        o.Slug,
+		Name:                        o.Name,
+		RegistrationMode:            o.RegistrationMode,
+		RegistrationAllowedDomains:  o.RegistrationAllowedDomains,
+		RegistrationRequireApproval: o.RegistrationRequireApproval,
+		AccessTokenTtlSeconds:       int32(o.AccessTokenTTLSeconds),
+		RefreshTokenTtlSeconds:      int32(o.RefreshTokenTTLSeconds),
+		CreatedAt:                   timestamppb.New(o.CreatedAt),
+		UpdatedAt:                   timestamppb.New(o.UpdatedAt),
+	}
+}
+
+func toOrganizationMemberPB(m *model.OrganizationMember) *authpb.OrganizationMember {
+	return &authpb.OrganizationMember{
+		OrgId:     m.OrgID,
+		OrgSlug:   m.OrgSlug,
+		OrgName:   m.OrgName,
+		UserId:    m.UserID,
+		Username:  m.Username,
+		Email:     m.Email,
+		Roles:     m.Roles,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+	}
+}
+
+// organizationStatus maps the errors shared by the organization RPCs.
+func organizationStatus(err error) error {
+	if st, ok := fieldErrorStatus(err); ok {
+		return st
+	}
+	switch {
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrNotOrganizationMember):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, repo.ErrOrganizationSlugTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return err
+}
+
+func (s *AuthServer) CreateOrganization(ctx context.Context, req *authpb.CreateOrganizationRequest) (*authpb.CreateOrganizationResponse, error) {
+	claims, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.AuthService.CreateOrganization(ctx, claims.UserID, req.Slug, req.Name)
+	if err != nil {
+		return nil, organizationStatus(err)
+	}
+	return &authpb.CreateOrganizationResponse{Organization: toOrganizationPB(org)}, nil
+}
+
+// ListOrganizations returns the caller's memberships.
+func (s *AuthServer) ListOrganizations(ctx context.Context, req *authpb.ListOrganizationsRequest) (*authpb.ListOrganizationsResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.AuthService.ListOrganizations(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListOrganizationsResponse{ActiveOrgId: claims.OrgID}
+	for _, m := range memberships {
+		resp.Memberships = append(resp.Memberships, toOrganizationMemberPB(m))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) UpdateOrganization(ctx context.Context, req *authpb.UpdateOrganizationRequest) (*authpb.UpdateOrganizationResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.AuthService.UpdateOrganization(ctx, claims.OrgID, &model.Organization{
+		Name:                        req.Name,
+		RegistrationMode:            req.RegistrationMode,
+		RegistrationAllowedDomains:  req.RegistrationAllowedDomains,
+		RegistrationRequireApproval: req.RegistrationRequireApproval,
+		AccessTokenTTLSeconds:       int(req.AccessTokenTtlSeconds),
+		RefreshTokenTTLSeconds:      int(req.RefreshTokenTtlSeconds),
+	})
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "organization not found")
+	}
+	if err != nil {
+		return nil, organizationStatus(err)
+	}
+	return &authpb.UpdateOrganizationResponse{Organization: toOrganizationPB(org)}, nil
+}
+
+func (s *AuthServer) ListOrganizationMembers(ctx context.Context, req *authpb.ListOrganizationMembersRequest) (*authpb.ListOrganizationMembersResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.AuthService.ListOrganizationMembers(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListOrganizationMembersResponse{}
+	for _, m := range members {
+		resp.Members = append(resp.Members, toOrganizationMemberPB(m))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) SetOrganizationMember(ctx context.Context, req *authpb.SetOrganizationMemberRequest) (*authpb.SetOrganizationMemberResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	m, err := s.AuthService.SetOrganizationMember(ctx, claims.OrgID, req.UserId, req.Roles)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.SetOrganizationMemberResponse{Member: toOrganizationMemberPB(m)}, nil
+}
+
+func (s *AuthServer) RemoveOrganizationMember(ctx context.Context, req *authpb.RemoveOrganizationMemberRequest) (*authpb.RemoveOrganizationMemberResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	err = s.AuthService.RemoveOrganizationMember(ctx, claims.OrgID, req.UserId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "member not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.RemoveOrganizationMemberResponse{Message: "member removed"}, nil
+}
+
+// SwitchOrganization returns a token pair bound to another organization the
+// caller belongs to.
+func (s *AuthServer) SwitchOrganization(ctx context.Context, req *authpb.SwitchOrganizationRequest) (*authpb.SwitchOrganizationResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, accessTTL, refreshTTL, err := s.AuthService.SwitchOrganization(ctx, claims.UserID, req.Organization)
+	if errors.Is(err, service.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, organizationStatus(err)
+	}
+	return &authpb.SwitchOrganizationResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        timestamppb.New(time.Now().Add(accessTTL)),
+		RefreshExpiresIn: timestamppb.New(time.Now().Add(refreshTTL)),
+	}, nil
+}
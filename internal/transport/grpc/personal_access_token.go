@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service/auth"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPATTTL = 30 * 24 * time.Hour
+	maxPATTTL     = 365 * 24 * time.Hour
+)
+
+// patMethodScopes lists the methods a personal access token may call and the
+// scope each one needs. Anything missing here (logout, token refresh,
+// account and token management) requires an interactive login.
+var patMethodScopes = map[string]string{
+	"/auth.v1.AuthService/ValidateToken": auth.ScopeRead,
+	"/auth.v1.AuthService/Me":            auth.ScopeRead,
+	"/auth.v1.AuthService/ExportMyData":  auth.ScopeRead,
+	"/auth.v1.AuthService/UpdateProfile": auth.ScopeWrite,
+
+	"/auth.v1.AuthService/GetMetadata":        auth.ScopeRead,
+	"/auth.v1.AuthService/UpdateUserMetadata": auth.ScopeWrite,
+	"/auth.v1.AuthService/UpdateAppMetadata":  auth.ScopeAdmin,
+
+	"/auth.v1.AuthService/ListOrganizations": auth.ScopeRead,
+	"/auth.v1.AuthService/GetEffectiveRoles": auth.ScopeRead,
+
+	"/auth.v1.AuthService/ListIPPolicies":           auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateIPPolicy":           auth.ScopeAdmin,
+	"/auth.v1.AuthService/DeleteIPPolicy":           auth.ScopeAdmin,
+	"/auth.v1.AuthService/ReloadIPPolicies":         auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateInvitation":         auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListInvitations":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/RevokeInvitation":         auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListPendingRegistrations": auth.ScopeAdmin,
+	"/auth.v1.AuthService/ApproveRegistration":      auth.ScopeAdmin,
+	"/auth.v1.AuthService/RejectRegistration":       auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListEmailDomainRules":     auth.ScopeAdmin,
+	"/auth.v1.AuthService/SetEmailDomainRule":       auth.ScopeAdmin,
+	"/auth.v1.AuthService/DeleteEmailDomainRule":    auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListClientApps":           auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateClientApp":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/UpdateClientApp":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/DeleteClientApp":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateWebhook":            auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListWebhooks":             auth.ScopeAdmin,
+	"/auth.v1.AuthService/UpdateWebhook":            auth.ScopeAdmin,
+	"/auth.v1.AuthService/DeleteWebhook":            auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListWebhookDeliveries":    auth.ScopeAdmin,
+	"/auth.v1.AuthService/ReplayWebhookDelivery":    auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListAuditEvents":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateServiceAccount":     auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListServiceAccounts":      auth.ScopeAdmin,
+	"/auth.v1.AuthService/DisableServiceAccount":    auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateScimClient":         auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListScimClients":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/RevokeScimClient":         auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateOrganization":       auth.ScopeAdmin,
+	"/auth.v1.AuthService/UpdateOrganization":       auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListOrganizationMembers":  auth.ScopeAdmin,
+	"/auth.v1.AuthService/SetOrganizationMember":    auth.ScopeAdmin,
+	"/auth.v1.AuthService/RemoveOrganizationMember": auth.ScopeAdmin,
+	"/auth.v1.AuthService/CreateGroup":              auth.ScopeAdmin,
+	"/auth.v1.AuthService/ListGroups":               auth.ScopeAdmin,
+	"/auth.v1.AuthService/GetGroup":                 auth.ScopeAdmin,
+	"/auth.v1.AuthService/UpdateGroup":              auth.ScopeAdmin,
+	"/auth.v1.AuthService/DeleteGroup":              auth.ScopeAdmin,
+	"/auth.v1.AuthService/AddGroupMembers":          auth.ScopeAdmin,
+	"/auth.v1.AuthService/RemoveGroupMember":        auth.ScopeAdmin,
+}
+
+func authenticatePAT(ctx context.Context, authService *auth.Service, method, rawToken string) (*token.Claims, error) {
+	scope, ok := patMethodScopes[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method not available to personal access tokens")
+	}
+	claims, err := authService.AuthenticatePersonalAccessToken(ctx, rawToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if !claims.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "token lacks the %q scope", scope)
+	}
+	return claims, nil
+}
+
+func toPersonalAccessTokenPB(t *model.PersonalAccessToken) *authpb.PersonalAccessToken {
+	pb := &authpb.PersonalAccessToken{
+		Id:        t.ID,
+		OrgId:     t.OrgID,
+		Name:      t.Name,
+		Prefix:    t.Prefix,
+		Scopes:    t.Scopes,
+		Active:    t.Active(time.Now()),
+		ExpiresAt: timestamppb.New(t.ExpiresAt),
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+	if t.LastUsedAt != nil {
+		pb.LastUsedAt = timestamppb.New(*t.LastUsedAt)
+	}
+	if t.RevokedAt != nil {
+		pb.RevokedAt = timestamppb.New(*t.RevokedAt)
+	}
+	return pb
+}
+
+func (s *AuthServer) CreatePersonalAccessToken(ctx context.Context, req *authpb.CreatePersonalAccessTokenRequest) (*authpb.CreatePersonalAccessTokenResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultPATTTL
+	if req.ExpiresInDays > 0 {
+		ttl = min(time.Duration(req.ExpiresInDays)*24*time.Hour, maxPATTTL)
+	}
+
+	pat, raw, err := s.AuthService.CreatePersonalAccessToken(ctx, claims.UserID, claims.OrgID, req.Name, req.Scopes, ttl)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreatePersonalAccessTokenResponse{
+		Token:               raw,
+		PersonalAccessToken: toPersonalAccessTokenPB(pat),
+	}, nil
+}
+
+func (s *AuthServer) ListPersonalAccessTokens(ctx context.Context, req *authpb.ListPersonalAccessTokensRequest) (*authpb.ListPersonalAccessTokensResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.AuthService.ListPersonalAccessTokens(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListPersonalAccessTokensResponse{}
+	for _, t := range tokens {
+		resp.Tokens = append(resp.Tokens, toPersonalAccessTokenPB(t))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) RevokePersonalAccessToken(ctx context.Context, req *authpb.RevokePersonalAccessTokenRequest) (*authpb.RevokePersonalAccessTokenResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	err = s.AuthService.RevokePersonalAccessToken(ctx, claims.UserID, req.Id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.RevokePersonalAccessTokenResponse{Message: "token revoked"}, nil
+}
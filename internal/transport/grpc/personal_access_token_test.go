@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/auth"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/pkg/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// patStore keeps personal access tokens in memory, keyed by token hash.
+type patStore struct {
+	entity.PersonalAccessTokenRepository
+	byHash map[string]*model.PersonalAccessToken
+}
+
+func (f *patStore) GetPersonalAccessTokenByHash(_ context.Context, hash string) (*model.PersonalAccessToken, error) {
+	t, ok := f.byHash[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+func (f *patStore) TouchPersonalAccessToken(context.Context, string) error { return nil }
+
+type patUsers struct {
+	entity.UserRepository
+	user *model.User
+}
+
+func (f *patUsers) GetUserByID(_ context.Context, id string) (entity.UserEntity, error) {
+	if id != f.user.ID {
+		return nil, sql.ErrNoRows
+	}
+	return f.user, nil
+}
+
+func TestAuthenticatePATScopes(t *testing.T) {
+	user := &model.User{ID: "user-1", Email: "alice@example.com", Status: entity.UserStatusActive}
+	store := &patStore{byHash: map[string]*model.PersonalAccessToken{}}
+	svc := auth.NewAuthService(&patUsers{user: user}, nil, nil, auth.WithPersonalAccessTokens(store))
+
+	issue := func(scopes ...string) string {
+		raw, prefix := token.GeneratePersonalAccessToken()
+		store.byHash[token.HashPersonalAccessToken(raw)] = &model.PersonalAccessToken{
+			ID: "pat-" + prefix, UserID: user.ID, Prefix: prefix, Scopes: scopes,
+			ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now(),
+		}
+		return raw
+	}
+	read := issue(auth.ScopeRead)
+	readWrite := issue(auth.ScopeRead, auth.ScopeWrite)
+	admin := issue(auth.ScopeAdmin)
+
+	tests := []struct {
+		name   string
+		method string
+		raw    string
+		want   codes.Code
+	}{
+		{"read scope reads", "/auth.v1.AuthService/Me", read, codes.OK},
+		{"read scope cannot write", "/auth.v1.AuthService/UpdateProfile", read, codes.PermissionDenied},
+		{"write scope writes", "/auth.v1.AuthService/UpdateProfile", readWrite, codes.OK},
+		{"write scope is not admin", "/auth.v1.AuthService/ListAuditEvents", readWrite, codes.PermissionDenied},
+		{"admin scope does not imply read", "/auth.v1.AuthService/Me", admin, codes.PermissionDenied},
+		{"admin scope calls admin methods", "/auth.v1.AuthService/ListAuditEvents", admin, codes.OK},
+		{"unlisted method", "/auth.v1.AuthService/Logout", readWrite, codes.PermissionDenied},
+		{"token management needs a login", "/auth.v1.AuthService/CreatePersonalAccessToken", admin, codes.PermissionDenied},
+		{"unknown token", "/auth.v1.AuthService/Me", token.PATPrefix + "0000", codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := authenticatePAT(context.Background(), svc, tt.method, tt.raw)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %v, want %v (err: %v)", got, tt.want, err)
+			}
+			if err == nil && (claims.UserID != user.ID || claims.TokenType != token.TokenTypePAT) {
+				t.Errorf("claims = %+v, want a pat token of %s", claims, user.ID)
+			}
+		})
+	}
+}
+
+func TestAuthenticatePATRejectsInactiveTokens(t *testing.T) {
+	user := &model.User{ID: "user-1", Status: entity.UserStatusActive}
+	revoked := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name string
+		pat  *model.PersonalAccessToken
+		user *model.User
+	}{
+		{"expired", &model.PersonalAccessToken{UserID: user.ID, ExpiresAt: time.Now().Add(-time.Second)}, user},
+		{"revoked", &model.PersonalAccessToken{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revoked}, user},
+		{"owner not active", &model.PersonalAccessToken{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)},
+			&model.User{ID: user.ID, Status: entity.UserStatusPendingApproval}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, _ := token.GeneratePersonalAccessToken()
+			tt.pat.Scopes = []string{auth.ScopeRead}
+			store := &patStore{byHash: map[string]*model.PersonalAccessToken{token.HashPersonalAccessToken(raw): tt.pat}}
+			svc := auth.NewAuthService(&patUsers{user: tt.user}, nil, nil, auth.WithPersonalAccessTokens(store))
+			_, err := authenticatePAT(context.Background(), svc, "/auth.v1.AuthService/Me", raw)
+			if got := status.Code(err); got != codes.Unauthenticated {
+				t.Errorf("code = %v, want %v", got, codes.Unauthenticated)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *AuthServer) UpdateProfile(ctx context.Context, req *authpb.UpdateProfileRequest) (*authpb.UpdateProfileResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthService.UpdateProfile(ctx, claims.UserID, req.Username)
+	switch {
+	case errors.Is(err, service.ErrUsernameNotValid):
+		return nil, status.Error(codes.InvalidArgument, "username must be non-empty and must not contain '@'")
+	case errors.Is(err, repo.ErrUsernameTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return &authpb.UpdateProfileResponse{
+		UserId:   user.GetID(),
+		Username: user.GetUsername(),
+		Email:    user.GetEmail(),
+	}, nil
+}
+
+func (s *AuthServer) ChangeEmail(ctx context.Context, req *authpb.ChangeEmailRequest) (*authpb.ChangeEmailResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.clientLinks(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.AuthService.RequestEmailChange(ctx, claims.UserID, req.NewEmail, links.EmailChange)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, service.ErrEmailInUse) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &authpb.ChangeEmailResponse{
+		Message: "confirmation email sent",
+	}, nil
+}
+
+func (s *AuthServer) ConfirmEmailChange(ctx context.Context, req *authpb.ConfirmEmailChangeRequest) (*authpb.ConfirmEmailChangeResponse, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := s.AuthService.ConfirmEmailChange(ctx, req.Token)
+	var exists *repo.ErrUserExists
+	if errors.As(err, &exists) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "email change failed: %v", err)
+	}
+
+	return &authpb.ConfirmEmailChangeResponse{
+		Message: "email changed",
+	}, nil
+}
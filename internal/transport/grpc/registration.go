@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *AuthServer) ListPendingRegistrations(ctx context.Context, req *authpb.ListPendingRegistrationsRequest) (*authpb.ListPendingRegistrationsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.AuthService.ListPendingRegistrations(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListPendingRegistrationsResponse{}
+	for _, u := range users {
+		resp.Users = append(resp.Users, &authpb.PendingRegistration{
+			UserId:        u.GetID(),
+			Email:         u.GetEmail(),
+			Username:      u.GetUsername(),
+			EmailVerified: u.GetEmailVerified(),
+			CreatedAt:     timestamppb.New(u.GetCreatedAt()),
+		})
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) ApproveRegistration(ctx context.Context, req *authpb.ApproveRegistrationRequest) (*authpb.ApproveRegistrationResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	err = s.AuthService.ApproveRegistration(ctx, claims.OrgID, req.UserId)
+	if errors.Is(err, repo.ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "no pending registration for user")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.ApproveRegistrationResponse{Message: "registration approved"}, nil
+}
+
+func (s *AuthServer) RejectRegistration(ctx context.Context, req *authpb.RejectRegistrationRequest) (*authpb.RejectRegistrationResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	err = s.AuthService.RejectRegistration(ctx, claims.OrgID, req.UserId, req.Reason)
+	if errors.Is(err, repo.ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "no pending registration for user")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.RejectRegistrationResponse{Message: "registration rejected"}, nil
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/provisioning"
+	"github.com/shinoda4/sd-svc-auth/pkg/scim"
+)
+
+const scimContentType = "application/scim+json"
+
+// scimHandler serves the SCIM 2.0 endpoints under /scim/v2. Every request
+// needs the bearer token of a provisioning client.
+func scimHandler(p *provisioning.Service) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /scim/v2/ServiceProviderConfig", func(w http.ResponseWriter, r *http.Request) {
+		writeSCIM(w, http.StatusOK, scimServiceProviderConfig)
+	})
+	mux.HandleFunc("GET /scim/v2/ResourceTypes", func(w http.ResponseWriter, r *http.Request) {
+		writeSCIM(w, http.StatusOK, scimResourceTypes)
+	})
+
+	mux.HandleFunc("GET /scim/v2/Users", func(w http.ResponseWriter, r *http.Request) {
+		filter, start, count, err := scimListParams(r)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		resp, err := p.ListUsers(r.Context(), scimOrg(r), filter, start, count)
+		respondSCIM(w, http.StatusOK, resp, err)
+	})
+	mux.HandleFunc("GET /scim/v2/Users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user, err := p.GetUser(r.Context(), scimOrg(r), r.PathValue("id"))
+		respondSCIM(w, http.StatusOK, user, err)
+	})
+	mux.HandleFunc("POST /scim/v2/Users", func(w http.ResponseWriter, r *http.Request) {
+		in := &provisioning.User{}
+		if err := decodeSCIM(r, in); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		user, err := p.CreateUser(r.Context(), scimOrg(r), in)
+		respondSCIM(w, http.StatusCreated, user, err)
+	})
+	mux.HandleFunc("PUT /scim/v2/Users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		in := &provisioning.User{}
+		if err := decodeSCIM(r, in); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		user, err := p.ReplaceUser(r.Context(), scimOrg(r), r.PathValue("id"), in)
+		respondSCIM(w, http.StatusOK, user, err)
+	})
+	mux.HandleFunc("PATCH /scim/v2/Users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req := &provisioning.PatchRequest{}
+		if err := decodeSCIM(r, req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		user, err := p.PatchUser(r.Context(), scimOrg(r), r.PathValue("id"), req)
+		respondSCIM(w, http.StatusOK, user, err)
+	})
+	mux.HandleFunc("DELETE /scim/v2/Users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := p.DeleteUser(r.Context(), scimOrg(r), r.PathValue("id"))
+		respondSCIM(w, http.StatusNoContent, nil, err)
+	})
+
+	mux.HandleFunc("GET /scim/v2/Groups", func(w http.ResponseWriter, r *http.Request) {
+		filter, start, count, err := scimListParams(r)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		resp, err := p.ListGroups(r.Context(), scimOrg(r), filter, start, count)
+		respondSCIM(w, http.StatusOK, resp, err)
+	})
+	mux.HandleFunc("GET /scim/v2/Groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		group, err := p.GetGroup(r.Context(), scimOrg(r), r.PathValue("id"))
+		respondSCIM(w, http.StatusOK, group, err)
+	})
+	mux.HandleFunc("POST /scim/v2/Groups", func(w http.ResponseWriter, r *http.Request) {
+		in := &provisioning.Group{}
+		if err := decodeSCIM(r, in); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		group, err := p.CreateGroup(r.Context(), scimOrg(r), in)
+		respondSCIM(w, http.StatusCreated, group, err)
+	})
+	mux.HandleFunc("PUT /scim/v2/Groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		in := &provisioning.Group{}
+		if err := decodeSCIM(r, in); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		group, err := p.ReplaceGroup(r.Context(), scimOrg(r), r.PathValue("id"), in)
+		respondSCIM(w, http.StatusOK, group, err)
+	})
+	mux.HandleFunc("PATCH /scim/v2/Groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req := &provisioning.PatchRequest{}
+		if err := decodeSCIM(r, req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		group, err := p.PatchGroup(r.Context(), scimOrg(r), r.PathValue("id"), req)
+		respondSCIM(w, http.StatusOK, group, err)
+	})
+	mux.HandleFunc("DELETE /scim/v2/Groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := p.DeleteGroup(r.Context(), scimOrg(r), r.PathValue("id"))
+		respondSCIM(w, http.StatusNoContent, nil, err)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			service.AuditAlways(r.Context())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, &provisioning.Error{Status: http.StatusUnauthorized, Detail: "missing bearer token"})
+			return
+		}
+		client, err := p.Authenticate(r.Context(), strings.TrimSpace(raw))
+		if err != nil {
+			service.AuditAlways(r.Context())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim", error="invalid_token"`)
+			writeSCIMError(w, &provisioning.Error{Status: http.StatusUnauthorized, Detail: "invalid token"})
+			return
+		}
+		service.AuditActor(r.Context(), client.ID, "scim_client", client.OrgID)
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "scim_org", client.OrgID)))
+	})
+}
+
+// scimAuditAction names SCIM requests "scim_<verb>_<resource>", e.g.
+// "scim_replace_user". Reads are only recorded when they are rejected.
+func scimAuditAction(r *http.Request) (string, bool) {
+	verb := map[string]string{
+		http.MethodPost:   "create",
+		http.MethodPut:    "replace",
+		http.MethodPatch:  "patch",
+		http.MethodDelete: "delete",
+	}[r.Method]
+	if verb == "" {
+		verb = "read"
+	}
+	resource, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/scim/v2/"), "/")
+	resource = strings.ToLower(strings.TrimSuffix(resource, "s"))
+	if resource == "" {
+		resource = "root"
+	}
+	return "scim_" + verb + "_" + resource, verb != "read"
+}
+
+// scimOrg returns the organization of the authenticated client; SCIM
+// clients only ever see their own organization's users and groups.
+func scimOrg(r *http.Request) string {
+	orgID, _ := r.Context().Value("scim_org").(string)
+	return orgID
+}
+
+// scimListParams reads filter, startIndex and count. A missing count is
+// reported as -1 so the service applies its default.
+func scimListParams(r *http.Request) (string, int, int, error) {
+	q := r.URL.Query()
+	start, count := 1, -1
+	if v := q.Get("startIndex"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", 0, 0, &provisioning.Error{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: "startIndex must be an integer"}
+		}
+		start = n
+	}
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", 0, 0, &provisioning.Error{Status: http.StatusBadRequest, ScimType: "invalidValue", Detail: "count must be an integer"}
+		}
+		count = max(n, 0)
+	}
+	return q.Get("filter"), start, count, nil
+}
+
+func decodeSCIM(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &provisioning.Error{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: "malformed JSON body"}
+	}
+	return nil
+}
+
+func respondSCIM(w http.ResponseWriter, code int, body interface{}, err error) {
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+	writeSCIM(w, code, body)
+}
+
+func writeSCIM(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("write scim response: %v", err)
+	}
+}
+
+// writeSCIMError maps service and repository errors onto SCIM error
+// responses.
+func writeSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *provisioning.Error
+	var exists *repo.ErrUserExists
+	switch {
+	case errors.As(err, &scimErr):
+	case errors.Is(err, scim.ErrInvalidFilter):
+		scimErr = &provisioning.Error{Status: http.StatusBadRequest, ScimType: "invalidFilter", Detail: err.Error()}
+	case errors.Is(err, repo.ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		scimErr = &provisioning.Error{Status: http.StatusNotFound, Detail: "resource not found"}
+	case errors.As(err, &exists), errors.Is(err, repo.ErrUsernameTaken),
+		errors.Is(err, repo.ErrExternalIDTaken), errors.Is(err, repo.ErrGroupNameTaken):
+		scimErr = &provisioning.Error{Status: http.StatusConflict, ScimType: "uniqueness", Detail: err.Error()}
+	default:
+		log.Printf("[scim] error: %v", err)
+		scimErr = &provisioning.Error{Status: http.StatusInternalServerError, Detail: "internal error"}
+	}
+
+	body := map[string]interface{}{
+		"schemas": []string{provisioning.SchemaError},
+		"status":  strconv.Itoa(scimErr.Status),
+		"detail":  scimErr.Detail,
+	}
+	if scimErr.ScimType != "" {
+		body["scimType"] = scimErr.ScimType
+	}
+	writeSCIM(w, scimErr.Status, body)
+}
+
+var scimServiceProviderConfig = map[string]interface{}{
+	"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+	"patch":          map[string]bool{"supported": true},
+	"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+	"filter":         map[string]interface{}{"supported": true, "maxResults": provisioning.MaxCount},
+	"changePassword": map[string]bool{"supported": false},
+	"sort":           map[string]bool{"supported": false},
+	"etag":           map[string]bool{"supported": false},
+	"authenticationSchemes": []map[string]interface{}{{
+		"type":        "oauthbearertoken",
+		"name":        "Bearer token",
+		"description": "Per-client token issued by CreateScimClient",
+		"primary":     true,
+	}},
+}
+
+var scimResourceTypes = map[string]interface{}{
+	"schemas":      []string{provisioning.SchemaListResponse},
+	"totalResults": 2,
+	"Resources": []map[string]interface{}{
+		{
+			"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   provisioning.SchemaUser,
+		},
+		{
+			"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   provisioning.SchemaGroup,
+		},
+	},
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toScimClientPB(c *model.ScimClient) *authpb.ScimClient {
+	pb := &authpb.ScimClient{
+		Id:        c.ID,
+		OrgId:     c.OrgID,
+		Name:      c.Name,
+		Prefix:    c.Prefix,
+		CreatedBy: c.CreatedBy,
+		CreatedAt: timestamppb.New(c.CreatedAt),
+	}
+	if c.LastUsedAt != nil {
+		pb.LastUsedAt = timestamppb.New(*c.LastUsedAt)
+	}
+	if c.RevokedAt != nil {
+		pb.RevokedAt = timestamppb.New(*c.RevokedAt)
+	}
+	return pb
+}
+
+func (s *AuthServer) CreateScimClient(ctx context.Context, req *authpb.CreateScimClientRequest) (*authpb.CreateScimClientResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, raw, err := s.Provisioning.CreateClient(ctx, claims.OrgID, req.Name, claims.UserID)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateScimClientResponse{
+		ScimClient: toScimClientPB(c),
+		Token:      raw,
+	}, nil
+}
+
+func (s *AuthServer) ListScimClients(ctx context.Context, req *authpb.ListScimClientsRequest) (*authpb.ListScimClientsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := s.Provisioning.ListClients(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListScimClientsResponse{}
+	for _, c := range clients {
+		resp.ScimClients = append(resp.ScimClients, toScimClientPB(c))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) RevokeScimClient(ctx context.Context, req *authpb.RevokeScimClientRequest) (*authpb.RevokeScimClientResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	err = s.Provisioning.RevokeClient(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "scim client not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.RevokeScimClientResponse{Message: "scim client revoked"}, nil
+}
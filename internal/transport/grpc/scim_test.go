@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/service/entity"
+	"github.com/shinoda4/sd-svc-auth/internal/service/provisioning"
+)
+
+// scimClients keeps provisioning clients in memory, keyed by token hash.
+type scimClients struct {
+	entity.ScimClientRepository
+	byHash map[string]*model.ScimClient
+}
+
+func (f *scimClients) CreateScimClient(_ context.Context, c *model.ScimClient) (*model.ScimClient, error) {
+	c.ID = "client-" + c.Prefix
+	f.byHash[c.TokenHash] = c
+	return c, nil
+}
+
+func (f *scimClients) GetScimClientByHash(_ context.Context, hash string) (*model.ScimClient, error) {
+	c, ok := f.byHash[hash]
+	if !ok || c.RevokedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f *scimClients) RevokeScimClient(_ context.Context, orgID, id string) error {
+	for _, c := range f.byHash {
+		if c.ID == id && c.OrgID == orgID {
+			now := time.Now()
+			c.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (f *scimClients) TouchScimClient(context.Context, string) error { return nil }
+
+func TestSCIMBearerAuth(t *testing.T) {
+	clients := &scimClients{byHash: map[string]*model.ScimClient{}}
+	p := provisioning.NewService(nil, nil, clients, nil, nil, nil, "https://auth.example.com/scim/v2")
+	ctx := context.Background()
+	_, valid, err := p.CreateClient(ctx, "org-1", "okta", "admin-1")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	revokedClient, revoked, err := p.CreateClient(ctx, "org-1", "old", "admin-1")
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	if err := p.RevokeClient(ctx, "org-1", revokedClient.ID); err != nil {
+		t.Fatalf("RevokeClient: %v", err)
+	}
+
+	srv := httptest.NewServer(scimHandler(p))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{"missing", "", http.StatusUnauthorized, `Bearer realm="scim"`},
+		{"basic scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, `Bearer realm="scim"`},
+		{"malformed", "Bearer not-a-scim-token", http.StatusUnauthorized, `Bearer realm="scim", error="invalid_token"`},
+		{"unknown", "Bearer " + valid + "0", http.StatusUnauthorized, `Bearer realm="scim", error="invalid_token"`},
+		{"revoked", "Bearer " + revoked, http.StatusUnauthorized, `Bearer realm="scim", error="invalid_token"`},
+		{"valid", "Bearer " + valid, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/scim/v2/ServiceProviderConfig", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Fatalf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != scimContentType {
+				t.Fatalf("Content-Type = %q", ct)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var body map[string]interface{}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("decode error body: %v", err)
+				}
+				if body["status"] != "401" {
+					t.Fatalf("error body = %v", body)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/scim/v2/Users?filter="+url.QueryEscape(`userName eq`), nil)
+		req.Header.Set("Authorization", "Bearer "+valid)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != http.StatusBadRequest || body["scimType"] != "invalidFilter" {
+			t.Fatalf("status %d body %v, want 400 invalidFilter", resp.StatusCode, body)
+		}
+	})
+}
+
+func TestSCIMListParams(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantFilter string
+		wantStart  int
+		wantCount  int
+		wantErr    bool
+	}{
+		{"", "", 1, -1, false},
+		{"startIndex=3&count=20", "", 3, 20, false},
+		{"count=-5", "", 1, 0, false},
+		{"filter=" + url.QueryEscape(`userName eq "bjensen"`), `userName eq "bjensen"`, 1, -1, false},
+		{"startIndex=abc", "", 0, 0, true},
+		{"count=1.5", "", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scim/v2/Users?"+tt.query, nil)
+			filter, start, count, err := scimListParams(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil || filter != tt.wantFilter || start != tt.wantStart || count != tt.wantCount {
+				t.Fatalf("scimListParams = %q, %d, %d, %v", filter, start, count, err)
+			}
+		})
+	}
+}
+
+func TestSCIMAuditAction(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+		wantRecord   bool
+	}{
+		{http.MethodPost, "/scim/v2/Users", "scim_create_user", true},
+		{http.MethodPut, "/scim/v2/Users/42", "scim_replace_user", true},
+		{http.MethodPatch, "/scim/v2/Groups/42", "scim_patch_group", true},
+		{http.MethodDelete, "/scim/v2/Groups/42", "scim_delete_group", true},
+		{http.MethodGet, "/scim/v2/Users", "scim_read_user", false},
+		{http.MethodGet, "/scim/v2/ServiceProviderConfig", "scim_read_serviceproviderconfig", false},
+		{http.MethodGet, "/scim/v2/", "scim_read_root", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			got, record := scimAuditAction(httptest.NewRequest(tt.method, tt.path, strings.NewReader("")))
+			if got != tt.want || record != tt.wantRecord {
+				t.Fatalf("scimAuditAction = %q, %v, want %q, %v", got, record, tt.want, tt.wantRecord)
+			}
+		})
+	}
+}
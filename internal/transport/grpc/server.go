@@ -22,12 +22,21 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/service"
+	"github.com/shinoda4/sd-svc-auth/internal/service/audit"
 	"github.com/shinoda4/sd-svc-auth/internal/service/auth"
+	"github.com/shinoda4/sd-svc-auth/internal/service/clientapp"
+	"github.com/shinoda4/sd-svc-auth/internal/service/emaildomain"
+	"github.com/shinoda4/sd-svc-auth/internal/service/ippolicy"
+	"github.com/shinoda4/sd-svc-auth/internal/service/provisioning"
+	"github.com/shinoda4/sd-svc-auth/internal/service/serviceaccount"
+	"github.com/shinoda4/sd-svc-auth/internal/service/webhook"
 	"github.com/shinoda4/sd-svc-auth/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -36,7 +45,7 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func RunGRPCServer(authService *auth.Service) {
+func RunGRPCServer(server *AuthServer, trustedProxies []netip.Prefix) {
 	lis, err := net.Listen("tcp", ":"+os.Getenv("GRPC_PORT"))
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
@@ -57,10 +66,14 @@ func RunGRPCServer(authService *auth.Service) {
 				}
 				return resp, err
 			},
-			AuthInterceptor(authService), // 认证 interceptor
+			AuditInterceptor(server.Audit, trustedProxies),         // 审计日志
+			IPPolicyInterceptor(server.IPPolicies, trustedProxies), // IP 访问策略
+			LocaleInterceptor(),                       // 邮件语言
+			AuthInterceptor(server.AuthService),       // 认证 interceptor
+			OrgIPPolicyInterceptor(server.IPPolicies), // 组织 IP 访问策略
 		),
 	)
-	authpb.RegisterAuthServiceServer(grpcServer, NewAuthServer(authService))
+	authpb.RegisterAuthServiceServer(grpcServer, server)
 
 	log.Printf("gRPC server running on %s", os.Getenv("GRPC_PORT"))
 	if err := grpcServer.Serve(lis); err != nil {
@@ -68,7 +81,7 @@ func RunGRPCServer(authService *auth.Service) {
 	}
 }
 
-func RunGateway() {
+func RunGateway(serviceAccounts *serviceaccount.Service, provisioner *provisioning.Service, auditLog *audit.Service, trustedProxies []netip.Prefix) {
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -85,12 +98,22 @@ func RunGateway() {
 		log.Fatalf("failed to start HTTP gateway: %v", err)
 	}
 
+	// OAuth2 and SCIM endpoints are plain HTTP, not gateway routes
+	root := http.NewServeMux()
+	root.Handle("/oauth2/token", auditHTTP(auditLog, trustedProxies, oauth2AuditAction, oauth2TokenHandler(serviceAccounts)))
+	root.Handle("/scim/v2/", auditHTTP(auditLog, trustedProxies, scimAuditAction, scimHandler(provisioner)))
+	root.Handle("/", mux)
+
 	httpAddr := fmt.Sprintf(":%s", os.Getenv("HTTP_PORT"))
 	log.Printf("HTTP gateway running on %s", httpAddr)
-	if err := http.ListenAndServe(httpAddr, mux); err != nil {
+	if err := http.ListenAndServe(httpAddr, root); err != nil {
 		log.Fatalf("failed to serve HTTP gateway: %v", err)
 	}
 }
+
+// refreshTokenMethod is the only method that accepts refresh tokens.
+const refreshTokenMethod = "/auth.v1.AuthService/RefreshToken"
+
 func AuthInterceptor(authService *auth.Service) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
@@ -101,12 +124,15 @@ func AuthInterceptor(authService *auth.Service) grpc.UnaryServerInterceptor {
 
 		// 白名单，不需要认证的 API
 		noAuthMethods := map[string]bool{
-			"/auth.v1.AuthService/HealthCheck":    true,
-			"/auth.v1.AuthService/Register":       true,
-			"/auth.v1.AuthService/VerifyEmail":    true,
-			"/auth.v1.AuthService/Login":          true,
-			"/auth.v1.AuthService/ForgotPassword": true,
-			"/auth.v1.AuthService/ResetPassword":  true,
+			"/auth.v1.AuthService/HealthCheck":        true,
+			"/auth.v1.AuthService/Register":           true,
+			"/auth.v1.AuthService/VerifyEmail":        true,
+			"/auth.v1.AuthService/ResendVerification": true,
+			"/auth.v1.AuthService/Login":              true,
+			"/auth.v1.AuthService/ForgotPassword":     true,
+			"/auth.v1.AuthService/ResetPassword":      true,
+			"/auth.v1.AuthService/ConfirmEmailChange": true,
+			"/auth.v1.AuthService/AcceptInvitation":   true,
 		}
 
 		if noAuthMethods[info.FullMethod] {
@@ -127,13 +153,46 @@ func AuthInterceptor(authService *auth.Service) grpc.UnaryServerInterceptor {
 		rawToken := strings.TrimPrefix(authHeaders[0], "Bearer ")
 		rawToken = strings.TrimSpace(rawToken)
 
-		claims, err := token.ParseToken(rawToken)
-		if err != nil {
-			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		var (
+			claims *token.Claims
+			err    error
+		)
+		if token.IsPersonalAccessToken(rawToken) {
+			claims, err = authenticatePAT(ctx, authService, info.FullMethod, rawToken)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			// refresh token 只能用于 RefreshToken
+			if info.FullMethod == refreshTokenMethod {
+				claims, err = token.ParseAndValidateRefresh(rawToken)
+			} else {
+				claims, err = token.ParseAndValidate(rawToken)
+			}
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			// service account tokens are meant for other services; here
+			// they may only introspect themselves
+			if claims.TokenType == token.TokenTypeClient && info.FullMethod != "/auth.v1.AuthService/ValidateToken" {
+				return nil, status.Error(codes.PermissionDenied, "method not available to client tokens")
+			}
+			if authService.TokenRevoked(ctx, claims) {
+				return nil, status.Error(codes.Unauthenticated, "token revoked")
+			}
+		}
+
+		// 代登录 (impersonation) token: 敏感操作禁止，每次调用都记审计日志
+		if claims.Act != nil {
+			if impersonationBlockedMethods[info.FullMethod] {
+				return nil, status.Error(codes.PermissionDenied, "method not available while impersonating")
+			}
+			service.AuditAlways(ctx)
 		}
 
 		ctx = context.WithValue(ctx, "claims", claims)
 		ctx = context.WithValue(ctx, "raw_token", rawToken)
+		noteActor(ctx, claims)
 
 		return handler(ctx, req)
 	}
@@ -141,9 +200,25 @@ func AuthInterceptor(authService *auth.Service) grpc.UnaryServerInterceptor {
 
 type AuthServer struct {
 	authpb.UnimplementedAuthServiceServer
-	AuthService *auth.Service
+	AuthService     *auth.Service
+	IPPolicies      *ippolicy.Service
+	EmailDomains    *emaildomain.Checker
+	ServiceAccounts *serviceaccount.Service
+	Provisioning    *provisioning.Service
+	ClientApps      *clientapp.Service
+	Webhooks        *webhook.Service
+	Audit           *audit.Service
 }
 
-func NewAuthServer(authService *auth.Service) *AuthServer {
-	return &AuthServer{AuthService: authService}
+func NewAuthServer(authService *auth.Service, ipPolicies *ippolicy.Service, emailDomains *emaildomain.Checker, serviceAccounts *serviceaccount.Service, provisioner *provisioning.Service, clientApps *clientapp.Service, webhooks *webhook.Service, auditLog *audit.Service) *AuthServer {
+	return &AuthServer{
+		AuthService:     authService,
+		IPPolicies:      ipPolicies,
+		EmailDomains:    emailDomains,
+		ServiceAccounts: serviceAccounts,
+		Provisioning:    provisioner,
+		ClientApps:      clientApps,
+		Webhooks:        webhooks,
+		Audit:           auditLog,
+	}
 }
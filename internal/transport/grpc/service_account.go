@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toServiceAccountPB(sa *model.ServiceAccount) *authpb.ServiceAccount {
+	authMethod := "client_secret"
+	if sa.PublicKey != "" {
+		authMethod = "private_key_jwt"
+	}
+	pb := &authpb.ServiceAccount{
+		Id:         sa.ID,
+		OrgId:      sa.OrgID,
+		ClientId:   sa.ClientID,
+		Name:       sa.Name,
+		AuthMethod: authMethod,
+		Scopes:     sa.Scopes,
+		Audiences:  sa.Audiences,
+		CreatedBy:  sa.CreatedBy,
+		CreatedAt:  timestamppb.New(sa.CreatedAt),
+	}
+	if sa.DisabledAt != nil {
+		pb.DisabledAt = timestamppb.New(*sa.DisabledAt)
+	}
+	return pb
+}
+
+func (s *AuthServer) CreateServiceAccount(ctx context.Context, req *authpb.CreateServiceAccountRequest) (*authpb.CreateServiceAccountResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sa, secret, err := s.ServiceAccounts.Create(ctx, claims.OrgID, req.Name, req.Scopes, req.Audiences, req.PublicKey, claims.UserID)
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateServiceAccountResponse{
+		ServiceAccount: toServiceAccountPB(sa),
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (s *AuthServer) ListServiceAccounts(ctx context.Context, req *authpb.ListServiceAccountsRequest) (*authpb.ListServiceAccountsResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.ServiceAccounts.List(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListServiceAccountsResponse{}
+	for _, sa := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, toServiceAccountPB(sa))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) DisableServiceAccount(ctx context.Context, req *authpb.DisableServiceAccountRequest) (*authpb.DisableServiceAccountResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	err = s.ServiceAccounts.Disable(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "service account not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.DisableServiceAccountResponse{Message: "service account disabled"}, nil
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	authpb "github.com/shinoda4/sd-grpc-proto/proto/auth/v1"
+	"github.com/shinoda4/sd-svc-auth/internal/model"
+	"github.com/shinoda4/sd-svc-auth/internal/repo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toWebhookPB(sub *model.WebhookSubscription) *authpb.Webhook {
+	return &authpb.Webhook{
+		Id:          sub.ID,
+		Url:         sub.URL,
+		Events:      sub.Events,
+		Description: sub.Description,
+		Disabled:    sub.Disabled,
+		CreatedBy:   sub.CreatedBy,
+		CreatedAt:   timestamppb.New(sub.CreatedAt),
+		UpdatedAt:   timestamppb.New(sub.UpdatedAt),
+	}
+}
+
+func toWebhookDeliveryPB(d *model.WebhookDelivery) *authpb.WebhookDelivery {
+	pb := &authpb.WebhookDelivery{
+		Id:             d.ID,
+		WebhookId:      d.SubscriptionID,
+		EventId:        d.EventID,
+		Event:          d.Event,
+		Payload:        d.Payload.String(),
+		Status:         d.Status,
+		Attempts:       int32(d.Attempts),
+		ResponseStatus: int32(d.ResponseStatus),
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+	}
+	if d.DeliveredAt != nil {
+		pb.DeliveredAt = timestamppb.New(*d.DeliveredAt)
+	}
+	return pb
+}
+
+func (s *AuthServer) CreateWebhook(ctx context.Context, req *authpb.CreateWebhookRequest) (*authpb.CreateWebhookResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.Webhooks.Create(ctx, &model.WebhookSubscription{
+		OrgID:       claims.OrgID,
+		URL:         req.Url,
+		Events:      req.Events,
+		Description: req.Description,
+		CreatedBy:   claims.UserID,
+	})
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.CreateWebhookResponse{Webhook: toWebhookPB(sub), Secret: sub.Secret}, nil
+}
+
+func (s *AuthServer) ListWebhooks(ctx context.Context, req *authpb.ListWebhooksRequest) (*authpb.ListWebhooksResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.Webhooks.List(ctx, claims.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListWebhooksResponse{}
+	for _, sub := range subs {
+		resp.Webhooks = append(resp.Webhooks, toWebhookPB(sub))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) UpdateWebhook(ctx context.Context, req *authpb.UpdateWebhookRequest) (*authpb.UpdateWebhookResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "webhook id is required")
+	}
+
+	sub, err := s.Webhooks.Update(ctx, &model.WebhookSubscription{
+		ID:          req.Id,
+		OrgID:       claims.OrgID,
+		URL:         req.Url,
+		Events:      req.Events,
+		Description: req.Description,
+		Disabled:    req.Disabled,
+	})
+	if st, ok := fieldErrorStatus(err); ok {
+		return nil, st
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.UpdateWebhookResponse{Webhook: toWebhookPB(sub)}, nil
+}
+
+func (s *AuthServer) DeleteWebhook(ctx context.Context, req *authpb.DeleteWebhookRequest) (*authpb.DeleteWebhookResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Webhooks.Delete(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.DeleteWebhookResponse{Message: "webhook deleted"}, nil
+}
+
+func (s *AuthServer) ListWebhookDeliveries(ctx context.Context, req *authpb.ListWebhookDeliveriesRequest) (*authpb.ListWebhookDeliveriesResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.WebhookId == "" {
+		return nil, status.Error(codes.InvalidArgument, "webhook id is required")
+	}
+
+	deliveries, err := s.Webhooks.Deliveries(ctx, claims.OrgID, req.WebhookId, int(req.Limit))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authpb.ListWebhookDeliveriesResponse{}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, toWebhookDeliveryPB(d))
+	}
+	return resp, nil
+}
+
+func (s *AuthServer) ReplayWebhookDelivery(ctx context.Context, req *authpb.ReplayWebhookDeliveryRequest) (*authpb.ReplayWebhookDeliveryResponse, error) {
+	claims, err := requireOrgAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "delivery id is required")
+	}
+
+	d, err := s.Webhooks.Replay(ctx, claims.OrgID, req.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "delivery not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authpb.ReplayWebhookDeliveryResponse{Delivery: toWebhookDeliveryPB(d)}, nil
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package emailstub is a local stand-in for a transactional email
+// provider's HTTP API. It accepts the payload shapes of email.HTTPMailer,
+// checks API keys and signatures, answers repeated idempotency keys
+// without recording the message twice, and can be told to fail, so the
+// HTTP mailer can be exercised without an account at a real provider.
+package emailstub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/email"
+)
+
+// Handler serves the stub API on any path.
+type Handler struct {
+	// APIKey, when set, must be sent as a Bearer token or
+	// X-Postmark-Server-Token.
+	APIKey string
+	// SigningSecret, when set, requires a valid request signature.
+	SigningSecret string
+	// OnMessage, when set, is called for every newly accepted message.
+	OnMessage func(msg email.Message)
+
+	mu       sync.Mutex
+	messages []email.Message
+	seen     map[string]bool
+	requests int
+	failNext int
+	failCode int
+}
+
+// Server is a Handler running on an httptest server.
+type Server struct {
+	*Handler
+	*httptest.Server
+}
+
+// NewServer starts a stub on a random local port. Close it when done.
+func NewServer(apiKey, signingSecret string) *Server {
+	h := &Handler{APIKey: apiKey, SigningSecret: signingSecret}
+	return &Server{Handler: h, Server: httptest.NewServer(h)}
+}
+
+// FailNext makes the next n requests fail with status, e.g. 503 or 429.
+func (h *Handler) FailNext(n, status int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failNext, h.failCode = n, status
+}
+
+// Messages returns the messages accepted so far, oldest first.
+func (h *Handler) Messages() []email.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]email.Message(nil), h.messages...)
+}
+
+// Requests returns how many requests were received, including failed and
+// repeated ones.
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, `{"error":"read body"}`, http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests++
+
+	if h.failNext > 0 {
+		h.failNext--
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, `{"error":"injected failure"}`, h.failCode)
+		return
+	}
+	if h.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+h.APIKey && r.Header.Get("X-Postmark-Server-Token") != h.APIKey {
+		http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+		return
+	}
+	if h.SigningSecret != "" && !email.VerifySignature(h.SigningSecret,
+		r.Header.Get(email.HeaderTimestamp), r.Header.Get(email.HeaderSignature), body, 5*time.Minute) {
+		http.Error(w, `{"error":"invalid signature"}`, http.StatusUnauthorized)
+		return
+	}
+
+	msg, ok := decode(body)
+	if !ok {
+		http.Error(w, `{"error":"unrecognised payload"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// 同一个 idempotency key 只记录一次，重复请求照常返回成功
+	key := r.Header.Get(email.HeaderIdempotencyKey)
+	msg.ID = key
+	if key == "" || !h.seen[key] {
+		if h.seen == nil {
+			h.seen = make(map[string]bool)
+		}
+		h.seen[key] = true
+		h.messages = append(h.messages, msg)
+		if h.OnMessage != nil {
+			h.OnMessage(msg)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": key, "status": "queued"})
+}
+
+// decode reads either payload shape back into a message.
+func decode(body []byte) (email.Message, bool) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return email.Message{}, false
+	}
+
+	if _, ok := probe["personalizations"]; ok {
+		var p email.SendGridMessage
+		if err := json.Unmarshal(body, &p); err != nil || len(p.Personalizations) == 0 || len(p.Personalizations[0].To) == 0 {
+			return email.Message{}, false
+		}
+		msg := email.Message{To: p.Personalizations[0].To[0].Email, Subject: p.Subject}
+		for _, c := range p.Content {
+			switch c.Type {
+			case "text/plain":
+				msg.Text = c.Value
+			case "text/html":
+				msg.HTML = c.Value
+			}
+		}
+		return msg, true
+	}
+
+	if _, ok := probe["From"]; ok {
+		var p email.PostmarkMessage
+		if err := json.Unmarshal(body, &p); err != nil || p.To == "" {
+			return email.Message{}, false
+		}
+		return email.Message{To: p.To, Subject: p.Subject, Text: p.TextBody, HTML: p.HtmlBody}, true
+	}
+	return email.Message{}, false
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shinoda4/sd-svc-auth/pkg/signature"
+)
+
+// Headers set on every request of the HTTP mailer.
+const (
+	// HeaderIdempotencyKey is the same for every attempt at one message.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderTimestamp and HeaderSignature carry the request signature when
+	// a signing secret is configured, see SignPayload.
+	HeaderTimestamp = "X-Mail-Timestamp"
+	HeaderSignature = "X-Mail-Signature"
+)
+
+type HTTPConfig struct {
+	// URL is the provider's send endpoint.
+	URL string
+	// Format is the payload shape, HTTPFormatSendGrid or
+	// HTTPFormatPostmark.
+	Format string
+	APIKey string
+	// SigningSecret, when set, signs every request with HMAC-SHA256.
+	SigningSecret string
+	From          mail.Address
+	// MaxRetries is how often a request answered with 429 or 5xx is
+	// repeated before Send gives up (default 3).
+	MaxRetries int
+	Client     *http.Client
+}
+
+// HTTPMailer sends through a transactional email provider's HTTP API.
+type HTTPMailer struct {
+	cfg    HTTPConfig
+	format httpFormat
+	client *http.Client
+	// retryDelay is the wait before the first retry; it doubles after
+	// every further attempt.
+	retryDelay time.Duration
+}
+
+// HTTPError is a response the provider did not accept.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+
+	retryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("mail api returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the request may succeed when repeated.
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+func NewHTTPMailer(cfg HTTPConfig) (*HTTPMailer, error) {
+	format, ok := httpFormats[cfg.Format]
+	if !ok {
+		return nil, fmt.Errorf("unknown mail api format %q", cfg.Format)
+	}
+	if cfg.URL == "" {
+		return nil, errors.New("mail api url is required")
+	}
+	if cfg.From.Address == "" {
+		return nil, errors.New("sender address is required")
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &HTTPMailer{cfg: cfg, format: format, client: client, retryDelay: 500 * time.Millisecond}, nil
+}
+
+func (m *HTTPMailer) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(m.format.payload(m.cfg.From, msg))
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	// 同一封邮件的所有重试共用一个 key，服务商据此去重
+	key := msg.ID
+	if key == "" {
+		key = randomKey()
+	}
+
+	delay := m.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := m.post(ctx, body, key)
+		var httpErr *HTTPError
+		if err == nil || attempt >= m.cfg.MaxRetries || (errors.As(err, &httpErr) && !httpErr.Temporary()) {
+			return err
+		}
+
+		wait := delay
+		if httpErr != nil && httpErr.retryAfter > 0 {
+			wait = httpErr.retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func (m *HTTPMailer) post(ctx context.Context, body []byte, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(HeaderIdempotencyKey, key)
+	if m.cfg.APIKey != "" {
+		m.format.authorize(req, m.cfg.APIKey)
+	}
+	if m.cfg.SigningSecret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, SignPayload(m.cfg.SigningSecret, ts, body))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post mail api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		httpErr.retryAfter = min(time.Duration(secs)*time.Second, 30*time.Second)
+	}
+	return httpErr
+}
+
+// SignPayload returns the signature header value for body sent at the
+// Unix timestamp ts, see package signature.
+func SignPayload(secret, ts string, body []byte) string {
+	return signature.Sign(secret, ts, body)
+}
+
+// VerifySignature checks a signature made by SignPayload and rejects
+// timestamps further than maxSkew from now.
+func VerifySignature(secret, ts, sig string, body []byte, maxSkew time.Duration) bool {
+	return signature.Verify(secret, ts, sig, body, maxSkew)
+}
+
+func randomKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
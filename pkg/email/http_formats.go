@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"net/http"
+	"net/mail"
+)
+
+// Payload shapes understood by HTTPMailer.
+const (
+	// HTTPFormatSendGrid is the v3 mail/send shape: personalizations, a
+	// from object and a content list. The API key is a Bearer token.
+	HTTPFormatSendGrid = "sendgrid"
+	// HTTPFormatPostmark is the flat From/To/TextBody/HtmlBody shape. The
+	// API key goes into X-Postmark-Server-Token.
+	HTTPFormatPostmark = "postmark"
+)
+
+type httpFormat struct {
+	payload   func(from mail.Address, msg *Message) interface{}
+	authorize func(req *http.Request, apiKey string)
+}
+
+var httpFormats = map[string]httpFormat{
+	HTTPFormatSendGrid: {payload: sendGridPayload, authorize: bearerAuth},
+	HTTPFormatPostmark: {payload: postmarkPayload, authorize: postmarkAuth},
+}
+
+type SendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type SendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type SendGridPersonalization struct {
+	To []SendGridAddress `json:"to"`
+}
+
+// SendGridMessage is the body of an HTTPFormatSendGrid request.
+type SendGridMessage struct {
+	Personalizations []SendGridPersonalization `json:"personalizations"`
+	From             SendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []SendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+func sendGridPayload(from mail.Address, msg *Message) interface{} {
+	p := SendGridMessage{
+		Personalizations: []SendGridPersonalization{{To: []SendGridAddress{{Email: msg.To}}}},
+		From:             SendGridAddress{Email: from.Address, Name: from.Name},
+		Subject:          msg.Subject,
+	}
+	// text/plain 必须排在 text/html 前面
+	if msg.Text != "" {
+		p.Content = append(p.Content, SendGridContent{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		p.Content = append(p.Content, SendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+	if msg.ID != "" {
+		p.Headers = map[string]string{"X-Message-Ref": msg.ID}
+	}
+	return p
+}
+
+func bearerAuth(req *http.Request, apiKey string) {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+}
+
+type PostmarkHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// PostmarkMessage is the body of an HTTPFormatPostmark request.
+type PostmarkMessage struct {
+	From     string           `json:"From"`
+	To       string           `json:"To"`
+	Subject  string           `json:"Subject"`
+	TextBody string           `json:"TextBody,omitempty"`
+	HtmlBody string           `json:"HtmlBody,omitempty"`
+	Headers  []PostmarkHeader `json:"Headers,omitempty"`
+}
+
+func postmarkPayload(from mail.Address, msg *Message) interface{} {
+	p := PostmarkMessage{
+		From:     from.String(),
+		To:       msg.To,
+		Subject:  msg.Subject,
+		TextBody: msg.Text,
+		HtmlBody: msg.HTML,
+	}
+	if msg.ID != "" {
+		p.Headers = []PostmarkHeader{{Name: "X-Message-Ref", Value: msg.ID}}
+	}
+	return p
+}
+
+func postmarkAuth(req *http.Request, apiKey string) {
+	req.Header.Set("X-Postmark-Server-Token", apiKey)
+}
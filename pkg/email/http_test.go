@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedRequest is what the test server saw of one request.
+type recordedRequest struct {
+	method string
+	header http.Header
+	body   []byte
+}
+
+// mailAPI starts a server that records every request and answers the n-th
+// one (0-based) with respond(n, w).
+func mailAPI(t *testing.T, respond func(n int, w http.ResponseWriter)) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []recordedRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		n := len(requests)
+		requests = append(requests, recordedRequest{method: r.Method, header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		respond(n, w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func newTestMailer(t *testing.T, cfg HTTPConfig) *HTTPMailer {
+	t.Helper()
+	if cfg.From.Address == "" {
+		cfg.From = mail.Address{Name: "Auth", Address: "noreply@example.com"}
+	}
+	m, err := NewHTTPMailer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.retryDelay = time.Millisecond
+	return m
+}
+
+var testMessage = &Message{
+	ID:      "msg-1",
+	To:      "alice@example.com",
+	Subject: "Verify your email",
+	Text:    "plain body",
+	HTML:    "<p>html body</p>",
+}
+
+func TestHTTPMailerSendGrid(t *testing.T) {
+	srv, requests := mailAPI(t, func(int, http.ResponseWriter) {})
+	m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatSendGrid, APIKey: "sg-key", SigningSecret: "s3cret"})
+
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.method)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":       "application/json",
+		"Authorization":      "Bearer sg-key",
+		HeaderIdempotencyKey: "msg-1",
+	} {
+		if got := req.header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if req.header.Get("X-Postmark-Server-Token") != "" {
+		t.Error("sendgrid request carries a postmark token")
+	}
+	if !VerifySignature("s3cret", req.header.Get(HeaderTimestamp), req.header.Get(HeaderSignature), req.body, time.Minute) {
+		t.Error("signature does not verify")
+	}
+
+	var got SendGridMessage
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := SendGridMessage{
+		Personalizations: []SendGridPersonalization{{To: []SendGridAddress{{Email: "alice@example.com"}}}},
+		From:             SendGridAddress{Email: "noreply@example.com", Name: "Auth"},
+		Subject:          "Verify your email",
+		Content: []SendGridContent{
+			{Type: "text/plain", Value: "plain body"},
+			{Type: "text/html", Value: "<p>html body</p>"},
+		},
+		Headers: map[string]string{"X-Message-Ref": "msg-1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+}
+
+func TestHTTPMailerPostmark(t *testing.T) {
+	srv, requests := mailAPI(t, func(int, http.ResponseWriter) {})
+	m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatPostmark, APIKey: "pm-key"})
+
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if got := req.header.Get("X-Postmark-Server-Token"); got != "pm-key" {
+		t.Errorf("X-Postmark-Server-Token = %q, want pm-key", got)
+	}
+	if got := req.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+	if req.header.Get(HeaderSignature) != "" || req.header.Get(HeaderTimestamp) != "" {
+		t.Error("request signed without a signing secret")
+	}
+
+	var got PostmarkMessage
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := PostmarkMessage{
+		From:     `"Auth" <noreply@example.com>`,
+		To:       "alice@example.com",
+		Subject:  "Verify your email",
+		TextBody: "plain body",
+		HtmlBody: "<p>html body</p>",
+		Headers:  []PostmarkHeader{{Name: "X-Message-Ref", Value: "msg-1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+}
+
+func TestHTTPMailerRetriesTemporaryErrors(t *testing.T) {
+	srv, requests := mailAPI(t, func(n int, w http.ResponseWriter) {
+		switch n {
+		case 0:
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 1:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	})
+	m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatSendGrid})
+
+	// 没有 ID 的邮件使用随机 key，但所有重试必须相同
+	msg := *testMessage
+	msg.ID = ""
+	if err := m.Send(context.Background(), &msg); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	key := reqs[0].header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		t.Fatal("no idempotency key")
+	}
+	for i, req := range reqs {
+		if got := req.header.Get(HeaderIdempotencyKey); got != key {
+			t.Errorf("attempt %d: idempotency key %q, want %q", i, got, key)
+		}
+		if string(req.body) != string(reqs[0].body) {
+			t.Errorf("attempt %d: body changed between retries", i)
+		}
+	}
+}
+
+func TestHTTPMailerGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		attempts  int
+		temporary bool
+	}{
+		{"server error is retried", http.StatusInternalServerError, 3, true},
+		{"timeout is retried", http.StatusRequestTimeout, 3, true},
+		{"bad request is permanent", http.StatusBadRequest, 1, false},
+		{"unauthorized is permanent", http.StatusUnauthorized, 1, false},
+		{"redirect is not success", http.StatusMovedPermanently, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := mailAPI(t, func(_ int, w http.ResponseWriter) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, "  rejected\n")
+			})
+			m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatPostmark, MaxRetries: 2})
+
+			err := m.Send(context.Background(), testMessage)
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("err = %v, want *HTTPError", err)
+			}
+			if httpErr.StatusCode != tt.status || httpErr.Body != "rejected" {
+				t.Errorf("err = %d %q, want %d %q", httpErr.StatusCode, httpErr.Body, tt.status, "rejected")
+			}
+			if httpErr.Temporary() != tt.temporary {
+				t.Errorf("Temporary() = %v, want %v", httpErr.Temporary(), tt.temporary)
+			}
+			if n := len(requests()); n != tt.attempts {
+				t.Errorf("got %d requests, want %d", n, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestHTTPMailerRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"2", 2 * time.Second},
+		{"3600", 30 * time.Second}, // capped
+		{"soon", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		srv, _ := mailAPI(t, func(_ int, w http.ResponseWriter) {
+			if tt.header != "" {
+				w.Header().Set("Retry-After", tt.header)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatSendGrid})
+
+		var httpErr *HTTPError
+		if err := m.post(context.Background(), []byte(`{}`), "key"); !errors.As(err, &httpErr) {
+			t.Fatalf("Retry-After %q: err = %v, want *HTTPError", tt.header, err)
+		}
+		if httpErr.retryAfter != tt.want {
+			t.Errorf("Retry-After %q: wait %v, want %v", tt.header, httpErr.retryAfter, tt.want)
+		}
+	}
+}
+
+func TestHTTPMailerStopsWaitingOnCancel(t *testing.T) {
+	srv, requests := mailAPI(t, func(_ int, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	m := newTestMailer(t, HTTPConfig{URL: srv.URL, Format: HTTPFormatSendGrid})
+	m.retryDelay = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := m.Send(ctx, testMessage)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want the 503 response", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send returned after %v, want it to stop at the deadline", elapsed)
+	}
+	if n := len(requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
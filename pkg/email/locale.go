@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// normalizeLocale turns "pt_BR" or "PT-br" into "pt-br".
+func normalizeLocale(l string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(l), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header,
+// most preferred first. Wildcards and entries with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type entry struct {
+		locale string
+		q      float64
+	}
+	var entries []entry
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := normalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || k != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		entries = append(entries, entry{locale, q})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+
+	locales := make([]string, len(entries))
+	for i, e := range entries {
+		locales[i] = e.locale
+	}
+	return locales
+}
+
+// PreferredLocales builds a preference list for Render from an explicit locale,
+// which wins when set, followed by the locales of an Accept-Language
+// header.
+func PreferredLocales(preferred, acceptLanguage string) []string {
+	var locales []string
+	if l := normalizeLocale(preferred); l != "" {
+		locales = append(locales, l)
+	}
+	return append(locales, ParseAcceptLanguage(acceptLanguage)...)
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"context"
+	"log"
+	"net/mail"
+)
+
+// LogMailer writes messages to the log instead of sending them.
+type LogMailer struct {
+	from mail.Address
+}
+
+func NewLogMailer(from mail.Address) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	log.Printf("[mail] from=%s to=%s subject=%q\n%s", m.from.String(), msg.To, msg.Subject, body)
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaildirMailer stores every message as a file in a maildir, so mail
+// clients like mutt can read them.
+type MaildirMailer struct {
+	dir  string
+	from mail.Address
+	host string
+}
+
+// NewMaildirMailer creates dir with its tmp, new and cur subdirectories
+// if they are missing.
+func NewMaildirMailer(dir string, from mail.Address) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return &MaildirMailer{dir: dir, from: from, host: host}, nil
+}
+
+func (m *MaildirMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.encode(m.from)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	// 先写 tmp 再移入 new，读取方不会看到写了一半的文件
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%d.%s_%d.%s", time.Now().Unix(), hex.EncodeToString(suffix), os.Getpid(), m.host)
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(m.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package email sends the messages the service mails to users through a
+// pluggable Mailer: SMTP in production, a maildir, the log or memory in
+// development and tests.
+package email
+
+import (
+	"bytes"
+	"context"
+	"net/mail"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Message is one email to a single recipient. When both Text and HTML are
+// set it is sent as multipart/alternative.
+type Message struct {
+	// ID, when set, stays the same across delivery retries and becomes the
+	// Message-ID header, so receivers can drop duplicates.
+	ID      string `json:"id,omitempty"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text,omitempty"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent
+// use.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// encode renders msg as an RFC 5322 message from the given sender.
+func (m *Message) encode(from mail.Address) ([]byte, error) {
+	gm := gomail.NewMessage()
+	gm.SetAddressHeader("From", from.Address, from.Name)
+	gm.SetHeader("To", m.To)
+	gm.SetHeader("Subject", m.Subject)
+	if m.ID != "" {
+		domain := "localhost"
+		if i := strings.LastIndexByte(from.Address, '@'); i >= 0 {
+			domain = from.Address[i+1:]
+		}
+		gm.SetHeader("Message-ID", "<"+m.ID+"@"+domain+">")
+	}
+	switch {
+	case m.Text != "" && m.HTML != "":
+		gm.SetBody("text/plain", m.Text)
+		gm.AddAlternative("text/html", m.HTML)
+	case m.Text != "":
+		gm.SetBody("text/plain", m.Text)
+	default:
+		gm.SetBody("text/html", m.HTML)
+	}
+
+	var buf bytes.Buffer
+	if _, err := gm.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
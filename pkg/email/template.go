@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Names of the templates the service sends.
+const (
+	TemplateVerifyEmail          = "verify_email"
+	TemplateWelcome              = "welcome"
+	TemplateEmailChange          = "email_change"
+	TemplateEmailChangeNotice    = "email_change_notice"
+	TemplatePasswordReset        = "password_reset"
+	TemplateInvitation           = "invitation"
+	TemplateRegistrationApproved = "registration_approved"
+	TemplateRegistrationRejected = "registration_rejected"
+	TemplateImpersonationNotice  = "impersonation_notice"
+)
+
+// DefaultLocale is used when no preferred locale has a template.
+const DefaultLocale = "en"
+
+//go:embed templates
+var embedded embed.FS
+
+// Data is what templates are rendered with. Each template uses the fields
+// that apply to it.
+type Data struct {
+	Username     string
+	Link         string
+	NewEmail     string
+	Organization string
+	Reason       string
+	ExpiresAt    time.Time
+	Time         time.Time
+}
+
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates renders the emails the service sends. Every template lives in
+// <locale>/<name>.tmpl and defines three blocks: "subject", "text" and
+// "html". The html block goes through html/template, so interpolated
+// values are escaped.
+type Templates struct {
+	defaultLocale string
+	sets          map[string]map[string]*templateSet // name -> locale -> set
+}
+
+// LoadTemplates parses the embedded templates and, when dir is not empty,
+// the files under dir, which replace embedded ones with the same locale and
+// name or add new locales. Every template must exist in defaultLocale.
+func LoadTemplates(dir, defaultLocale string) (*Templates, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	t := &Templates{
+		defaultLocale: normalizeLocale(defaultLocale),
+		sets:          make(map[string]map[string]*templateSet),
+	}
+
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.load(sub); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := t.load(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+
+	for name, locales := range t.sets {
+		if _, ok := locales[t.defaultLocale]; !ok {
+			return nil, fmt.Errorf("template %s has no %s variant", name, t.defaultLocale)
+		}
+	}
+	return t, nil
+}
+
+// DefaultTemplates returns the embedded templates. It panics if they do
+// not parse, which would be a bug in the build.
+func DefaultTemplates() *Templates {
+	t, err := LoadTemplates("", DefaultLocale)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Templates) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		locale := normalizeLocale(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+
+		set, err := parseSet(name, string(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if t.sets[name] == nil {
+			t.sets[name] = make(map[string]*templateSet)
+		}
+		t.sets[name][locale] = set
+	}
+	return nil
+}
+
+func parseSet(name, src string) (*templateSet, error) {
+	text, err := texttemplate.New(name).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(name).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range []string{"subject", "text", "html"} {
+		if text.Lookup(block) == nil {
+			return nil, fmt.Errorf("missing %q block", block)
+		}
+	}
+	return &templateSet{text: text, html: html}, nil
+}
+
+// Render builds the message for the named template in the first of
+// locales that has it, falling back from a region to its language
+// ("pt-br" to "pt") and finally to the default locale. To is left empty.
+func (t *Templates) Render(name string, locales []string, data Data) (*Message, error) {
+	variants, ok := t.sets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	set := variants[t.defaultLocale]
+	for _, l := range locales {
+		if s, ok := variants[l]; ok {
+			set = s
+			break
+		}
+		if i := strings.IndexByte(l, '-'); i > 0 {
+			if s, ok := variants[l[:i]]; ok {
+				set = s
+				break
+			}
+		}
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := set.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if err := set.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return nil, err
+	}
+	// 主题是单行 header，去掉模板里的换行
+	return &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
+
+// Names lists the loaded templates.
+func (t *Templates) Names() []string {
+	names := make([]string, 0, len(t.sets))
+	for name := range t.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales lists the locales the named template has a variant for.
+func (t *Templates) Locales(name string) []string {
+	locales := make([]string, 0, len(t.sets[name]))
+	for l := range t.sets[name] {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
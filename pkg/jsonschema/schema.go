@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jsonschema validates JSON documents against a subset of JSON
+// Schema (draft 2020-12): type, enum, const, properties, required,
+// additionalProperties, items, minLength, maxLength, pattern, minimum,
+// maximum, maxItems and maxProperties. Other keywords are rejected when
+// the schema is compiled, so a schema never silently validates less than
+// it appears to.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled schema.
+type Schema struct {
+	types                []string
+	enum                 []interface{}
+	constant             interface{}
+	hasConst             bool
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	items                *Schema
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	maxItems             *int
+	maxProperties        *int
+}
+
+// ValidationError describes the first violation found. Path is a JSON
+// pointer to the offending value, "" for the document itself.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+var knownTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Compile parses a schema document.
+func Compile(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	return compile(raw, "")
+}
+
+// LoadFile compiles the schema stored at path.
+func LoadFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(data)
+}
+
+func compile(raw interface{}, path string) (*Schema, error) {
+	if b, ok := raw.(bool); ok {
+		if b {
+			return &Schema{}, nil
+		}
+		return &Schema{enum: []interface{}{}}, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema%s: must be an object or boolean", path)
+	}
+
+	s := &Schema{}
+	for key, v := range m {
+		var err error
+		switch key {
+		case "$schema", "$id", "title", "description", "default", "examples", "$comment":
+		case "type":
+			s.types, err = compileTypes(v)
+		case "enum":
+			list, ok := v.([]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an array")
+			}
+			s.enum = list
+		case "const":
+			s.constant, s.hasConst = v, true
+		case "properties":
+			props, ok := v.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an object")
+				break
+			}
+			s.properties = map[string]*Schema{}
+			for name, sub := range props {
+				if s.properties[name], err = compile(sub, path+"/properties/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			list, ok := v.([]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an array of strings")
+				break
+			}
+			for _, r := range list {
+				name, ok := r.(string)
+				if !ok {
+					err = fmt.Errorf("must be an array of strings")
+					break
+				}
+				s.required = append(s.required, name)
+			}
+		case "additionalProperties":
+			if b, ok := v.(bool); ok {
+				s.noAdditional = !b
+				break
+			}
+			s.additionalProperties, err = compile(v, path+"/additionalProperties")
+		case "items":
+			s.items, err = compile(v, path+"/items")
+		case "minLength":
+			s.minLength, err = compileCount(v)
+		case "maxLength":
+			s.maxLength, err = compileCount(v)
+		case "maxItems":
+			s.maxItems, err = compileCount(v)
+		case "maxProperties":
+			s.maxProperties, err = compileCount(v)
+		case "pattern":
+			p, ok := v.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(p)
+		case "minimum":
+			s.minimum, err = compileNumber(v)
+		case "maximum":
+			s.maximum, err = compileNumber(v)
+		default:
+			err = fmt.Errorf("unsupported keyword")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("schema%s/%s: %w", path, key, err)
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(v interface{}) ([]string, error) {
+	var types []string
+	switch t := v.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			name, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a string or an array of strings")
+			}
+			types = append(types, name)
+		}
+	default:
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+	for _, name := range types {
+		if !slices.Contains(knownTypes, name) {
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return types, nil
+}
+
+func compileCount(v interface{}) (*int, error) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func compileNumber(v interface{}) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &f, nil
+}
+
+// Validate checks a document decoded by encoding/json (maps, slices,
+// strings, float64, bool and nil).
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate(v, "")
+}
+
+func (s *Schema) validate(v interface{}, path string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(v, t) }) {
+		return fail("must be of type %s", strings.Join(s.types, " or "))
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e interface{}) bool { return equal(e, v) }) {
+		return fail("must be one of the allowed values")
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		return fail("must equal the constant value")
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := len([]rune(v))
+		if s.minLength != nil && n < *s.minLength {
+			return fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			return fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("must match %s", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return fail("must be at least %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return fail("must be at most %v", *s.maximum)
+		}
+	case []interface{}:
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				if err := s.items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if s.maxProperties != nil && len(v) > *s.maxProperties {
+			return fail("must have at most %d properties", *s.maxProperties)
+		}
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				return fail("missing required property %q", name)
+			}
+		}
+		// 按键名排序，保证报告的第一个错误是确定的
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := s.properties[k]
+			if sub == nil {
+				if s.noAdditional {
+					return &ValidationError{Path: path + "/" + escape(k), Message: "property is not allowed"}
+				}
+				sub = s.additionalProperties
+			}
+			if sub == nil {
+				continue
+			}
+			if err := sub.validate(v[k], path+"/"+escape(k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasType(v interface{}, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || t == "integer" && v == math.Trunc(v)
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// escape encodes a property name as a JSON pointer token.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
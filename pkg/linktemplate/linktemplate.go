@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package linktemplate builds the links mailed to users from URL
+// templates such as "https://app.example.com/verify?token={token}" or
+// "myapp://reset/{token}".
+package linktemplate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Placeholder marks where a template takes the token. Templates without
+// it get "?token=" appended.
+const Placeholder = "{token}"
+
+// Schemes that must never appear in a link, custom or not.
+var blockedSchemes = map[string]bool{
+	"javascript": true, "data": true, "vbscript": true, "file": true, "blob": true, "about": true,
+}
+
+// Validate accepts https URLs (web and universal links), http only on
+// loopback hosts, and custom schemes for mobile deep links. The token may
+// only appear after the host. An empty template is valid.
+func Validate(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+	u, err := url.Parse(strings.ReplaceAll(tmpl, Placeholder, "TOKEN"))
+	if err != nil {
+		return fmt.Errorf("not a valid URL: %v", err)
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+	if strings.Contains(u.Scheme+u.Host, "TOKEN") {
+		return errors.New("token placeholder must come after the host")
+	}
+
+	// 深链接的 host 可以为空（myapp:///verify）
+	switch scheme := strings.ToLower(u.Scheme); {
+	case scheme == "https":
+		if u.Hostname() == "" {
+			return errors.New("https URL needs a host")
+		}
+	case scheme == "http":
+		switch u.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return errors.New("http is only allowed for localhost, use https")
+		}
+	case scheme == "" || blockedSchemes[scheme]:
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	return nil
+}
+
+// Expand fills tmpl with token.
+func Expand(tmpl, token string) string {
+	if strings.Contains(tmpl, Placeholder) {
+		return strings.ReplaceAll(tmpl, Placeholder, url.QueryEscape(token))
+	}
+	sep := "?"
+	if strings.Contains(tmpl, "?") {
+		sep = "&"
+	}
+	return tmpl + sep + "token=" + url.QueryEscape(token)
+}
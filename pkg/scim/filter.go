@@ -0,0 +1,386 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scim parses SCIM 2.0 filter expressions (RFC 7644 section 3.4.2.2)
+// and PATCH paths into a small AST. Rendering the AST is left to the caller.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidFilter is wrapped by every parse error and should be wrapped by
+// renderers that reject an attribute or operator.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter is a parsed filter expression: *Logical, *Not, *Compare, *Present
+// or *ValuePath.
+type Filter interface {
+	filter()
+}
+
+// Logical joins two filters with "and" or "or".
+type Logical struct {
+	Op          string
+	Left, Right Filter
+}
+
+type Not struct {
+	Filter Filter
+}
+
+// Compare is "attr op value". Attr is lower-cased, sub-attributes are
+// joined with dots ("emails.value"). Value is a string, float64, bool or nil.
+type Compare struct {
+	Attr  string
+	Op    string
+	Value interface{}
+}
+
+// Present is "attr pr".
+type Present struct {
+	Attr string
+}
+
+// ValuePath is "attr[filter]"; attribute names inside Filter are relative
+// to Attr.
+type ValuePath struct {
+	Attr   string
+	Filter Filter
+}
+
+func (*Logical) filter()   {}
+func (*Not) filter()       {}
+func (*Compare) filter()   {}
+func (*Present) filter()   {}
+func (*ValuePath) filter() {}
+
+var compareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// ParseFilter parses a filter expression.
+func ParseFilter(s string) (Filter, error) {
+	p := &parser{src: s}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.rest())
+	}
+	return f, nil
+}
+
+// Path is a parsed PATCH path: attr, attr.sub, attr[filter] or
+// attr[filter].sub.
+type Path struct {
+	Attr   string
+	Filter Filter
+	Sub    string
+}
+
+// ParsePath parses a PATCH operation path. Schema URN prefixes such as
+// "urn:ietf:params:scim:schemas:core:2.0:User:" are stripped.
+func ParsePath(s string) (*Path, error) {
+	p := &parser{src: stripURN(strings.TrimSpace(s))}
+	attr, err := p.parseAttrPath()
+	if err != nil {
+		return nil, err
+	}
+	path := &Path{Attr: attr}
+	if i := strings.IndexByte(attr, '.'); i >= 0 {
+		path.Attr, path.Sub = attr[:i], attr[i+1:]
+	}
+	if p.peek() == '[' {
+		if path.Sub != "" {
+			return nil, p.errorf("filter must follow the top-level attribute")
+		}
+		p.pos++
+		path.Filter, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ']' {
+			return nil, p.errorf("missing ]")
+		}
+		p.pos++
+		if p.peek() == '.' {
+			p.pos++
+			sub, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			path.Sub = strings.ToLower(sub)
+		}
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.rest())
+	}
+	return path, nil
+}
+
+func stripURN(s string) string {
+	if !strings.HasPrefix(strings.ToLower(s), "urn:") {
+		return s
+	}
+	// the attribute starts after the last colon outside any filter
+	end := len(s)
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		end = i
+	}
+	if i := strings.LastIndexByte(s[:end], ':'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at %d: %s", ErrInvalidFilter, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool    { return p.pos >= len(p.src) }
+func (p *parser) rest() string { return p.src[p.pos:] }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// keyword consumes word (case-insensitively) when it is followed by a
+// space, "(" or the end of input.
+func (p *parser) keyword(word string) bool {
+	p.skipSpace()
+	end := p.pos + len(word)
+	if end > len(p.src) || !strings.EqualFold(p.src[p.pos:end], word) {
+		return false
+	}
+	if end < len(p.src) && p.src[end] != ' ' && p.src[end] != '(' {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	p.skipSpace()
+	if p.keyword("not") {
+		p.skipSpace()
+		if p.peek() != '(' {
+			return nil, p.errorf("not must be followed by (")
+		}
+		f, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Filter: f}, nil
+	}
+	if p.peek() == '(' {
+		return p.parseGroup()
+	}
+	return p.parseAttrExp()
+}
+
+func (p *parser) parseGroup() (Filter, error) {
+	p.pos++ // (
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ')' {
+		return nil, p.errorf("missing )")
+	}
+	p.pos++
+	return f, nil
+}
+
+func (p *parser) parseAttrExp() (Filter, error) {
+	attr, err := p.parseAttrPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() == '[' {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ']' {
+			return nil, p.errorf("missing ]")
+		}
+		p.pos++
+		return &ValuePath{Attr: attr, Filter: inner}, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for !p.eof() && (p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' || p.src[p.pos] >= 'A' && p.src[p.pos] <= 'Z') {
+		p.pos++
+	}
+	op := strings.ToLower(p.src[start:p.pos])
+	if op == "pr" {
+		return &Present{Attr: attr}, nil
+	}
+	if !compareOps[op] {
+		p.pos = start
+		return nil, p.errorf("unknown operator %q", op)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &Compare{Attr: attr, Op: op, Value: value}, nil
+}
+
+// parseAttrPath reads attr or attr.sub and lower-cases it, since attribute
+// names are case-insensitive.
+func (p *parser) parseAttrPath() (string, error) {
+	p.skipSpace()
+	if strings.HasPrefix(strings.ToLower(p.rest()), "urn:") {
+		// skip a schema URN prefix; it contains dots and colons
+		end := strings.IndexAny(p.rest(), " [)")
+		if end < 0 {
+			end = len(p.rest())
+		}
+		urn := p.rest()[:end]
+		p.pos += len(urn) - len(stripURN(urn))
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return "", err
+	}
+	if p.peek() == '.' {
+		p.pos++
+		sub, err := p.parseName()
+		if err != nil {
+			return "", err
+		}
+		name += "." + sub
+	}
+	return strings.ToLower(name), nil
+}
+
+func (p *parser) parseName() (string, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '$' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if start == p.pos {
+		return "", p.errorf("expected attribute name")
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	p.skipSpace()
+	if p.peek() == '"' {
+		start := p.pos
+		p.pos++
+		for !p.eof() && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos++
+		var s string
+		if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+			return nil, p.errorf("invalid string")
+		}
+		return s, nil
+	}
+
+	start := p.pos
+	for !p.eof() && p.src[p.pos] != ' ' && p.src[p.pos] != ')' && p.src[p.pos] != ']' {
+		p.pos++
+	}
+	raw := p.src[start:p.pos]
+	switch strings.ToLower(raw) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	var n float64
+	if err := json.Unmarshal([]byte(raw), &n); err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid value %q", raw)
+	}
+	return n, nil
+}
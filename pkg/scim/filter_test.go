@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Filter
+	}{
+		{`userName eq "bjensen"`, &Compare{Attr: "username", Op: "eq", Value: "bjensen"}},
+		{`USERNAME EQ "BJensen"`, &Compare{Attr: "username", Op: "eq", Value: "BJensen"}},
+		{`name.familyName co "O'Malley"`, &Compare{Attr: "name.familyname", Op: "co", Value: "O'Malley"}},
+		{`userName sw "J"`, &Compare{Attr: "username", Op: "sw", Value: "J"}},
+		{`title pr`, &Present{Attr: "title"}},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, &Compare{Attr: "meta.lastmodified", Op: "gt", Value: "2011-05-13T04:42:34Z"}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`, &Compare{Attr: "username", Op: "eq", Value: "bjensen"}},
+		{`active eq true`, &Compare{Attr: "active", Op: "eq", Value: true}},
+		{`active ne false`, &Compare{Attr: "active", Op: "ne", Value: false}},
+		{`externalId eq null`, &Compare{Attr: "externalid", Op: "eq", Value: nil}},
+		{`age ge 21.5`, &Compare{Attr: "age", Op: "ge", Value: 21.5}},
+		{`displayName eq "say \"hi\""`, &Compare{Attr: "displayname", Op: "eq", Value: `say "hi"`}},
+		{
+			`title pr and userType eq "Employee"`,
+			&Logical{Op: "and", Left: &Present{Attr: "title"}, Right: &Compare{Attr: "usertype", Op: "eq", Value: "Employee"}},
+		},
+		{
+			// and binds tighter than or
+			`a eq "1" or b eq "2" and c eq "3"`,
+			&Logical{Op: "or",
+				Left:  &Compare{Attr: "a", Op: "eq", Value: "1"},
+				Right: &Logical{Op: "and", Left: &Compare{Attr: "b", Op: "eq", Value: "2"}, Right: &Compare{Attr: "c", Op: "eq", Value: "3"}},
+			},
+		},
+		{
+			`(a eq "1" or b eq "2") and c eq "3"`,
+			&Logical{Op: "and",
+				Left:  &Logical{Op: "or", Left: &Compare{Attr: "a", Op: "eq", Value: "1"}, Right: &Compare{Attr: "b", Op: "eq", Value: "2"}},
+				Right: &Compare{Attr: "c", Op: "eq", Value: "3"},
+			},
+		},
+		{`not (userName eq "x")`, &Not{Filter: &Compare{Attr: "username", Op: "eq", Value: "x"}}},
+		{
+			`emails[type eq "work" and value co "@example.com"]`,
+			&ValuePath{Attr: "emails", Filter: &Logical{Op: "and",
+				Left:  &Compare{Attr: "type", Op: "eq", Value: "work"},
+				Right: &Compare{Attr: "value", Op: "co", Value: "@example.com"},
+			}},
+		},
+		{
+			`userType eq "Employee" and emails[type eq "work"]`,
+			&Logical{Op: "and",
+				Left:  &Compare{Attr: "usertype", Op: "eq", Value: "Employee"},
+				Right: &ValuePath{Attr: "emails", Filter: &Compare{Attr: "type", Op: "eq", Value: "work"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFilter(tt.in)
+			if err != nil {
+				t.Fatalf("ParseFilter: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseFilter = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`userName`,
+		`userName xx "a"`,
+		`userName eq`,
+		`userName eq "unterminated`,
+		`userName eq bjensen`,
+		`(userName eq "a"`,
+		`emails[type eq "work"`,
+		`not userName eq "a"`,
+		`userName eq "a" trailing`,
+		`userName eq "a" and`,
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			_, err := ParseFilter(in)
+			if !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("ParseFilter(%q) error = %v, want ErrInvalidFilter", in, err)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Path
+	}{
+		{`displayName`, &Path{Attr: "displayname"}},
+		{`name.givenName`, &Path{Attr: "name", Sub: "givenname"}},
+		{`members`, &Path{Attr: "members"}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:name.familyName`, &Path{Attr: "name", Sub: "familyname"}},
+		{
+			`members[value eq "2819c223-7f76-453a-919d-413861904646"]`,
+			&Path{Attr: "members", Filter: &Compare{Attr: "value", Op: "eq", Value: "2819c223-7f76-453a-919d-413861904646"}},
+		},
+		{
+			`emails[type eq "work"].value`,
+			&Path{Attr: "emails", Filter: &Compare{Attr: "type", Op: "eq", Value: "work"}, Sub: "value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePath(tt.in)
+			if err != nil {
+				t.Fatalf("ParsePath: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParsePath = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, in := range []string{``, `name.givenName[type eq "x"]`, `emails[type eq "work"`, `emails[type eq "work"]value`} {
+		if _, err := ParsePath(in); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParsePath(%q) error = %v, want ErrInvalidFilter", in, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type FileConfig struct {
+	Path string
+	// Format is FormatJSON (JSON Lines) or FormatCEF, one event per line.
+	Format string
+	// MaxBytes rotates the file before it would grow past this size
+	// (default 100 MiB). Rotated files are Path.1 (newest) to
+	// Path.MaxBackups; older ones are deleted.
+	MaxBytes   int64
+	MaxBackups int
+}
+
+// FileSink appends events to a local file that a log shipper tails.
+type FileSink struct {
+	cfg  FileConfig
+	file *os.File
+	size int64
+}
+
+func NewFileSink(cfg FileConfig) (*FileSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("audit file path is required")
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatCEF {
+		return nil, fmt.Errorf("unknown audit file format %q", cfg.Format)
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 100 << 20
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("create audit file directory: %w", err)
+	}
+	s := &FileSink{cfg: cfg}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Write(ev *Event) error {
+	var line []byte
+	if s.cfg.Format == FormatCEF {
+		line = []byte(CEF(ev) + "\n")
+	} else {
+		var err error
+		if line, err = JSONLine(ev); err != nil {
+			return err
+		}
+	}
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.cfg.MaxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write audit file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat audit file: %w", err)
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts Path.N to Path.N+1, moves the current file to Path.1 and
+// starts a new one.
+func (s *FileSink) rotate() error {
+	if err := s.Close(); err != nil {
+		return fmt.Errorf("close audit file: %w", err)
+	}
+	backup := func(i int) string { return s.cfg.Path + "." + strconv.Itoa(i) }
+	if s.cfg.MaxBackups <= 0 {
+		if err := os.Remove(s.cfg.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate audit file: %w", err)
+		}
+		return s.open()
+	}
+	for i := s.cfg.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate audit file: %w", err)
+		}
+	}
+	if err := os.Rename(s.cfg.Path, backup(1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate audit file: %w", err)
+	}
+	return s.open()
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CEF header fields.
+const (
+	cefVendor  = "shinoda4"
+	cefProduct = "sd-svc-auth"
+	// cefMaxValue is the longest value most CEF parsers accept for the
+	// custom string extensions.
+	cefMaxValue = 4000
+)
+
+// cefVersion is the module version the binary was built from.
+var cefVersion = func() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}()
+
+// JSONLine encodes ev as one JSON object followed by a newline.
+func JSONLine(ev *Event) ([]byte, error) {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit event %d: %w", ev.ID, err)
+	}
+	return append(line, '\n'), nil
+}
+
+// CEF encodes ev in ArcSight Common Event Format, without a trailing
+// newline:
+//
+//	CEF:0|shinoda4|sd-svc-auth|<version>|<action>|<action> <outcome>|<severity>|rt=... act=... outcome=...
+func CEF(ev *Event) string {
+	severity := 3
+	if ev.failed() {
+		severity = 7
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeader(cefVendor), cefHeader(cefProduct), cefHeader(cefVersion),
+		cefHeader(ev.Action), cefHeader(ev.Action+" "+ev.Outcome), severity)
+
+	n := 0
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		n++
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(cefExtension(truncate(value, cefMaxValue)))
+	}
+	// csN 只在有值时连同 label 一起输出
+	custom := func(key, label, value string) {
+		if value != "" {
+			add(key+"Label", label)
+			add(key, value)
+		}
+	}
+
+	add("rt", strconv.FormatInt(ev.Time.UnixMilli(), 10))
+	add("externalId", strconv.FormatInt(ev.ID, 10))
+	add("act", ev.Action)
+	add("outcome", ev.Outcome)
+	add("reason", ev.Reason)
+	add("suid", ev.ActorID)
+	add("duid", ev.TargetID)
+	// src 只接受 IP 地址
+	if net.ParseIP(ev.IP) != nil {
+		add("src", ev.IP)
+	}
+	add("requestClientApplication", ev.UserAgent)
+	custom("cs1", "orgId", ev.OrgID)
+	custom("cs2", "actorType", ev.ActorType)
+	custom("cs3", "requestId", ev.RequestID)
+	if details := string(ev.Details); details != "{}" {
+		custom("cs4", "details", details)
+	}
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	cefExtEscaper    = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefExtension(s string) string {
+	return cefExtEscaper.Replace(s)
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow policies of a Queue whose buffer is full.
+const (
+	// OverflowDrop discards the event and counts it in dropped_total.
+	OverflowDrop = "drop"
+	// OverflowBlock makes Export wait for room until its context ends,
+	// slowing the caller down to the pace of the sink.
+	OverflowBlock = "block"
+)
+
+var (
+	ErrDropped = errors.New("siem: export buffer full, event dropped")
+	ErrClosed  = errors.New("siem: queue closed")
+)
+
+// 每个 Queue 的计数器通过 expvar 暴露在 /debug/vars 的 audit_export 下
+var metrics = expvar.NewMap("audit_export")
+
+type QueueConfig struct {
+	// Size is how many events wait for the sink (default 1024).
+	Size int
+	// Overflow is OverflowDrop (default) or OverflowBlock.
+	Overflow string
+	// MaxBackoff caps the delay between attempts at an event the sink
+	// rejected (default 30s).
+	MaxBackoff time.Duration
+}
+
+// Queue buffers events in front of a sink and writes them from a single
+// goroutine. An event the sink rejects is retried with backoff, keeping
+// the events behind it in order; while it waits the buffer fills up and
+// the overflow policy applies.
+type Queue struct {
+	name       string
+	sink       Sink
+	overflow   string
+	maxBackoff time.Duration
+
+	events    chan *Event
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+
+	written, dropped, failed expvar.Int
+	// dropping is set by the first drop and cleared by the next
+	// successful write, so a backlog is logged once rather than per event.
+	dropping atomic.Bool
+}
+
+// NewQueue starts writing events exported to the queue to sink. name
+// identifies the sink in logs and metrics.
+func NewQueue(name string, sink Sink, cfg QueueConfig) (*Queue, error) {
+	if cfg.Size <= 0 {
+		cfg.Size = 1024
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowDrop
+	}
+	if cfg.Overflow != OverflowDrop && cfg.Overflow != OverflowBlock {
+		return nil, fmt.Errorf("unknown overflow policy %q", cfg.Overflow)
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	q := &Queue{
+		name:       name,
+		sink:       sink,
+		overflow:   cfg.Overflow,
+		maxBackoff: cfg.MaxBackoff,
+		events:     make(chan *Event, cfg.Size),
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	stats := new(expvar.Map)
+	stats.Set("queued", expvar.Func(func() interface{} { return len(q.events) }))
+	stats.Set("written_total", &q.written)
+	stats.Set("dropped_total", &q.dropped)
+	stats.Set("failed_writes_total", &q.failed)
+	metrics.Set(name, stats)
+
+	go q.run()
+	return q, nil
+}
+
+// Export hands ev to the sink. It returns ErrDropped when the buffer is
+// full under OverflowDrop, or when ctx ended while waiting for room under
+// OverflowBlock (then wrapping ctx's error too).
+func (q *Queue) Export(ctx context.Context, ev *Event) error {
+	select {
+	case <-q.closing:
+		return ErrClosed
+	default:
+	}
+	select {
+	case q.events <- ev:
+		return nil
+	default:
+	}
+	if q.overflow == OverflowBlock {
+		select {
+		case q.events <- ev:
+			return nil
+		case <-q.closing:
+			return ErrClosed
+		case <-ctx.Done():
+		}
+	}
+	q.dropped.Add(1)
+	if q.dropping.CompareAndSwap(false, true) {
+		log.Printf("[audit] export to %s is falling behind, dropping events", q.name)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrDropped, err)
+	}
+	return ErrDropped
+}
+
+// Close stops accepting events, writes what is buffered (without retries)
+// and closes the sink. It gives up when ctx ends.
+func (q *Queue) Close(ctx context.Context) error {
+	q.closeOnce.Do(func() { close(q.closing) })
+	select {
+	case <-q.done:
+		return q.sink.Close()
+	case <-ctx.Done():
+		return fmt.Errorf("flush %s: %w", q.name, ctx.Err())
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for {
+		select {
+		case ev := <-q.events:
+			q.write(ev)
+		case <-q.closing:
+			// 关闭时把缓冲中的事件各写一次，失败不再重试
+			for {
+				select {
+				case ev := <-q.events:
+					if err := q.sink.Write(ev); err != nil {
+						q.failed.Add(1)
+						q.dropped.Add(1)
+						continue
+					}
+					q.written.Add(1)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write retries ev until the sink accepts it or the queue is closed.
+func (q *Queue) write(ev *Event) {
+	delay := time.Second
+	for {
+		err := q.sink.Write(ev)
+		if err == nil {
+			q.written.Add(1)
+			if q.dropping.CompareAndSwap(true, false) {
+				log.Printf("[audit] export to %s caught up", q.name)
+			}
+			return
+		}
+		q.failed.Add(1)
+		log.Printf("[audit] export to %s failed, retrying in %s: %v", q.name, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-q.closing:
+			q.dropped.Add(1)
+			return
+		}
+		delay = min(delay*2, q.maxBackoff)
+	}
+}
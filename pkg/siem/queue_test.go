@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateSink holds every Write until the test releases it.
+type gateSink struct {
+	started chan *Event
+	release chan struct{}
+
+	mu      sync.Mutex
+	written []int64
+}
+
+func newGateSink() *gateSink {
+	return &gateSink{started: make(chan *Event, 100), release: make(chan struct{})}
+}
+
+func (s *gateSink) Write(ev *Event) error {
+	s.started <- ev
+	<-s.release
+	s.mu.Lock()
+	s.written = append(s.written, ev.ID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *gateSink) Close() error { return nil }
+
+func (s *gateSink) ids() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.written...)
+}
+
+// waitStarted waits until the queue's writer is inside sink.Write.
+func (s *gateSink) waitStarted(t *testing.T) *Event {
+	t.Helper()
+	select {
+	case ev := <-s.started:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink was not written to")
+		return nil
+	}
+}
+
+func closeQueue(t *testing.T, q *Queue) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueDropBoundsBuffer(t *testing.T) {
+	sink := newGateSink()
+	q, err := NewQueue(t.Name(), sink, QueueConfig{Size: 2, Overflow: OverflowDrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 第一个事件被写入协程取走并卡在 sink 中，之后的两个占满缓冲
+	if err := q.Export(ctx, &Event{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	sink.waitStarted(t)
+	for id := int64(2); id <= 3; id++ {
+		if err := q.Export(ctx, &Event{ID: id}); err != nil {
+			t.Fatalf("event %d: %v", id, err)
+		}
+	}
+	for id := int64(4); id <= 6; id++ {
+		start := time.Now()
+		if err := q.Export(ctx, &Event{ID: id}); !errors.Is(err, ErrDropped) {
+			t.Fatalf("event %d: err = %v, want ErrDropped", id, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("event %d: dropping took %v", id, elapsed)
+		}
+	}
+	if n := len(q.events); n != 2 {
+		t.Errorf("buffer holds %d events, want 2", n)
+	}
+	if n := q.dropped.Value(); n != 3 {
+		t.Errorf("dropped_total = %d, want 3", n)
+	}
+
+	close(sink.release)
+	closeQueue(t, q)
+	if got := sink.ids(); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("written %v, want [1 2 3]", got)
+	}
+	if n := q.written.Value(); n != 3 {
+		t.Errorf("written_total = %d, want 3", n)
+	}
+}
+
+func TestQueueBlockAppliesBackpressure(t *testing.T) {
+	sink := newGateSink()
+	q, err := NewQueue(t.Name(), sink, QueueConfig{Size: 1, Overflow: OverflowBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := q.Export(ctx, &Event{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	sink.waitStarted(t)
+	if err := q.Export(ctx, &Event{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓冲已满：Export 必须等待，直到 sink 取走一个事件
+	exported := make(chan error, 1)
+	go func() { exported <- q.Export(ctx, &Event{ID: 3}) }()
+	select {
+	case err := <-exported:
+		t.Fatalf("Export returned %v while the buffer was full", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if n := len(q.events); n != 1 {
+		t.Errorf("buffer holds %d events, want 1", n)
+	}
+
+	sink.release <- struct{}{} // event 1 done, writer takes event 2
+	sink.waitStarted(t)
+	select {
+	case err := <-exported:
+		if err != nil {
+			t.Fatalf("blocked Export: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Export still blocked after the sink caught up")
+	}
+
+	// 等待超时的调用方得到 ErrDropped 和 ctx 的错误
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = q.Export(timeout, &Event{ID: 4})
+	if !errors.Is(err, ErrDropped) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrDropped wrapping context.DeadlineExceeded", err)
+	}
+	if n := q.dropped.Value(); n != 1 {
+		t.Errorf("dropped_total = %d, want 1", n)
+	}
+
+	close(sink.release)
+	closeQueue(t, q)
+	if got := sink.ids(); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("written %v, want [1 2 3]", got)
+	}
+	if err := q.Export(ctx, &Event{ID: 5}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Export after Close: err = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package siem exports audit events to a SIEM: as RFC 5424 syslog over
+// UDP, TCP or TLS, as CEF, or as rotating JSON Lines files. Sinks write
+// one event at a time; a Queue puts a bounded buffer in front of a sink so
+// a slow or unreachable collector does not stall the caller.
+package siem
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Formats a sink can write events in.
+const (
+	FormatJSON    = "json"
+	FormatCEF     = "cef"
+	FormatRFC5424 = "rfc5424"
+)
+
+// OutcomeFailure is the outcome of an action that did not succeed; such
+// events are reported with a higher severity.
+const OutcomeFailure = "failure"
+
+// Event is one audit event as exported.
+type Event struct {
+	ID        int64           `json:"id"`
+	Time      time.Time       `json:"occurred_at"`
+	Action    string          `json:"action"`
+	Outcome   string          `json:"outcome"`
+	Reason    string          `json:"reason,omitempty"`
+	ActorID   string          `json:"actor_id,omitempty"`
+	ActorType string          `json:"actor_type,omitempty"`
+	TargetID  string          `json:"target_id,omitempty"`
+	OrgID     string          `json:"org_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+func (ev *Event) failed() bool {
+	return ev.Outcome == OutcomeFailure
+}
+
+// Sink writes events to one destination. Sinks are not safe for
+// concurrent use; wrap them in a Queue.
+type Sink interface {
+	// Write delivers ev. After an error the sink must be usable again,
+	// e.g. by reconnecting on the next call.
+	Write(ev *Event) error
+	Close() error
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Framings of syslog messages over TCP and TLS (RFC 6587). UDP always
+// sends one message per datagram.
+const (
+	// FramingOctet prefixes each message with its length, as RFC 5425
+	// requires for TLS.
+	FramingOctet = "octet"
+	// FramingLF ends each message with a newline, for collectors that
+	// only split on newlines.
+	FramingLF = "lf"
+)
+
+const (
+	// facilityAuthPriv is the syslog facility for security messages.
+	facilityAuthPriv = 10
+	severityWarning  = 4
+	severityInfo     = 6
+	// sdID names the structured data element. 32473 is the enterprise
+	// number the IANA reserves for examples and documentation.
+	sdID = "audit@32473"
+	// rfc5424Time allows at most six digits of fractional seconds.
+	rfc5424Time = "2006-01-02T15:04:05.999999Z07:00"
+)
+
+type SyslogConfig struct {
+	// Network is udp, tcp or tls.
+	Network string
+	// Addr is the collector's host:port.
+	Addr string
+	// Format is FormatRFC5424 (structured data) or FormatCEF (a CEF
+	// message in an RFC 5424 envelope).
+	Format string
+	// Framing is FramingOctet (default) or FramingLF; ignored for udp.
+	Framing string
+	// CAFile holds PEM certificates trusted for tls instead of the
+	// system pool.
+	CAFile string
+	// Hostname and AppName fill the syslog header; they default to the
+	// machine's hostname and "sd-svc-auth".
+	Hostname string
+	AppName  string
+	// Timeout bounds connecting and each write (default 5s).
+	Timeout time.Duration
+}
+
+// SyslogSink sends events to a syslog collector. It connects on the first
+// write and again after a failed one.
+type SyslogSink struct {
+	cfg    SyslogConfig
+	tls    *tls.Config
+	procID string
+	conn   net.Conn
+}
+
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q", cfg.Network)
+	}
+	if cfg.Addr == "" {
+		return nil, errors.New("syslog address is required")
+	}
+	if cfg.Format != FormatRFC5424 && cfg.Format != FormatCEF {
+		return nil, fmt.Errorf("unknown syslog format %q", cfg.Format)
+	}
+	if cfg.Framing == "" {
+		cfg.Framing = FramingOctet
+	}
+	if cfg.Framing != FramingOctet && cfg.Framing != FramingLF {
+		return nil, fmt.Errorf("unknown syslog framing %q", cfg.Framing)
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "sd-svc-auth"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	s := &SyslogSink{cfg: cfg, procID: strconv.Itoa(os.Getpid())}
+	if cfg.Network == "tls" {
+		s.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read syslog ca file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+			}
+			s.tls.RootCAs = pool
+		}
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) Write(ev *Event) error {
+	if s.conn != nil && s.cfg.Network != "udp" && !s.alive() {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return fmt.Errorf("connect to syslog %s: %w", s.cfg.Addr, err)
+		}
+		s.conn = conn
+	}
+	msg := s.message(ev)
+	if s.cfg.Network != "udp" {
+		if s.cfg.Framing == FramingLF {
+			msg = append(msg, '\n')
+		} else {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		// 下次写入时重新连接
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("write to syslog %s: %w", s.cfg.Addr, err)
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// alive reports whether the collector still holds the stream open. A
+// write to a connection the peer has closed succeeds once and only the
+// next one fails, losing the first message; collectors never send
+// anything, so a read that does not time out means the connection is
+// gone. An expired deadline fails before reading, hence the millisecond.
+func (s *SyslogSink) alive() bool {
+	_ = s.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	var b [1]byte
+	_, err := s.conn.Read(b[:])
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (s *SyslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	if s.cfg.Network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", s.cfg.Addr, s.tls)
+	}
+	return dialer.Dial(s.cfg.Network, s.cfg.Addr)
+}
+
+// message renders ev as an RFC 5424 message:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [audit@32473 ...] MSG
+//
+// The action is the MSGID. In the CEF format the structured data is
+// empty and MSG is the CEF record.
+func (s *SyslogSink) message(ev *Event) []byte {
+	severity := severityInfo
+	if ev.failed() {
+		severity = severityWarning
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		facilityAuthPriv*8+severity,
+		ev.Time.UTC().Format(rfc5424Time),
+		headerField(s.cfg.Hostname, 255),
+		headerField(s.cfg.AppName, 48),
+		headerField(s.procID, 128),
+		headerField(ev.Action, 32))
+
+	if s.cfg.Format == FormatCEF {
+		b.WriteString("- ")
+		b.WriteString(CEF(ev))
+		return b.Bytes()
+	}
+
+	b.WriteString("[" + sdID)
+	param := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, ` %s="%s"`, name, sdEscaper.Replace(value))
+		}
+	}
+	param("id", strconv.FormatInt(ev.ID, 10))
+	param("outcome", ev.Outcome)
+	param("reason", ev.Reason)
+	param("actor_id", ev.ActorID)
+	param("actor_type", ev.ActorType)
+	param("target_id", ev.TargetID)
+	param("org_id", ev.OrgID)
+	param("ip", ev.IP)
+	param("user_agent", ev.UserAgent)
+	param("request_id", ev.RequestID)
+	if details := string(ev.Details); details != "{}" {
+		param("details", details)
+	}
+	b.WriteString("] ")
+
+	msg := ev.Action + " " + ev.Outcome
+	if ev.Reason != "" {
+		msg += ": " + ev.Reason
+	}
+	b.WriteString(lineEscaper.Replace(msg))
+	return b.Bytes()
+}
+
+var (
+	sdEscaper   = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`, "\r", " ", "\n", " ")
+	lineEscaper = strings.NewReplacer("\r", " ", "\n", " ")
+)
+
+// headerField makes s a valid header field: printable ASCII without
+// spaces, at most n bytes, "-" when empty.
+func headerField(s string, n int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return truncate(s, n)
+}
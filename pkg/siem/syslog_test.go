@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package siem
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEvent = &Event{
+	ID:        7,
+	Time:      time.Date(2026, 10, 19, 10, 0, 0, 123e6, time.UTC),
+	Action:    "login",
+	Outcome:   OutcomeFailure,
+	Reason:    `bad "pass]word\`,
+	ActorID:   "u1",
+	IP:        "10.0.0.1",
+	Details:   json.RawMessage(`{"k":"v"}`),
+	RequestID: "req-1",
+}
+
+// wantRFC5424 is testEvent as rendered by a sink with hostname "host" and
+// app name "app".
+var wantRFC5424 = `<84>1 2026-10-19T10:00:00.123Z host app ` + strconv.Itoa(os.Getpid()) + ` login ` +
+	`[audit@32473 id="7" outcome="failure" reason="bad \"pass\]word\\" actor_id="u1" ip="10.0.0.1" request_id="req-1" details="{\"k\":\"v\"}"] ` +
+	`login failure: bad "pass]word\`
+
+func newTestSyslog(t *testing.T, network, addr, format, framing string) *SyslogSink {
+	t.Helper()
+	s, err := NewSyslogSink(SyslogConfig{
+		Network:  network,
+		Addr:     addr,
+		Format:   format,
+		Framing:  framing,
+		Hostname: "host",
+		AppName:  "app",
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// acceptOne returns a reader over the first connection accepted by l.
+func acceptOne(t *testing.T, l net.Listener) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+// readOctetCounted reads one "LEN SP MSG" frame (RFC 6587 3.4.1).
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	prefix, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatalf("read frame length: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	if err != nil {
+		t.Fatalf("frame length %q: %v", prefix, err)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		t.Fatalf("read %d byte frame: %v", n, err)
+	}
+	return string(msg)
+}
+
+func TestSyslogOctetCounting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := newTestSyslog(t, "tcp", l.Addr().String(), FormatRFC5424, "")
+
+	success := &Event{ID: 8, Time: testEvent.Time, Action: "logout", Outcome: "success", Details: json.RawMessage(`{}`)}
+	if err := s.Write(testEvent); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(success); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, r := acceptOne(t, l)
+	defer conn.Close()
+	if got := readOctetCounted(t, r); got != wantRFC5424 {
+		t.Errorf("first frame\n got %s\nwant %s", got, wantRFC5424)
+	}
+	// 成功事件为 info 级别，空字段和空 details 不输出
+	want := `<86>1 2026-10-19T10:00:00.123Z host app ` + strconv.Itoa(os.Getpid()) + ` logout [audit@32473 id="8" outcome="success"] logout success`
+	if got := readOctetCounted(t, r); got != want {
+		t.Errorf("second frame\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestSyslogLFFraming(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := newTestSyslog(t, "tcp", l.Addr().String(), FormatRFC5424, FramingLF)
+
+	multiline := *testEvent
+	multiline.Reason = "line one\nline two"
+	if err := s.Write(&multiline); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(testEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, r := acceptOne(t, l)
+	defer conn.Close()
+	first, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(first, `login failure: line one line two`+"\n") || !strings.Contains(first, `reason="line one line two"`) {
+		t.Errorf("newlines in the event split the frame: %q", first)
+	}
+	second, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != wantRFC5424+"\n" {
+		t.Errorf("second line\n got %q\nwant %q", second, wantRFC5424+"\n")
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// UDP 不加分帧，一个数据报一条消息
+	for _, framing := range []string{FramingOctet, FramingLF} {
+		s := newTestSyslog(t, "udp", pc.LocalAddr().String(), FormatRFC5424, framing)
+		if err := s.Write(testEvent); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 64<<10)
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != wantRFC5424 {
+			t.Errorf("framing %s: datagram\n got %q\nwant %q", framing, got, wantRFC5424)
+		}
+	}
+}
+
+func TestSyslogCEF(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	s := newTestSyslog(t, "udp", pc.LocalAddr().String(), FormatCEF, "")
+
+	if err := s.Write(testEvent); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64<<10)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<84>1 2026-10-19T10:00:00.123Z host app ` + strconv.Itoa(os.Getpid()) + ` login - ` + CEF(testEvent)
+	if got := string(buf[:n]); got != want {
+		t.Errorf("datagram\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestCEFEscaping(t *testing.T) {
+	ev := &Event{
+		ID:        9,
+		Time:      testEvent.Time,
+		Action:    `grant|role\x`,
+		Outcome:   OutcomeFailure,
+		Reason:    "a=b\\c\r\nd",
+		ActorID:   "u1",
+		TargetID:  "u2",
+		IP:        "not an ip",
+		UserAgent: "curl/8",
+		OrgID:     "org-1",
+		Details:   json.RawMessage(`{"q":"x=1"}`),
+	}
+	want := fmt.Sprintf(`CEF:0|shinoda4|sd-svc-auth|%s|grant\|role\\x|grant\|role\\x failure|7|`, cefHeader(cefVersion)) +
+		fmt.Sprintf(`rt=%d externalId=9 act=grant|role\\x outcome=failure reason=a\=b\\c\nd suid=u1 duid=u2 `, ev.Time.UnixMilli()) +
+		`requestClientApplication=curl/8 cs1Label=orgId cs1=org-1 cs4Label=details cs4={"q":"x\=1"}`
+	if got := CEF(ev); got != want {
+		t.Errorf("CEF\n got %s\nwant %s", got, want)
+	}
+
+	// 超长值在 UTF-8 边界截断
+	ev = &Event{Time: testEvent.Time, Action: "x", Outcome: "success", Reason: strings.Repeat("é", cefMaxValue)}
+	got := CEF(ev)
+	reason := got[strings.Index(got, "reason=")+len("reason="):]
+	if len(reason) != cefMaxValue || !strings.HasSuffix(reason, "é") {
+		t.Errorf("reason cut to %d bytes ending in %q, want %d bytes of whole runes", len(reason), reason[len(reason)-2:], cefMaxValue)
+	}
+}
+
+func TestSyslogReconnects(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	s := newTestSyslog(t, "tcp", addr, FormatRFC5424, "")
+
+	if err := s.Write(testEvent); err != nil {
+		t.Fatal(err)
+	}
+	conn, r := acceptOne(t, l)
+	if got := readOctetCounted(t, r); got != wantRFC5424 {
+		t.Fatalf("before restart got %s", got)
+	}
+
+	// 采集端重启：连接和监听都关闭
+	conn.Close()
+	l.Close()
+	if err := s.Write(testEvent); err == nil {
+		t.Fatal("write while the collector is down succeeded")
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("restart listener: %v", err)
+	}
+	defer l.Close()
+	second := *testEvent
+	second.ID = 10
+	if err := s.Write(&second); err != nil {
+		t.Fatalf("write after restart: %v", err)
+	}
+	conn, r = acceptOne(t, l)
+	defer conn.Close()
+	if got := readOctetCounted(t, r); !strings.Contains(got, `id="10"`) {
+		t.Errorf("after restart got %s, want event 10", got)
+	}
+}
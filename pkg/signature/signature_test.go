@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVector(t *testing.T) {
+	// Computed independently: HMAC-SHA256("whsec_test", "<ts>.<body>").
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"event body", `{"id":"evt_1","type":"user.created"}`, "sha256=25e9427a14f1b89708da76da758332a4c9d04e4bce30f9649ed20645dac8ffe5"},
+		{"empty body", "", "sha256=5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc"},
+	}
+	for _, tt := range tests {
+		if got := Sign("whsec_test", "1700000000", []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: Sign = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"user.created"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		secret string
+		ts     string
+		sig    string
+		body   []byte
+		want   bool
+	}{
+		{"valid", "whsec_test", now, Sign("whsec_test", now, body), body, true},
+		{"wrong secret", "whsec_other", now, Sign("whsec_test", now, body), body, false},
+		{"tampered body", "whsec_test", now, Sign("whsec_test", now, body), []byte(`{"id":"evt_2"}`), false},
+		{"timestamp swapped", "whsec_test", now, Sign("whsec_test", old, body), body, false},
+		{"too old", "whsec_test", old, Sign("whsec_test", old, body), body, false},
+		{"too far ahead", "whsec_test", future, Sign("whsec_test", future, body), body, false},
+		{"malformed timestamp", "whsec_test", "yesterday", Sign("whsec_test", "yesterday", body), body, false},
+		{"missing prefix", "whsec_test", now, Sign("whsec_test", now, body)[len("sha256="):], body, false},
+	}
+	for _, tt := range tests {
+		if got := Verify(tt.secret, tt.ts, tt.sig, tt.body, 5*time.Minute); got != tt.want {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
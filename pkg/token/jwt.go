@@ -20,6 +20,7 @@ import (
 	"errors"
 	"log"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
@@ -30,6 +31,8 @@ var (
 	secret       []byte
 	expireHours  int
 	refreshHours int
+	clientMins   int
+	issuer       string
 )
 
 func init() {
@@ -37,6 +40,10 @@ func init() {
 	secret = []byte(getenv("JWT_SECRET", "change_me"))
 	expireHours = getenvInt("JWT_EXPIRE_HOURS", 1)
 	refreshHours = getenvInt("JWT_REFRESH_HOURS", 72)
+	clientMins = getenvInt("CLIENT_TOKEN_MINUTES", 15)
+	issuer = getenv("JWT_ISSUER", "sd-svc-auth")
+	// 毫秒精度的 iat，撤销之后同一秒内签发的 token 不会被误判为已撤销
+	jwt.TimePrecision = time.Millisecond
 }
 
 func getenv(key, def string) string {
@@ -56,21 +63,137 @@ func getenvInt(key string, def int) int {
 }
 
 type Claims struct {
-	TokenType string `json:"token_type"`
-	UserID    string `json:"uid"`
-	Email     string `json:"email"`
+	TokenType string   `json:"token_type"`
+	UserID    string   `json:"uid,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	Act       *Actor   `json:"act,omitempty"`
+	// OrgID is the active organization and OrgRoles the roles the
+	// subject holds in it.
+	OrgID    string   `json:"org_id,omitempty"`
+	OrgRoles []string `json:"org_roles,omitempty"`
+	// Groups names the groups the subject belongs to in the organization,
+	// directly or through nesting. Only set when enabled.
+	Groups []string `json:"groups,omitempty"`
+	// Metadata holds the user attributes mapped into the token by
+	// TOKEN_METADATA_CLAIMS, keyed by claim name.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID, email string) (string, time.Duration, error) {
-	return generateToken(userID, email, time.Duration(expireHours)*time.Hour, "access")
+// Actor is the party acting on behalf of the subject (RFC 8693 "act"
+// claim), set on impersonation tokens.
+type Actor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
 }
 
-func GenerateRefreshJWT(userID, email string) (string, time.Duration, error) {
-	return generateToken(userID, email, time.Duration(refreshHours)*time.Hour, "refresh")
+// HasRole reports whether the token carries the given role.
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOrgRole reports whether the subject holds role in the active
+// organization.
+func (c *Claims) HasOrgRole(role string) bool {
+	for _, r := range c.OrgRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token was granted the given scope. Only
+// personal access tokens and client tokens carry scopes.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Option customises the claims of a token before it is signed.
+type Option func(*Claims)
+
+func WithRoles(roles []string) Option {
+	return func(c *Claims) {
+		c.Roles = roles
+	}
+}
+
+// WithOrganization sets the active organization and the roles held there.
+func WithOrganization(orgID string, roles []string) Option {
+	return func(c *Claims) {
+		c.OrgID = orgID
+		c.OrgRoles = roles
+	}
+}
+
+// WithGroups sets the groups claim.
+func WithGroups(groups []string) Option {
+	return func(c *Claims) {
+		c.Groups = groups
+	}
+}
+
+// WithMetadata sets the metadata claim.
+func WithMetadata(metadata map[string]interface{}) Option {
+	return func(c *Claims) {
+		c.Metadata = metadata
+	}
+}
+
+// WithActor marks the token as issued to actorID acting as the subject.
+func WithActor(actorID, actorEmail string) Option {
+	return func(c *Claims) {
+		c.Act = &Actor{Subject: actorID, Email: actorEmail}
+	}
+}
+
+// AccessTTL is the lifetime of newly issued access tokens.
+func AccessTTL() time.Duration {
+	return time.Duration(expireHours) * time.Hour
+}
+
+// RefreshTTL is the lifetime of newly issued refresh tokens.
+func RefreshTTL() time.Duration {
+	return time.Duration(refreshHours) * time.Hour
+}
+
+func GenerateJWT(userID, email string, opts ...Option) (string, time.Duration, error) {
+	return generateToken(userID, email, time.Duration(expireHours)*time.Hour, "access", opts...)
+}
+
+// GenerateJWTWithTTL issues an access token with a lifetime other than
+// JWT_EXPIRE_HOURS, e.g. one configured by an organization.
+func GenerateJWTWithTTL(userID, email string, ttl time.Duration, opts ...Option) (string, time.Duration, error) {
+	return generateToken(userID, email, ttl, "access", opts...)
+}
+
+// GenerateImpersonationJWT issues an access token with a custom, usually
+// shorter, lifetime. Callers pass WithActor.
+func GenerateImpersonationJWT(userID, email string, ttl time.Duration, opts ...Option) (string, time.Duration, error) {
+	return generateToken(userID, email, ttl, "access", opts...)
+}
+
+func GenerateRefreshJWT(userID, email string, opts ...Option) (string, time.Duration, error) {
+	return generateToken(userID, email, time.Duration(refreshHours)*time.Hour, "refresh", opts...)
 }
 
-func generateToken(userID, email string, duration time.Duration, tokenType string) (string, time.Duration, error) {
+func GenerateRefreshJWTWithTTL(userID, email string, ttl time.Duration, opts ...Option) (string, time.Duration, error) {
+	return generateToken(userID, email, ttl, "refresh", opts...)
+}
+
+func generateToken(userID, email string, duration time.Duration, tokenType string, opts ...Option) (string, time.Duration, error) {
 	exp := time.Now().Add(duration)
 	claims := &Claims{
 		TokenType: tokenType,
@@ -81,6 +204,9 @@ func generateToken(userID, email string, duration time.Duration, tokenType strin
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	for _, opt := range opts {
+		opt(claims)
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	ss, err := token.SignedString(secret)
 	if err != nil {
@@ -88,12 +214,33 @@ func generateToken(userID, email string, duration time.Duration, tokenType strin
 	}
 	return ss, duration, nil
 }
+
+// ErrTokenType is returned when a valid token is presented where another
+// type of token is expected.
+var ErrTokenType = errors.New("unexpected token type")
+
+// ParseAndValidate parses a token that authenticates API calls: an access
+// token or a client-credentials token. Refresh and invitation tokens are
+// rejected with ErrTokenType.
 func ParseAndValidate(tokenStr string) (*Claims, error) {
-	return ParseToken(tokenStr)
+	return parseTokenType(tokenStr, "access", TokenTypeClient)
 }
 
+// ParseAndValidateRefresh parses a refresh token and rejects any other
+// type with ErrTokenType.
 func ParseAndValidateRefresh(tokenStr string) (*Claims, error) {
-	return ParseToken(tokenStr)
+	return parseTokenType(tokenStr, "refresh")
+}
+
+func parseTokenType(tokenStr string, types ...string) (*Claims, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(types, claims.TokenType) {
+		return nil, ErrTokenType
+	}
+	return claims, nil
 }
 
 func ParseToken(tokenStr string) (*Claims, error) {
@@ -111,3 +258,62 @@ func ParseToken(tokenStr string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// TokenTypeClient marks tokens issued to service accounts. They carry the
+// client ID as subject and never an email.
+const TokenTypeClient = "client"
+
+// ClientTTL is the lifetime of client-credentials access tokens.
+func ClientTTL() time.Duration {
+	return time.Duration(clientMins) * time.Minute
+}
+
+// GenerateClientToken issues a client-credentials token with the client ID
+// as subject and JWT_ISSUER as issuer.
+func GenerateClientToken(clientID, orgID string, scopes, audience []string) (string, time.Duration, error) {
+	ttl := ClientTTL()
+	now := time.Now()
+	claims := &Claims{
+		TokenType: TokenTypeClient,
+		Scopes:    scopes,
+		OrgID:     orgID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   clientID,
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	ss, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", 0, err
+	}
+	return ss, ttl, nil
+}
+
+// GenerateInvitationToken signs an invitation link. The invitation ID is
+// carried in the jti claim.
+func GenerateInvitationToken(invitationID, email string, expiresAt time.Time) (string, error) {
+	claims := &Claims{
+		TokenType: "invitation",
+		Email:     email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invitationID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+func ParseInvitationToken(tokenStr string) (*Claims, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "invitation" {
+		return nil, errors.New("not an invitation token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2025-11-20 shinoda4
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	TokenTypePAT = "pat"
+
+	// PATPrefix marks personal access tokens so they can be told apart
+	// from JWTs without parsing.
+	PATPrefix = "sdpat_"
+
+	// patDisplayLen is how much of a token is kept in clear for listing.
+	patDisplayLen = len(PATPrefix) + 8
+)
+
+// GeneratePersonalAccessToken returns a new random token and the short
+// prefix that identifies it in listings.
+func GeneratePersonalAccessToken() (raw, prefix string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	raw = PATPrefix + hex.EncodeToString(b)
+	return raw, raw[:patDisplayLen]
+}
+
+// HashPersonalAccessToken is the value stored in the database. The token
+// carries 256 bits of entropy, so a plain SHA-256 is enough.
+func HashPersonalAccessToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsPersonalAccessToken(raw string) bool {
+	return strings.HasPrefix(raw, PATPrefix)
+}
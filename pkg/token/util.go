@@ -18,11 +18,30 @@ package token
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"math/big"
 )
 
-func GenerateVerifyToken() string {
+// GenerateOneTimeToken returns a random token for links mailed to users.
+func GenerateOneTimeToken() string {
 	b := make([]byte, 32)
 	_, _ = rand.Read(b)
 	return hex.EncodeToString(b)
 }
+
+// GenerateOneTimeCode returns a random numeric code of the given length
+// for users to type in.
+func GenerateOneTimeCode(digits int) string {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, _ := rand.Int(rand.Reader, limit)
+	return fmt.Sprintf("%0*d", digits, n)
+}
+
+// HashOneTimeToken is the value stored in the database for a one-time
+// token or code.
+func HashOneTimeToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}